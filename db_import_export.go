@@ -1,14 +1,16 @@
 package main
 
 import (
-	"database/sql"
+	"encoding/csv"
+	"flag"
 	"fmt"
 	//iconv "github.com/djimenez/iconv-go"
-	_ "github.com/marcboeker/go-duckdb"  // Import the duckdb driver
+	"github.com/ggivl/GoMusicLibraryGUIApp/musiclib"
 	"golang.org/x/text/encoding/charmap" // For common single-byte encodings like ISO-8859-1
 	"golang.org/x/text/transform"        // For general encoding transformations
 	"io"
 	"os"
+	"strings"
 )
 
 // ConvertCSVToUTF8 converts a CSV file from a specified source encoding to UTF-8.
@@ -49,12 +51,74 @@ func ConvertCSVToUTF8(inputFile, outputFile string, sourceEncoding string) error
 	return nil
 }
 
+// ImportCSV loads a UTF-8 CSV file into a table of text columns named by
+// its header, in SQL every backend accepts, replacing the table of an
+// earlier import. Like DuckDB's read_csv_auto with ignore_errors, it skips
+// rows it cannot read, and returns how many.
+func ImportCSV(store musiclib.LibraryStore, csvFilename, tableName string) (int, error) {
+	file, err := os.Open(csvFilename)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = `"` + strings.ReplaceAll(name, `"`, `""`) + `" TEXT`
+	}
+	err = store.ExecuteQuery("DROP TABLE IF EXISTS " + tableName)
+	if err != nil {
+		return 0, err
+	}
+	err = store.CreateTable(tableName, strings.Join(columns, ", "))
+	if err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || len(record) != len(header) {
+			skipped++
+			continue
+		}
+		data := make([]interface{}, len(record))
+		for i, value := range record {
+			data[i] = value
+		}
+		err = store.InsertData(tableName, data)
+		if err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
+
 func main() {
-	db, err := sql.Open("duckdb", "") // In-memory database
+	configFile := flag.String("c", "config.yml", "Configuration file naming the database")
+	flag.Parse()
+
+	// The table goes into the database config.yml names, as walk_demo's do
+	config, err := musiclib.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Printf("Using default configuration: %v\n", err)
+	}
+	store, err := musiclib.OpenStore(config.Store())
 	if err != nil {
-		panic(err)
+		fmt.Printf("Error opening database: %v\n", err)
+		return
 	}
-	defer db.Close()
+	defer store.Close()
 
 	csvFilePath := "C:\\Users\\ggivl\\Documents\\GoDevelopment\\GoMusicLibraryGUIApp\\csv_output_full.csv"
 	utf8CsvFilePath := "output_utf8.csv"
@@ -67,35 +131,34 @@ func main() {
 	}
 	fmt.Println("CSV file converted to UTF-8 successfully!")
 
-	// Use the converted UTF-8 file and enable ignore_errors to skip problematic rows
-	query := `CREATE TABLE ` + tableName + ` AS SELECT * FROM read_csv_auto('` + utf8CsvFilePath + `', ignore_errors=true);`
-	_, err = db.Exec(query)
+	// Use the converted UTF-8 file, skipping problematic rows
+	skipped, err := ImportCSV(store, utf8CsvFilePath, tableName)
 	if err != nil {
-		panic(err)
+		fmt.Printf("Error importing CSV: %v\n", err)
+		return
 	}
 
-	fmt.Println("Table created successfully from UTF-8 CSV!")
+	fmt.Printf("Table created successfully from UTF-8 CSV! (%d rows skipped)\n", skipped)
 
 	// Verify the table was created and show some stats
 	var count int
-	err = db.QueryRow("SELECT COUNT(*) FROM " + tableName).Scan(&count)
+	err = store.QueryRow("SELECT COUNT(*) FROM " + tableName).Scan(&count)
 	if err != nil {
 		fmt.Printf("Error querying table: %v\n", err)
 	} else {
 		fmt.Printf("Successfully loaded %d rows into the table.\n", count)
 	}
 
-	// Show a few sample rows
+	// Show a few sample rows in catalog order
 	fmt.Println("\nFirst 3 rows:")
-	rows, err := db.Query("SELECT * FROM " + tableName + " LIMIT 3")
+	rows, err := store.FetchAll(tableName, `ORDER BY "sort key", "original filename" LIMIT 3`)
 	if err != nil {
 		fmt.Printf("Error selecting sample rows: %v\n", err)
 		return
 	}
-	defer rows.Close()
 
 	// Get column names
-	columns, err := rows.Columns()
+	columns, err := store.Columns(tableName)
 	if err != nil {
 		fmt.Printf("Error getting columns: %v\n", err)
 		return
@@ -103,20 +166,7 @@ func main() {
 	fmt.Printf("Columns: %v\n", columns)
 
 	// Print sample rows
-	for rows.Next() {
-		// Create a slice of interface{} to hold the values
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-
-		err := rows.Scan(valuePtrs...)
-		if err != nil {
-			fmt.Printf("Error scanning row: %v\n", err)
-			continue
-		}
-
+	for _, values := range rows {
 		fmt.Printf("Row: %v\n", values)
 	}
 }
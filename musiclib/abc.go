@@ -0,0 +1,185 @@
+package musiclib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// abcModes maps the modes of an ABC K: field, which may be abbreviated to
+// their first three letters, to the modes of a Key
+var abcModes = map[string]string{
+	"":    "Major",
+	"m":   "Minor",
+	"maj": "Major",
+	"min": "Minor",
+	"ion": "Major",
+	"aeo": "Minor",
+	"mix": "Mixolydian",
+	"dor": "Dorian",
+	"phr": "Phrygian",
+	"lyd": "Lydian",
+	"loc": "Locrian",
+}
+
+var (
+	abcField      = regexp.MustCompile(`^([A-Za-z]):\s*(.*)$`)
+	abcKeyField   = regexp.MustCompile(`^([A-G])([#b♯♭]?)\s*([A-Za-z]*)`)
+	abcTempo      = regexp.MustCompile(`(?:\d+/\d+\s*)*=\s*(\d+(?:\.\d+)?)|^(\d+(?:\.\d+)?)$`)
+	abcVoiceName  = regexp.MustCompile(`\b(?:name|nm)\s*=\s*"([^"]*)"|\b(?:name|nm)\s*=\s*(\S+)`)
+	movedArticle  = regexp.MustCompile(`^(.+),\s*(The|A|An)$`)
+	abcEmail      = regexp.MustCompile(`<[^>]*@[^>]*>|\S+@\S+`)
+	abcTranscribe = regexp.MustCompile(`(?i)^abc-(?:transcription|edited-by)\s+`)
+	abcCopyright  = regexp.MustCompile(`(?i)^abc-copyright\s+`)
+)
+
+// ReadABC reads the header of the first tune of an ABC file: its title
+// (T:), composer and arranger (C:), transcriber (Z:), meter (M:), tempo
+// (Q:), voices (V:) and key (K:, which ends the header). The transcriber
+// of a choir's ABC arrangement is taken for its arranger when no C: field
+// credits one, and voices named Soprano, Alto and so on give the voicing.
+func ReadABC(filePath string) (ScoreInfo, error) {
+	info := ScoreInfo{Format: "ABC"}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return info, fmt.Errorf("error reading ABC '%s': %v", filePath, err)
+	}
+	if err := readABCHeader(data, &info); err != nil {
+		return info, fmt.Errorf("error reading ABC '%s': %v", filePath, err)
+	}
+	return info, nil
+}
+
+func readABCHeader(data []byte, info *ScoreInfo) error {
+	text := string(data)
+	if !utf8.Valid(data) {
+		text = decodeLatin1(data)
+	}
+
+	var credits []string
+	var transcriber string
+	tune, key := false, false
+	voices := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for !key && scanner.Scan() {
+		groups := abcField.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if groups == nil {
+			continue
+		}
+		field, value := groups[1], abcValue(groups[2])
+		if field == "X" {
+			if tune {
+				break
+			}
+			tune = true
+			continue
+		}
+
+		switch field {
+		case "T":
+			if info.Title == "" {
+				info.Title = value
+				if groups := movedArticle.FindStringSubmatch(value); groups != nil {
+					info.Title = groups[2] + " " + groups[1]
+				}
+			}
+		case "C":
+			credits = append(credits, value)
+		case "Z":
+			if abcCopyright.MatchString(value) {
+				info.Copyright = abcCopyright.ReplaceAllString(value, "")
+			} else if transcriber == "" {
+				transcriber = strings.Trim(abcEmail.ReplaceAllString(abcTranscribe.ReplaceAllString(value, ""), ""), " ,;")
+			}
+		case "M":
+			if info.Time == "" {
+				info.Time = abcMeter(value)
+			}
+		case "Q":
+			if groups := abcTempo.FindStringSubmatch(value); groups != nil {
+				bpm, _ := strconv.ParseFloat(groups[1]+groups[2], 64)
+				if bpm > 0 {
+					info.Tempos = append(info.Tempos, bpm)
+				}
+			}
+		case "V":
+			if groups := abcVoiceName.FindStringSubmatch(value); groups != nil {
+				if name := groups[1] + groups[2]; !voices[name] {
+					voices[name] = true
+					info.Parts = append(info.Parts, ScorePart{Name: name})
+				}
+			}
+		case "K":
+			info.Key, _ = abcKey(value)
+			key = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !tune && !key {
+		return errors.New("no tune header")
+	}
+
+	info.Composer, info.Arranger = splitCredits(strings.Join(credits, "\n"))
+	if info.Arranger == "" {
+		info.Arranger = transcriber
+	}
+	return nil
+}
+
+// abcValue drops the comment that may follow the value of a field
+func abcValue(value string) string {
+	if i := strings.Index(value, "%"); i >= 0 && (i == 0 || value[i-1] != '\\') {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// abcMeter writes the meter of an M: field as a time signature, so "C" is
+// 4/4 and "C|" is 2/2. A free meter has none.
+func abcMeter(value string) string {
+	switch value {
+	case "C":
+		return "4/4"
+	case "C|":
+		return "2/2"
+	case "", "none":
+		return ""
+	}
+	return strings.ReplaceAll(value, " ", "")
+}
+
+// abcKey reads the key of a K: field, such as "G", "Em", "Ador" or "Bb
+// mix". Highland pipe keys (HP, Hp) and "none" name no key.
+func abcKey(value string) (Key, bool) {
+	groups := abcKeyField.FindStringSubmatch(value)
+	if groups == nil {
+		return Key{}, false
+	}
+	tonic := groups[1] + accidentals[groups[2]]
+	mode := strings.ToLower(groups[3])
+	if len(mode) > 3 {
+		mode = mode[:3]
+	}
+	name, ok := abcModes[mode]
+	if !ok {
+		// A clef or other key field setting follows the tonic of a major key
+		name = "Major"
+	}
+	return Key{Tonic: tonic, Mode: name}, true
+}
+
+// sniffABC recognizes an ABC file by its version comment or the X: field
+// that starts each tune
+func sniffABC(header []byte) bool {
+	text := bytes.TrimLeft(header, "\xef\xbb\xbf \t\r\n")
+	return bytes.HasPrefix(text, []byte("%abc")) || bytes.HasPrefix(text, []byte("X:"))
+}
@@ -0,0 +1,44 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadABC(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		want   ScoreInfo
+		wantOK bool
+	}{
+		{
+			// Only the first tune of the file is read
+			name: "choir setting",
+			path: filepath.Join("testdata", "water_is_wide.abc"),
+			want: ScoreInfo{Format: "ABC", Title: "The Water Is Wide", Composer: "Trad. English", Arranger: "Jane Smith", Copyright: "© 2018 Jane Smith",
+				Key: Key{Tonic: "Eb", Mode: "Major"}, Time: "2/2", Tempos: []float64{72},
+				Parts: []ScorePart{{Name: "Soprano"}, {Name: "Alto"}, {Name: "Tenor"}, {Name: "Bass"}}},
+			wantOK: true,
+		},
+		{
+			name:   "Latin-1",
+			path:   writeTemp(t, "latin1.abc", []byte("X:1\nT:Ave Mar\xeda\nK:G\n")),
+			want:   ScoreInfo{Format: "ABC", Title: "Ave María", Key: Key{Tonic: "G", Mode: "Major"}},
+			wantOK: true,
+		},
+		{"no tune header", filepath.Join("testdata", "shenandoah.ly"), ScoreInfo{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadABC(tt.path)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadABC() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if tt.wantOK && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadABC() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package musiclib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxASFHeaderLength limits the ASF header read into memory. Its metadata
+// can hold cover art, but not this much.
+const maxASFHeaderLength = 16 << 20
+
+// GUIDs of the ASF header objects that are read, in the byte order they
+// are stored in
+var (
+	asfFilePropertiesGUID      = []byte{0xA1, 0xDC, 0xAB, 0x8C, 0x47, 0xA9, 0xCF, 0x11, 0x8E, 0xE4, 0x00, 0xC0, 0x0C, 0x20, 0x53, 0x65}
+	asfStreamPropertiesGUID    = []byte{0x91, 0x07, 0xDC, 0xB7, 0xB7, 0xA9, 0xCF, 0x11, 0x8E, 0xE6, 0x00, 0xC0, 0x0C, 0x20, 0x53, 0x65}
+	asfContentDescriptionGUID  = []byte{0x33, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	asfExtendedDescriptionGUID = []byte{0x40, 0xA4, 0xD0, 0xD2, 0x07, 0xE3, 0xD2, 0x11, 0x97, 0xF0, 0x00, 0xA0, 0xC9, 0x5E, 0xA8, 0x50}
+	asfAudioStreamGUID         = []byte{0x40, 0x9E, 0x69, 0xF8, 0x4D, 0x5B, 0xCF, 0x11, 0xA8, 0xFD, 0x00, 0x80, 0x5F, 0x5C, 0x44, 0x2B}
+)
+
+// asfAttributes maps the names of Extended Content Description attributes
+// to the tags they hold. WM/Track counts from zero, so WM/TrackNumber is
+// preferred.
+var asfAttributes = map[string]string{
+	"WM/AlbumTitle":  "album",
+	"WM/Composer":    "composer",
+	"WM/TrackNumber": "track",
+	"WM/Year":        "year",
+	"WM/Text":        "comment",
+}
+
+// ReadASF reads the header objects of an ASF file, such as a WMA rehearsal
+// track: the Content Description and Extended Content Description for its
+// tags, and the File and Stream Properties for its duration and stream
+func ReadASF(filePath string) (AudioInfo, error) {
+	return readAudioFile(filePath, "ASF", ReadASFAt)
+}
+
+// ReadASFAt reads ASF data of the given size, as ReadASF reads a file
+func ReadASFAt(r io.ReaderAt, size int64) (AudioInfo, error) {
+	info := AudioInfo{Format: "WMA", TagFormat: "ASF"}
+	reader := io.NewSectionReader(r, 0, size)
+
+	header := make([]byte, 30)
+	if _, err := io.ReadFull(reader, header); err != nil || !bytes.Equal(header[:16], asfHeaderGUID) {
+		return info, errors.New("no header object")
+	}
+	headerSize := binary.LittleEndian.Uint64(header[16:])
+	if headerSize < 30 || headerSize > maxASFHeaderLength {
+		return info, fmt.Errorf("bad header size %d", headerSize)
+	}
+	objects := make([]byte, headerSize-30)
+	if _, err := io.ReadFull(reader, objects); err != nil {
+		return info, err
+	}
+
+	var track string
+	for len(objects) >= 24 {
+		guid := objects[:16]
+		objectSize := binary.LittleEndian.Uint64(objects[16:])
+		if objectSize < 24 || objectSize > uint64(len(objects)) {
+			return info, errors.New("bad object size")
+		}
+		data := objects[24:objectSize]
+		objects = objects[objectSize:]
+
+		switch {
+		case bytes.Equal(guid, asfFilePropertiesGUID):
+			if len(data) < 64 {
+				continue
+			}
+			// Play duration in 100 ns units, less the preroll in milliseconds
+			seconds := float64(binary.LittleEndian.Uint64(data[40:]))/1e7 - float64(binary.LittleEndian.Uint64(data[56:]))/1e3
+			if seconds > 0 {
+				info.Properties.Duration = secondsDuration(seconds)
+			}
+		case bytes.Equal(guid, asfStreamPropertiesGUID):
+			if len(data) < 54+16 || !bytes.Equal(data[:16], asfAudioStreamGUID) || info.Properties.SampleRate > 0 {
+				continue
+			}
+			// A WAVEFORMATEX structure follows the fixed fields
+			format := data[54:]
+			info.Properties.Channels = int(binary.LittleEndian.Uint16(format[2:]))
+			info.Properties.SampleRate = int(binary.LittleEndian.Uint32(format[4:]))
+			info.Properties.Bitrate = int(binary.LittleEndian.Uint32(format[8:])) * 8 / 1000
+		case bytes.Equal(guid, asfContentDescriptionGUID):
+			tags, err := parseASFContentDescription(data)
+			if err != nil {
+				return info, err
+			}
+			info.Tags.fillBlank(tags)
+		case bytes.Equal(guid, asfExtendedDescriptionGUID):
+			tags, zeroBasedTrack, err := parseASFExtendedDescription(data)
+			if err != nil {
+				return info, err
+			}
+			info.Tags.fillBlank(tags)
+			track = zeroBasedTrack
+		}
+	}
+
+	if info.Tags.Track == "" && track != "" {
+		if n, err := strconv.Atoi(track); err == nil {
+			info.Tags.Track = strconv.Itoa(n + 1)
+		}
+	}
+	return info, nil
+}
+
+// parseASFContentDescription reads the title, author and description of a
+// Content Description object: five lengths, then the UTF-16LE strings
+func parseASFContentDescription(data []byte) (AudioTags, error) {
+	if len(data) < 10 {
+		return AudioTags{}, errors.New("truncated content description")
+	}
+	var values [5]string
+	offset := 10
+	for i := range values {
+		length := int(binary.LittleEndian.Uint16(data[i*2:]))
+		if offset+length > len(data) {
+			return AudioTags{}, errors.New("truncated content description")
+		}
+		values[i] = asfString(data[offset : offset+length])
+		offset += length
+	}
+	return AudioTags{Title: values[0], Artist: values[1], Comment: values[3]}, nil
+}
+
+// parseASFExtendedDescription reads the named attributes of an Extended
+// Content Description object. The zero-based WM/Track is returned apart.
+func parseASFExtendedDescription(data []byte) (AudioTags, string, error) {
+	var tags AudioTags
+	var track string
+	if len(data) < 2 {
+		return tags, "", errors.New("truncated extended content description")
+	}
+	count := int(binary.LittleEndian.Uint16(data))
+	data = data[2:]
+	for i := 0; i < count; i++ {
+		if len(data) < 2 {
+			return tags, track, errors.New("truncated extended content description")
+		}
+		nameLength := int(binary.LittleEndian.Uint16(data))
+		if len(data) < 2+nameLength+4 {
+			return tags, track, errors.New("truncated extended content description")
+		}
+		name := asfString(data[2 : 2+nameLength])
+		data = data[2+nameLength:]
+		valueType := binary.LittleEndian.Uint16(data)
+		valueLength := int(binary.LittleEndian.Uint16(data[2:]))
+		if len(data) < 4+valueLength {
+			return tags, track, errors.New("truncated extended content description")
+		}
+		value := asfValue(valueType, data[4:4+valueLength])
+		data = data[4+valueLength:]
+
+		if name == "WM/Track" {
+			track = value
+		} else if field, ok := asfAttributes[name]; ok {
+			tags.set(field, value)
+		}
+	}
+	return tags, track, nil
+}
+
+// asfValue writes an attribute value as text. Byte arrays are left out.
+func asfValue(valueType uint16, value []byte) string {
+	switch {
+	case valueType == 0:
+		return asfString(value)
+	case valueType == 2 && len(value) >= 4:
+		return strconv.FormatBool(binary.LittleEndian.Uint32(value) != 0)
+	case valueType == 3 && len(value) >= 4:
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(value)), 10)
+	case valueType == 4 && len(value) >= 8:
+		return strconv.FormatUint(binary.LittleEndian.Uint64(value), 10)
+	case valueType == 5 && len(value) >= 2:
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(value)), 10)
+	}
+	return ""
+}
+
+// asfString decodes a null-terminated UTF-16LE string
+func asfString(data []byte) string {
+	return strings.TrimSpace(strings.TrimRight(decodeUTF16(data, false), "\x00"))
+}
@@ -0,0 +1,66 @@
+package musiclib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadASFAt(t *testing.T) {
+	wma := readTestdata(t, "tags.wma")
+	// The play duration is longer than a Duration holds
+	overflow := bytes.Clone(wma)
+	properties := bytes.Index(overflow, asfFilePropertiesGUID) + 24
+	binary.LittleEndian.PutUint64(overflow[properties+40:], 1<<60)
+	// WM/Track counts from zero
+	wmaTags := AudioTags{Title: "The Water Is Wide - Alto", Artist: "Forty Niners", Album: "Rehearsal CD 2004", Composer: "Gilpin", Track: "5"}
+	noHeader := AudioInfo{Format: "WMA", TagFormat: "ASF"}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   AudioInfo
+		wantOK bool
+	}{
+		{
+			name: "tags",
+			data: wma,
+			want: AudioInfo{Format: "WMA", TagFormat: "ASF", Tags: wmaTags,
+				Properties: AudioProperties{Duration: 185000000000, Bitrate: 128, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{
+			name: "duration overflow",
+			data: overflow,
+			want: AudioInfo{Format: "WMA", TagFormat: "ASF", Tags: wmaTags,
+				Properties: AudioProperties{Bitrate: 128, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{name: "empty", want: noHeader},
+		{name: "cut after the header GUID", data: wma[:16], want: noHeader},
+		{name: "cut after the header object", data: wma[:30], want: noHeader},
+		{name: "cut in the header objects", data: wma[:200], want: noHeader},
+		{name: "not ASF", data: readTestdata(t, "id3v23.mp3"), want: noHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadASFAt(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadASFAt() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ReadASFAt() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReadASFAt(f *testing.F) {
+	f.Add(readTestdata(f, "tags.wma"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadASFAt(bytes.NewReader(data), int64(len(data)))
+		if err == nil && (info.Properties.Duration < 0 || info.Properties.Bitrate < 0) {
+			t.Errorf("negative duration %v or bitrate %d", info.Properties.Duration, info.Properties.Bitrate)
+		}
+	})
+}
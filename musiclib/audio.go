@@ -0,0 +1,157 @@
+package musiclib
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// AudioPropertiesColumns is the schema of the audio_properties table, which
+// holds the stream properties of each audio file keyed by its path below
+// the library root
+const AudioPropertiesColumns = "path TEXT PRIMARY KEY, format TEXT, duration_seconds DOUBLE, bitrate INTEGER, sample_rate INTEGER, channels INTEGER, has_video BOOLEAN"
+
+// AudioTags are the descriptive tags of an audio file. Track is the track
+// number without the total, as in "3" for "3/12". Part is the voice or
+// instrument a rehearsal track is for, where the tags give one.
+type AudioTags struct {
+	Title    string
+	Artist   string
+	Album    string
+	Composer string
+	Track    string
+	Year     string
+	Comment  string
+	Part     string
+}
+
+// AudioProperties describe an audio file's stream. Bitrate is the average
+// in kbit/s.
+type AudioProperties struct {
+	Duration   time.Duration
+	Bitrate    int
+	SampleRate int
+	Channels   int
+}
+
+// AudioInfo is what an audio reader reads from a file. TagFormat names the
+// tags the values came from, such as "ID3v2.4". Created is when a
+// recording was made, where the file keeps it, and HasVideo is set for a
+// video recording.
+type AudioInfo struct {
+	Format     string
+	TagFormat  string
+	Tags       AudioTags
+	Properties AudioProperties
+	Created    time.Time
+	HasVideo   bool
+}
+
+// FormatDuration writes the duration of the stream as FormatDuration does
+func (p AudioProperties) FormatDuration() string {
+	return FormatDuration(p.Duration)
+}
+
+// FormatDuration writes a duration as minutes and seconds, as in "3:07",
+// or "" when it is not known
+func FormatDuration(duration time.Duration) string {
+	if duration <= 0 {
+		return ""
+	}
+	seconds := int(duration.Round(time.Second) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// readAudioFile reads an audio file with the reader of its format, which
+// takes the data and its size, naming the file in any error. What was read
+// before an error is returned with it.
+func readAudioFile(filePath, format string, read func(r io.ReaderAt, size int64) (AudioInfo, error)) (AudioInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return AudioInfo{}, fmt.Errorf("error reading %s '%s': %v", format, filePath, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return AudioInfo{}, fmt.Errorf("error reading %s '%s': %v", format, filePath, err)
+	}
+	info, err := read(file, stat.Size())
+	if err != nil {
+		return info, fmt.Errorf("error reading %s '%s': %v", format, filePath, err)
+	}
+	return info, nil
+}
+
+// secondsDuration converts a length in seconds to a Duration. A length
+// too long for a Duration, which only a damaged header gives, is unknown.
+func secondsDuration(seconds float64) time.Duration {
+	if !(seconds >= 0 && seconds < float64(math.MaxInt64/time.Second)) {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// fillBlank sets the blank fields of t from other
+func (t *AudioTags) fillBlank(other AudioTags) {
+	for _, field := range []struct {
+		target *string
+		value  string
+	}{
+		{&t.Title, other.Title},
+		{&t.Artist, other.Artist},
+		{&t.Album, other.Album},
+		{&t.Composer, other.Composer},
+		{&t.Track, other.Track},
+		{&t.Year, other.Year},
+		{&t.Comment, other.Comment},
+		{&t.Part, other.Part},
+	} {
+		if *field.target == "" {
+			*field.target = field.value
+		}
+	}
+}
+
+// ResetAudioPropertiesTable recreates the audio_properties table, which is
+// rebuilt with the catalog on every scan
+func ResetAudioPropertiesTable(db LibraryStore) error {
+	if _, err := db.Exec("DROP TABLE IF EXISTS audio_properties"); err != nil {
+		return fmt.Errorf("error dropping audio_properties table: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE audio_properties (" + AudioPropertiesColumns + ")"); err != nil {
+		return fmt.Errorf("error creating audio_properties table: %v", err)
+	}
+	return nil
+}
+
+// SaveAudioProperties writes the stream properties of audio files, keyed by
+// their path below the library root
+func SaveAudioProperties(db LibraryStore, audio map[string]AudioInfo) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error saving audio_properties: %v", err)
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare("INSERT INTO audio_properties VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("error saving audio_properties: %v", err)
+	}
+	defer statement.Close()
+
+	for path, info := range audio {
+		properties := info.Properties
+		_, err := statement.Exec(path, info.Format, properties.Duration.Seconds(), properties.Bitrate, properties.SampleRate, properties.Channels, info.HasVideo)
+		if err != nil {
+			return fmt.Errorf("error adding '%s' to audio_properties: %v", path, err)
+		}
+	}
+
+	return tx.Commit()
+}
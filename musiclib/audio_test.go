@@ -0,0 +1,40 @@
+package musiclib
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReadAudioFile checks that each reader reads a file as its At
+// function reads the same data, and names a file it cannot open
+func TestReadAudioFile(t *testing.T) {
+	tests := []struct {
+		file   string
+		read   func(filePath string) (AudioInfo, error)
+		readAt func(r io.ReaderAt, size int64) (AudioInfo, error)
+	}{
+		{"id3v23.mp3", ReadMP3, ReadMP3At},
+		{"vorbis.ogg", ReadOgg, ReadOggAt},
+		{"comments.flac", ReadFLAC, ReadFLACAt},
+		{"tags.m4a", ReadMP4, ReadMP4At},
+		{"tags.wma", ReadASF, ReadASFAt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data := readTestdata(t, tt.file)
+			want, wantErr := tt.readAt(bytes.NewReader(data), int64(len(data)))
+			got, err := tt.read(filepath.Join("testdata", tt.file))
+			if got != want || (err == nil) != (wantErr == nil) {
+				t.Errorf("read = %+v, %v, want %+v, %v", got, err, want, wantErr)
+			}
+
+			missing := filepath.Join("testdata", "missing"+filepath.Ext(tt.file))
+			if _, err := tt.read(missing); err == nil || !strings.Contains(err.Error(), missing) {
+				t.Errorf("read of a missing file: error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package musiclib
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ComposerLexiconColumns is the schema of the composer_lexicon table
+const ComposerLexiconColumns = "name TEXT PRIMARY KEY, folded_name TEXT, source TEXT, added_date TEXT"
+
+// ComposerLexicon holds the composer and arranger names known to the library
+type ComposerLexicon struct {
+	names  map[string]string // folded name -> name as the librarian wrote it
+	common map[string]bool   // folded names that are also ordinary words
+	maxLen int               // longest name in words, used when matching fragments
+}
+
+// ComposerMatch is a lexicon name found in a piece of text
+type ComposerMatch struct {
+	Name       string
+	Matched    string
+	Confidence float64
+}
+
+// NewComposerLexicon builds a lexicon from a list of names
+func NewComposerLexicon(names []string) *ComposerLexicon {
+	lexicon := &ComposerLexicon{names: make(map[string]string), common: make(map[string]bool)}
+	for _, name := range names {
+		lexicon.add(name)
+	}
+	return lexicon
+}
+
+func (l *ComposerLexicon) add(name string) {
+	name = strings.TrimSpace(name)
+	folded := foldName(name)
+	if folded == "" {
+		return
+	}
+	if _, ok := l.names[folded]; !ok {
+		l.names[folded] = name
+	}
+	if words := len(wordSpans(name)); words > l.maxLen {
+		l.maxLen = words
+	}
+}
+
+// MarkCommon marks names that are also ordinary words or given names, such
+// as "Shaw" or "Christopher". They are matched in a composer field, but
+// only at commonNameConfidence, so they are not cut off the end of a title
+// or picked out of a filename.
+func (l *ComposerLexicon) MarkCommon(names []string) {
+	for _, name := range names {
+		if folded := foldName(name); folded != "" {
+			l.common[folded] = true
+		}
+	}
+}
+
+// Len returns the number of names in the lexicon
+func (l *ComposerLexicon) Len() int {
+	return len(l.names)
+}
+
+// ReadComposerSeedFile reads one name per line, skipping blank lines and #
+// comments. A name followed by "?" is also returned in common, for
+// ComposerLexicon.MarkCommon.
+func ReadComposerSeedFile(filename string) (names, common []string, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening composer seed file '%s': %v", filename, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name, ok := strings.CutSuffix(line, "?"); ok {
+			line = strings.TrimSpace(name)
+			common = append(common, line)
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error reading composer seed file '%s': %v", filename, err)
+	}
+
+	return names, common, nil
+}
+
+// CreateComposerLexiconTable creates the composer_lexicon table if it does not exist
+func CreateComposerLexiconTable(db LibraryStore) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS composer_lexicon (" + ComposerLexiconColumns + ")")
+	if err != nil {
+		return fmt.Errorf("error creating composer_lexicon table: %v", err)
+	}
+	return nil
+}
+
+// SeedComposerLexicon adds names to the composer_lexicon table, leaving
+// names that are already there untouched
+func SeedComposerLexicon(db LibraryStore, names []string, source string) error {
+	for _, name := range names {
+		if err := insertComposerName(db, name, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertComposerName(db LibraryStore, name, source string) error {
+	name = strings.TrimSpace(name)
+	if foldName(name) == "" {
+		return nil
+	}
+	_, err := db.Exec("INSERT OR IGNORE INTO composer_lexicon VALUES (?, ?, ?, ?)",
+		name, foldName(name), source, time.Now().Format("2006-01-02"))
+	if err != nil {
+		return fmt.Errorf("error adding '%s' to composer_lexicon: %v", name, err)
+	}
+	return nil
+}
+
+// LoadComposerLexicon reads every name from the composer_lexicon table
+func LoadComposerLexicon(db LibraryStore) (*ComposerLexicon, error) {
+	rows, err := db.Query("SELECT name FROM composer_lexicon")
+	if err != nil {
+		return nil, fmt.Errorf("error reading composer_lexicon: %v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return NewComposerLexicon(names), rows.Err()
+}
+
+// Confirm records a name a librarian entered in a correction, adding it to
+// both the lexicon and the composer_lexicon table
+func (l *ComposerLexicon) Confirm(db LibraryStore, name string) error {
+	if err := insertComposerName(db, name, "correction"); err != nil {
+		return err
+	}
+	l.add(name)
+	return nil
+}
+
+// maxEditDistance is how many typos a name of this length may contain and
+// still match. Short names must match exactly.
+func maxEditDistance(length int) int {
+	switch {
+	case length >= 9:
+		return 2
+	case length >= 5:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// commonNameConfidence is the confidence of a match to a name marked
+// common, which is below titleMatchConfidence
+const commonNameConfidence = 0.6
+
+// Match looks text up in the lexicon, allowing a few typos in longer names
+func (l *ComposerLexicon) Match(text string) (ComposerMatch, bool) {
+	match, ok := l.match(text)
+	if ok && l.common[foldName(match.Name)] {
+		match.Confidence = min(match.Confidence, commonNameConfidence)
+	}
+	return match, ok
+}
+
+func (l *ComposerLexicon) match(text string) (ComposerMatch, bool) {
+	folded := foldName(text)
+	if folded == "" {
+		return ComposerMatch{}, false
+	}
+	if name, ok := l.names[folded]; ok {
+		return ComposerMatch{Name: name, Matched: text, Confidence: 1.0}, true
+	}
+
+	limit := maxEditDistance(len(folded))
+	if limit == 0 {
+		return ComposerMatch{}, false
+	}
+
+	best := ComposerMatch{}
+	bestDistance := limit + 1
+	for key, name := range l.names {
+		if abs(len(key)-len(folded)) > limit {
+			continue
+		}
+		distance := editDistance(folded, key)
+		if distance < bestDistance || (distance == bestDistance && name < best.Name) {
+			bestDistance = distance
+			best = ComposerMatch{Name: name, Matched: text}
+		}
+	}
+	if bestDistance > limit {
+		return ComposerMatch{}, false
+	}
+
+	best.Confidence = 1.0 - float64(bestDistance)/float64(len(folded))
+	return best, true
+}
+
+// MatchTokens returns the lexicon names found among the words of text, such
+// as the composer field "Wilson_Hayes" or a PDF author "Music by Mack Wilberg".
+// Adjacent words are tried together so two-word names are found.
+func (l *ComposerLexicon) MatchTokens(text string) []ComposerMatch {
+	var matches []ComposerMatch
+	spans := wordSpans(text)
+
+	for i := 0; i < len(spans); {
+		found := false
+		for n := min(l.maxLen, len(spans)-i); n >= 1; n-- {
+			candidate := text[spans[i].Start:spans[i+n-1].End]
+			if match, ok := l.Match(candidate); ok {
+				matches = append(matches, match)
+				i += n
+				found = true
+				break
+			}
+		}
+		if !found {
+			i++
+		}
+	}
+
+	return matches
+}
+
+// titleMatchConfidence is the lowest confidence accepted when cutting a name
+// off a title, where a near miss would eat a real title word
+const titleMatchConfidence = 0.85
+
+// ExtractFromTitle removes a composer name from the end of a raw title, as in
+// "AveMariaBruckner" or "In Summer Billingsley". The title is returned
+// unchanged when its last words are not in the lexicon or are all it has. A
+// name marked common is only removed when an underscore sets it apart, as
+// in "OHolyNight_Solo_Adam".
+func (l *ComposerLexicon) ExtractFromTitle(title string) (string, ComposerMatch, bool) {
+	spans := wordSpans(title)
+
+	for n := min(l.maxLen, len(spans)-1); n >= 1; n-- {
+		first := spans[len(spans)-n]
+		match, ok := l.Match(title[first.Start:])
+		if !ok {
+			continue
+		}
+		if match.Confidence >= titleMatchConfidence || l.common[foldName(match.Name)] && strings.HasSuffix(title[:first.Start], "_") {
+			remaining := strings.TrimRight(title[:first.Start], " _.-+")
+			return remaining, match, true
+		}
+	}
+
+	return title, ComposerMatch{}, false
+}
+
+// FindInFilename returns the first confident lexicon name among the words of
+// a filename, for files whose pattern has no composer field
+func (l *ComposerLexicon) FindInFilename(filename string) (ComposerMatch, bool) {
+	for _, match := range l.MatchTokens(filename) {
+		if match.Confidence >= titleMatchConfidence {
+			return match, true
+		}
+	}
+	return ComposerMatch{}, false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
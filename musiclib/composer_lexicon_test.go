@@ -0,0 +1,189 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testComposerLexicon holds a few names from composers.txt, with the
+// common ones marked as they are there
+func testComposerLexicon() *ComposerLexicon {
+	lexicon := NewComposerLexicon([]string{"Bruckner", "Billingsley", "Rutter", "Lojeski", "Mack Wilberg", "Wilson", "Hayes", "Adam", "Shaw"})
+	lexicon.MarkCommon([]string{"Adam", "Shaw"})
+	return lexicon
+}
+
+func TestComposerLexiconMatch(t *testing.T) {
+	lexicon := testComposerLexicon()
+	tests := []struct {
+		text   string
+		want   ComposerMatch
+		wantOK bool
+	}{
+		{"Bruckner", ComposerMatch{Name: "Bruckner", Matched: "Bruckner", Confidence: 1}, true},
+		{"bruckner", ComposerMatch{Name: "Bruckner", Matched: "bruckner", Confidence: 1}, true},
+		{"mack wilberg", ComposerMatch{Name: "Mack Wilberg", Matched: "mack wilberg", Confidence: 1}, true},
+		{"Billingsly", ComposerMatch{Name: "Billingsley", Matched: "Billingsly", Confidence: 0.9}, true},
+		{"Ruttr", ComposerMatch{Name: "Rutter", Matched: "Ruttr", Confidence: 0.8}, true},
+		{"Shaw", ComposerMatch{Name: "Shaw", Matched: "Shaw", Confidence: commonNameConfidence}, true},
+		// Short names must match exactly
+		{"Shew", ComposerMatch{}, false},
+		// Half of a two-word name is not the name
+		{"Wilberg", ComposerMatch{}, false},
+		{"", ComposerMatch{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := lexicon.Match(tt.text)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Match() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestComposerLexiconExtractFromTitle(t *testing.T) {
+	lexicon := testComposerLexicon()
+	tests := []struct {
+		title    string
+		want     string
+		wantName string
+	}{
+		{"AveMariaBruckner", "AveMaria", "Bruckner"},
+		{"In Summer Billingsley", "In Summer", "Billingsley"},
+		{"Underneath The Tree Lojeski", "Underneath The Tree", "Lojeski"},
+		{"ShenandoahMackWilberg", "Shenandoah", "Mack Wilberg"},
+		// A common name needs an underscore before it
+		{"OHolyNight_Solo_Adam", "OHolyNight_Solo", "Adam"},
+		{"OHolyNightAdam", "OHolyNightAdam", ""},
+		// A title is never cut down to nothing
+		{"Bruckner", "Bruckner", ""},
+		{"LocusIste", "LocusIste", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got, match, ok := lexicon.ExtractFromTitle(tt.title)
+			if got != tt.want || match.Name != tt.wantName || ok != (tt.wantName != "") {
+				t.Errorf("ExtractFromTitle() = %q, %q, %v, want %q, %q", got, match.Name, ok, tt.want, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestComposerLexiconMatchTokens(t *testing.T) {
+	lexicon := testComposerLexicon()
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Wilson_Hayes", []string{"Wilson", "Hayes"}},
+		{"Music by Mack Wilberg", []string{"Mack Wilberg"}},
+		{"Arranged by Kirby Shaw", []string{"Shaw"}},
+		{"Hal Leonard", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var got []string
+			for _, match := range lexicon.MatchTokens(tt.text) {
+				got = append(got, match.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchTokens() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComposerLexiconFindInFilename(t *testing.T) {
+	lexicon := testComposerLexicon()
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"Underneath The Tree Lojeski.pdf", "Lojeski"},
+		// A common name is not picked out of a filename
+		{"ShawneeHymn_Shaw.pdf", ""},
+		{"AveMaria.pdf", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			match, ok := lexicon.FindInFilename(tt.filename)
+			if match.Name != tt.want || ok != (tt.want != "") {
+				t.Errorf("FindInFilename() = %q, %v, want %q", match.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadComposerSeedFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantNames  []string
+		wantCommon []string
+	}{
+		{
+			name:      "names and comments",
+			content:   "# Arrangers\nBillingsley\n\n  Mack Wilberg  \n",
+			wantNames: []string{"Billingsley", "Mack Wilberg"},
+		},
+		{
+			name:       "common names",
+			content:    "Beck ?\nRutter\nChristopher?\n",
+			wantNames:  []string{"Beck", "Rutter", "Christopher"},
+			wantCommon: []string{"Beck", "Christopher"},
+		},
+		{name: "only comments", content: "# Nothing yet\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, common, err := ReadComposerSeedFile(writeTemp(t, "composers.txt", []byte(tt.content)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) || !reflect.DeepEqual(common, tt.wantCommon) {
+				t.Errorf("ReadComposerSeedFile() = %q, %q, want %q, %q", names, common, tt.wantNames, tt.wantCommon)
+			}
+		})
+	}
+
+	if _, _, err := ReadComposerSeedFile(filepath.Join(t.TempDir(), "composers.txt")); err == nil {
+		t.Error("ReadComposerSeedFile() of a missing file succeeded")
+	}
+}
+
+func TestComposerLexiconConfirm(t *testing.T) {
+	for backend, store := range openTestStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			if err := CreateComposerLexiconTable(store); err != nil {
+				t.Fatal(err)
+			}
+			if err := SeedComposerLexicon(store, []string{"Bruckner", "Rutter", "Bruckner", " "}, "composers.txt"); err != nil {
+				t.Fatal(err)
+			}
+			lexicon, err := LoadComposerLexicon(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lexicon.Len() != 2 {
+				t.Errorf("Len() after seeding = %d, want 2", lexicon.Len())
+			}
+
+			// A librarian's correction is known at once and after a reload
+			if err := lexicon.Confirm(store, "Gjeilo"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := lexicon.Match("Gjeilo"); !ok {
+				t.Error("Match() of a confirmed name failed")
+			}
+			reloaded, err := LoadComposerLexicon(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := reloaded.Match("Gjeilo"); !ok || reloaded.Len() != 3 {
+				t.Errorf("reloaded lexicon: Match() ok = %v, Len() = %d, want true, 3", ok, reloaded.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package musiclib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the parts of config.yml used by the library scanner
+type Config struct {
+	FilenamePatterns []FilenamePattern     `yaml:"FilenamePatterns"`
+	ComposerLexicon  ComposerLexiconConfig `yaml:"ComposerLexicon"`
+	Instruments      []Instrument          `yaml:"Instruments"`
+	TitleNormalizer  TitleNormalizerConfig `yaml:"TitleNormalizer"`
+	Variants         []VariantMarker       `yaml:"Variants"`
+	Filing           FilingConfig          `yaml:"Filing"`
+	Publishers       []PublisherRule       `yaml:"Publishers"`
+	PathRules        []PathRule            `yaml:"PathRules"`
+	Database         DatabaseConfig        `yaml:"Database"`
+	GoDatabase       GoDatabaseConfig      `yaml:"GoDatabase"`
+}
+
+// DatabaseConfig is the SQLite database shared with the Python version of
+// the app
+type DatabaseConfig struct {
+	SQLite   bool   `yaml:"sqlite"`
+	Filename string `yaml:"database_filename"`
+}
+
+// GoDatabaseConfig is the DuckDB database of the Go version of the app
+type GoDatabaseConfig struct {
+	DuckDB   bool   `yaml:"duckdb"`
+	Filename string `yaml:"go_database_filename"`
+}
+
+// ComposerLexiconConfig names the file used to seed the composer lexicon
+type ComposerLexiconConfig struct {
+	SeedFile string `yaml:"seed_file"`
+}
+
+// DefaultConfig returns the configuration used when config.yml does not
+// provide a section
+func DefaultConfig() Config {
+	return Config{
+		FilenamePatterns: DefaultFilenamePatterns(),
+		ComposerLexicon:  ComposerLexiconConfig{SeedFile: "composers.txt"},
+		Instruments:      DefaultInstruments(),
+		TitleNormalizer:  DefaultTitleNormalizerConfig(),
+		Variants:         DefaultVariantMarkers(),
+		Filing:           DefaultFilingConfig(),
+		Publishers:       DefaultPublisherRules(),
+		PathRules:        DefaultPathRules(),
+		Database:         DatabaseConfig{Filename: "musiclibrary.db"},
+		GoDatabase:       GoDatabaseConfig{DuckDB: true, Filename: "musiclibrary.duckdb"},
+	}
+}
+
+// Store returns the backend and file the catalog is kept in: the DuckDB
+// database when GoDatabase.duckdb is set, else the SQLite database when
+// Database.sqlite is, and memory when neither is or the filename is
+// ":memory:"
+func (c Config) Store() (backend, filename string) {
+	switch {
+	case c.GoDatabase.DuckDB:
+		backend, filename = BackendDuckDB, c.GoDatabase.Filename
+	case c.Database.SQLite:
+		backend, filename = BackendSQLite, c.Database.Filename
+	default:
+		return BackendMemory, ""
+	}
+	if filename == ":memory:" {
+		return BackendMemory, ""
+	}
+	return backend, filename
+}
+
+// StoreFile is Store with a database file named on the command line in
+// place of the configured one, or Store itself when filename is empty. The
+// extension of the file picks its backend: .duckdb is DuckDB, and .db,
+// .sqlite and .sqlite3 are SQLite. A file of another extension has the
+// configured backend, or SQLite when that is memory, which keeps no file.
+func (c Config) StoreFile(filename string) (backend, name string) {
+	switch {
+	case filename == "":
+		return c.Store()
+	case filename == ":memory:":
+		return BackendMemory, ""
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".duckdb":
+		return BackendDuckDB, filename
+	case ".db", ".sqlite", ".sqlite3":
+		return BackendSQLite, filename
+	}
+	backend, _ = c.Store()
+	if backend == BackendMemory {
+		backend = BackendSQLite
+	}
+	return backend, filename
+}
+
+// LoadConfig reads config.yml and fills any missing sections with defaults
+func LoadConfig(filename string) (Config, error) {
+	config := DefaultConfig()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return config, fmt.Errorf("error reading config file '%s': %v", filename, err)
+	}
+
+	var fileConfig Config
+	err = yaml.Unmarshal(data, &fileConfig)
+	if err != nil {
+		return config, fmt.Errorf("error parsing config file '%s': %v", filename, err)
+	}
+
+	if len(fileConfig.FilenamePatterns) > 0 {
+		config.FilenamePatterns = fileConfig.FilenamePatterns
+	}
+	if fileConfig.ComposerLexicon.SeedFile != "" {
+		config.ComposerLexicon = fileConfig.ComposerLexicon
+	}
+	if len(fileConfig.Instruments) > 0 {
+		config.Instruments = fileConfig.Instruments
+	}
+	if len(fileConfig.TitleNormalizer.SmallWords) > 0 {
+		config.TitleNormalizer.SmallWords = fileConfig.TitleNormalizer.SmallWords
+	}
+	if len(fileConfig.TitleNormalizer.Contractions) > 0 {
+		config.TitleNormalizer.Contractions = fileConfig.TitleNormalizer.Contractions
+	}
+
+	if len(fileConfig.Variants) > 0 {
+		config.Variants = fileConfig.Variants
+	}
+	if len(fileConfig.Filing.Articles) > 0 {
+		config.Filing = fileConfig.Filing
+	}
+	if len(fileConfig.Publishers) > 0 {
+		config.Publishers = fileConfig.Publishers
+	}
+	if len(fileConfig.PathRules) > 0 {
+		config.PathRules = fileConfig.PathRules
+	}
+
+	// A database section's flag is taken as written, so "duckdb: false"
+	// turns off the default DuckDB database
+	var sections map[string]interface{}
+	if err := yaml.Unmarshal(data, &sections); err == nil {
+		if _, ok := sections["Database"]; ok {
+			config.Database.SQLite = fileConfig.Database.SQLite
+		}
+		if _, ok := sections["GoDatabase"]; ok {
+			config.GoDatabase.DuckDB = fileConfig.GoDatabase.DuckDB
+		}
+	}
+	if fileConfig.Database.Filename != "" {
+		config.Database.Filename = fileConfig.Database.Filename
+	}
+	if fileConfig.GoDatabase.Filename != "" {
+		config.GoDatabase.Filename = fileConfig.GoDatabase.Filename
+	}
+
+	return config, nil
+}
@@ -0,0 +1,69 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigStore(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		wantBackend string
+		wantFile    string
+	}{
+		{"config.yml sets both flags and DuckDB wins", filepath.Join("..", "config.yml"), BackendDuckDB, "musiclibrary.duckdb"},
+		{"no config file", filepath.Join("testdata", "missing.yml"), BackendDuckDB, "musiclibrary.duckdb"},
+		{"no database sections", writeTemp(t, "empty.yml", []byte("SoftwareAuthor: x\n")), BackendDuckDB, "musiclibrary.duckdb"},
+		{
+			name:        "DuckDB turned off",
+			path:        writeTemp(t, "sqlite.yml", []byte("Database:\n  sqlite: true\n  database_filename: trial.db\nGoDatabase:\n  duckdb: false\n")),
+			wantBackend: BackendSQLite,
+			wantFile:    "trial.db",
+		},
+		{"both turned off", writeTemp(t, "memory.yml", []byte("Database:\n  sqlite: false\nGoDatabase:\n  duckdb: false\n")), BackendMemory, ""},
+		{"in-memory filename", writeTemp(t, "inmemory.yml", []byte("GoDatabase:\n  duckdb: true\n  go_database_filename: \":memory:\"\n")), BackendMemory, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _ := LoadConfig(tt.path)
+			backend, filename := config.Store()
+			if backend != tt.wantBackend || filename != tt.wantFile {
+				t.Errorf("Store() = %q, %q, want %q, %q", backend, filename, tt.wantBackend, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestConfigStoreFile(t *testing.T) {
+	duckDB := DefaultConfig()
+	sqlite := DefaultConfig()
+	sqlite.GoDatabase.DuckDB, sqlite.Database.SQLite = false, true
+	memory := DefaultConfig()
+	memory.GoDatabase.DuckDB = false
+
+	tests := []struct {
+		name        string
+		config      Config
+		filename    string
+		wantBackend string
+		wantFile    string
+	}{
+		{"no file keeps the configured database", duckDB, "", BackendDuckDB, "musiclibrary.duckdb"},
+		{"SQLite file with DuckDB configured", duckDB, "musiclibrary.db", BackendSQLite, "musiclibrary.db"},
+		{"DuckDB file with SQLite configured", sqlite, "trial.duckdb", BackendDuckDB, "trial.duckdb"},
+		{"upper-case extension", duckDB, "TRIAL.SQLITE3", BackendSQLite, "TRIAL.SQLITE3"},
+		{"other extension keeps the configured backend", sqlite, "trial.catalog", BackendSQLite, "trial.catalog"},
+		{"other extension with memory configured", memory, "trial.catalog", BackendSQLite, "trial.catalog"},
+		{"SQLite file with memory configured", memory, "trial.db", BackendSQLite, "trial.db"},
+		{"in-memory filename", sqlite, ":memory:", BackendMemory, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, filename := tt.config.StoreFile(tt.filename)
+			if backend != tt.wantBackend || filename != tt.wantFile {
+				t.Errorf("StoreFile(%q) = %q, %q, want %q, %q", tt.filename, backend, filename, tt.wantBackend, tt.wantFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package musiclib
+
+import (
+	"fmt"
+)
+
+// FileHistoryColumns is the schema of the file_history table, which keeps
+// the date each file was first scanned. Paths are relative to the library
+// root, so the dates survive copying the library to another drive.
+const FileHistoryColumns = "path TEXT PRIMARY KEY, first_seen TEXT"
+
+// FileHistory holds the first-seen dates of the files scanned so far
+type FileHistory struct {
+	firstSeen map[string]string // relative path -> date first scanned
+	added     []string          // paths first seen in this scan
+}
+
+// NewFileHistory returns an empty history, in which every file is new
+func NewFileHistory() *FileHistory {
+	return &FileHistory{firstSeen: make(map[string]string)}
+}
+
+// CreateFileHistoryTable creates the file_history table if it does not exist
+func CreateFileHistoryTable(db LibraryStore) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS file_history (" + FileHistoryColumns + ")")
+	if err != nil {
+		return fmt.Errorf("error creating file_history table: %v", err)
+	}
+	return nil
+}
+
+// LoadFileHistory reads every first-seen date from the file_history table
+func LoadFileHistory(db LibraryStore) (*FileHistory, error) {
+	rows, err := db.Query("SELECT path, first_seen FROM file_history")
+	if err != nil {
+		return nil, fmt.Errorf("error reading file_history: %v", err)
+	}
+	defer rows.Close()
+
+	history := NewFileHistory()
+	for rows.Next() {
+		var path, firstSeen string
+		if err := rows.Scan(&path, &firstSeen); err != nil {
+			return nil, err
+		}
+		history.firstSeen[path] = firstSeen
+	}
+
+	return history, rows.Err()
+}
+
+// FirstSeen returns the date a file was first scanned, recording today for
+// a file the history has not seen before
+func (h *FileHistory) FirstSeen(path, today string) string {
+	if firstSeen, ok := h.firstSeen[path]; ok {
+		return firstSeen
+	}
+	h.firstSeen[path] = today
+	h.added = append(h.added, path)
+	return today
+}
+
+// Added returns the number of files first seen in this scan
+func (h *FileHistory) Added() int {
+	return len(h.added)
+}
+
+// SaveFileHistory writes the files first seen in this scan to the
+// file_history table
+func SaveFileHistory(db LibraryStore, history *FileHistory) error {
+	for _, path := range history.added {
+		_, err := db.Exec("INSERT OR IGNORE INTO file_history VALUES (?, ?)", path, history.firstSeen[path])
+		if err != nil {
+			return fmt.Errorf("error adding '%s' to file_history: %v", path, err)
+		}
+	}
+	history.added = nil
+	return nil
+}
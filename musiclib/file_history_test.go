@@ -0,0 +1,55 @@
+package musiclib
+
+import "testing"
+
+func TestFileHistory(t *testing.T) {
+	scans := []struct {
+		date      string
+		paths     []string
+		want      []string
+		wantAdded int
+	}{
+		{
+			date:      "2011-08-15",
+			paths:     []string{"2011 Christmas/JingleBellls_BC.pdf", "2011 Christmas/Christmas 2011.pdf"},
+			want:      []string{"2011-08-15", "2011-08-15"},
+			wantAdded: 2,
+		},
+		{
+			// The same file seen twice in one scan is added once
+			date:      "2014-10-27",
+			paths:     []string{"2011 Christmas/JingleBellls_BC.pdf", "2014 Christmas/BassParts/FrostyTheSnowman-Bass.pdf", "2014 Christmas/BassParts/FrostyTheSnowman-Bass.pdf"},
+			want:      []string{"2011-08-15", "2014-10-27", "2014-10-27"},
+			wantAdded: 1,
+		},
+		{
+			date:  "2025-06-09",
+			paths: []string{"2014 Christmas/BassParts/FrostyTheSnowman-Bass.pdf", "2011 Christmas/Christmas 2011.pdf"},
+			want:  []string{"2014-10-27", "2011-08-15"},
+		},
+	}
+	for backend, store := range openTestStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			if err := CreateFileHistoryTable(store); err != nil {
+				t.Fatal(err)
+			}
+			for _, scan := range scans {
+				history, err := LoadFileHistory(store)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i, path := range scan.paths {
+					if got := history.FirstSeen(path, scan.date); got != scan.want[i] {
+						t.Errorf("scan of %s: FirstSeen(%q) = %q, want %q", scan.date, path, got, scan.want[i])
+					}
+				}
+				if history.Added() != scan.wantAdded {
+					t.Errorf("scan of %s: Added() = %d, want %d", scan.date, history.Added(), scan.wantAdded)
+				}
+				if err := SaveFileHistory(store, history); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package musiclib
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileTimes are the timestamps the filesystem keeps for a file. Created is
+// the zero time when the filesystem does not record when a file was born.
+type FileTimes struct {
+	Created  time.Time
+	Modified time.Time
+}
+
+// ReadFileTimes returns the birth and modification times of a file. The
+// birth time survives edits but not a copy to another drive, which is why
+// the first time a file was scanned is kept in the file_history table.
+func ReadFileTimes(filePath string) (FileTimes, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return FileTimes{}, fmt.Errorf("error reading times of '%s': %v", filePath, err)
+	}
+
+	times := FileTimes{Modified: info.ModTime()}
+	if created, ok := birthTime(filePath, info); ok {
+		times.Created = created
+	}
+	return times, nil
+}
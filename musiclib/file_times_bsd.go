@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd
+
+package musiclib
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime reads the birth time stat returns on macOS and the BSDs
+func birthTime(_ string, info os.FileInfo) (time.Time, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Birthtimespec.Sec <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stat.Birthtimespec.Unix()), true
+}
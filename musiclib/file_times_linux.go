@@ -0,0 +1,23 @@
+//go:build linux
+
+package musiclib
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// birthTime asks statx for the birth time, which ext4, btrfs, xfs and
+// tmpfs record but older kernels and some network filesystems do not
+func birthTime(filePath string, _ os.FileInfo) (time.Time, bool) {
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, filePath, 0, unix.STATX_BTIME, &stx); err != nil {
+		return time.Time{}, false
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 || stx.Btime.Sec == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), true
+}
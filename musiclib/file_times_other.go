@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+
+package musiclib
+
+import (
+	"os"
+	"time"
+)
+
+// birthTime is not available on this platform
+func birthTime(_ string, _ os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,35 @@
+package musiclib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadFileTimes(t *testing.T) {
+	before := time.Now().Add(-time.Minute)
+	path := writeTemp(t, "Christmas 2011.pdf", readTestdata(t, "octavo.pdf"))
+	// As a copy from the old drive would leave it
+	modified := time.Date(2011, time.August, 15, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	times, err := ReadFileTimes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times.Modified.Equal(modified) {
+		t.Errorf("Modified = %v, want %v", times.Modified, modified)
+	}
+	// Not every filesystem records the birth time, but one that does
+	// records when the file was written, not its modified time
+	if !times.Created.IsZero() && times.Created.Before(before) {
+		t.Errorf("Created = %v, want the time the file was written", times.Created)
+	}
+
+	if _, err := ReadFileTimes(filepath.Join(t.TempDir(), "missing.pdf")); err == nil {
+		t.Error("ReadFileTimes() of a missing file succeeded")
+	}
+}
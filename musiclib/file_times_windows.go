@@ -0,0 +1,18 @@
+//go:build windows
+
+package musiclib
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime reads the creation time NTFS keeps for every file
+func birthTime(_ string, info os.FileInfo) (time.Time, bool) {
+	attributes, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, attributes.CreationTime.Nanoseconds()), true
+}
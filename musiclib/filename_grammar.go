@@ -0,0 +1,165 @@
+package musiclib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FilenamePattern is one naming convention declared in config.yml, such as
+// "{title}_{voicing}_{composer}.{ext}". Fields optionally overrides the
+// regular expression used for a named field in this pattern only.
+type FilenamePattern struct {
+	Name    string            `yaml:"name"`
+	Pattern string            `yaml:"pattern"`
+	Fields  map[string]string `yaml:"fields,omitempty"`
+}
+
+// FilenameMatch is the result of matching a filename against the grammar.
+// Order lists the fields in the order they appear in the pattern.
+type FilenameMatch struct {
+	Pattern string
+	Fields  map[string]string
+	Order   []string
+}
+
+// Field returns the value captured for name, or "" when the pattern has no such field
+func (m FilenameMatch) Field(name string) string {
+	return m.Fields[name]
+}
+
+// Token returns the position of a field in the pattern, counting from 1, or
+// 0 when the pattern has no such field
+func (m FilenameMatch) Token(name string) int {
+	for i, field := range m.Order {
+		if field == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// FilenameGrammar tries a filename against an ordered list of patterns
+type FilenameGrammar struct {
+	patterns []compiledPattern
+}
+
+type compiledPattern struct {
+	name   string
+	re     *regexp.Regexp
+	fields []string
+}
+
+// defaultFieldExpressions are used for fields a pattern does not override.
+// Anything not listed here matches any text.
+var defaultFieldExpressions = map[string]string{
+	"letter":  `[A-Za-z0-9]`,
+	"voicing": `[SATB]{2,8}(?:[-_ ]?[Dd]iv)?|(?i:\d[-_ ]?part(?:[-_ ]mixed)?|two[-_ ]?part|three[-_ ]?part|unison)`,
+	"ext":     `[A-Za-z0-9]+`,
+}
+
+var fieldPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// DefaultFilenamePatterns returns the naming conventions used by the library
+// before any were configured, most specific first
+func DefaultFilenamePatterns() []FilenamePattern {
+	return []FilenamePattern{
+		{Name: "letter_title_voicing_composer", Pattern: "{letter}_{title}_{voicing}_{composer}.{ext}"},
+		{Name: "title_voicing_composer", Pattern: "{title}_{voicing}_{composer}.{ext}"},
+		{Name: "spaced_title_voicing_composer", Pattern: "{title} {voicing} {composer}.{ext}"},
+		{Name: "title_part", Pattern: "{title} - {part}.{ext}"},
+		{Name: "title_only", Pattern: "{title}.{ext}"},
+	}
+}
+
+// NewFilenameGrammar compiles the patterns in the order given. fieldExpressions
+// replaces the built-in expression of a field for every pattern, such as a
+// {part} expression built from the instrument vocabulary.
+func NewFilenameGrammar(patterns []FilenamePattern, fieldExpressions map[string]string) (*FilenameGrammar, error) {
+	grammar := &FilenameGrammar{}
+
+	for i, pattern := range patterns {
+		name := pattern.Name
+		if name == "" {
+			name = fmt.Sprintf("pattern_%d", i+1)
+		}
+
+		compiled, err := compileFilenamePattern(name, pattern, fieldExpressions)
+		if err != nil {
+			return nil, err
+		}
+		grammar.patterns = append(grammar.patterns, compiled)
+	}
+
+	return grammar, nil
+}
+
+func compileFilenamePattern(name string, pattern FilenamePattern, fieldExpressions map[string]string) (compiledPattern, error) {
+	compiled := compiledPattern{name: name}
+	seen := make(map[string]bool)
+
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	last := 0
+	for _, loc := range fieldPlaceholder.FindAllStringSubmatchIndex(pattern.Pattern, -1) {
+		expr.WriteString(regexp.QuoteMeta(pattern.Pattern[last:loc[0]]))
+
+		field := pattern.Pattern[loc[2]:loc[3]]
+		if seen[field] {
+			return compiled, fmt.Errorf("error in filename pattern '%s': field {%s} used more than once", name, field)
+		}
+		seen[field] = true
+		compiled.fields = append(compiled.fields, field)
+
+		fieldExpr, ok := pattern.Fields[field]
+		if !ok {
+			fieldExpr, ok = fieldExpressions[field]
+		}
+		if !ok {
+			fieldExpr, ok = defaultFieldExpressions[field]
+		}
+		if !ok {
+			fieldExpr = `.+?`
+		}
+		expr.WriteString("(?P<" + field + ">" + fieldExpr + ")")
+
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern.Pattern[last:]))
+	expr.WriteString("$")
+
+	if len(compiled.fields) == 0 {
+		return compiled, fmt.Errorf("error in filename pattern '%s': no {fields} in '%s'", name, pattern.Pattern)
+	}
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return compiled, fmt.Errorf("error compiling filename pattern '%s': %v", name, err)
+	}
+	compiled.re = re
+
+	return compiled, nil
+}
+
+// Match returns the first pattern that matches the whole filename
+func (g *FilenameGrammar) Match(filename string) (FilenameMatch, bool) {
+	for _, pattern := range g.patterns {
+		groups := pattern.re.FindStringSubmatch(filename)
+		if groups == nil {
+			continue
+		}
+
+		match := FilenameMatch{
+			Pattern: pattern.name,
+			Fields:  make(map[string]string, len(pattern.fields)),
+			Order:   pattern.fields,
+		}
+		for _, field := range pattern.fields {
+			match.Fields[field] = strings.TrimSpace(groups[pattern.re.SubexpIndex(field)])
+		}
+		return match, true
+	}
+
+	return FilenameMatch{}, false
+}
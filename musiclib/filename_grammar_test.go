@@ -0,0 +1,145 @@
+package musiclib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilenameGrammarMatch(t *testing.T) {
+	grammar, err := NewFilenameGrammar(DefaultFilenamePatterns(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		filename    string
+		wantPattern string
+		want        map[string]string
+		wantOK      bool
+	}{
+		{
+			filename:    "S_SomebodyToLove_SATB_Emerson.pdf",
+			wantPattern: "letter_title_voicing_composer",
+			want:        map[string]string{"letter": "S", "title": "SomebodyToLove", "voicing": "SATB", "composer": "Emerson", "ext": "pdf"},
+			wantOK:      true,
+		},
+		{
+			filename:    "P_PleaseKindSir_TB_PDQBach.pdf",
+			wantPattern: "letter_title_voicing_composer",
+			want:        map[string]string{"letter": "P", "title": "PleaseKindSir", "voicing": "TB", "composer": "PDQBach", "ext": "pdf"},
+			wantOK:      true,
+		},
+		{
+			filename:    "TaylorTheLatteBoy_SSA_Huff.pdf",
+			wantPattern: "title_voicing_composer",
+			want:        map[string]string{"title": "TaylorTheLatteBoy", "voicing": "SSA", "composer": "Huff", "ext": "pdf"},
+			wantOK:      true,
+		},
+		{
+			filename:    "Silver Bells SATB Robison.pdf",
+			wantPattern: "spaced_title_voicing_composer",
+			want:        map[string]string{"title": "Silver Bells", "voicing": "SATB", "composer": "Robison", "ext": "pdf"},
+			wantOK:      true,
+		},
+		{
+			filename:    "AHollyJollyChristmas_Bass - Electric Bass.pdf",
+			wantPattern: "title_part",
+			want:        map[string]string{"title": "AHollyJollyChristmas_Bass", "part": "Electric Bass", "ext": "pdf"},
+			wantOK:      true,
+		},
+		{
+			// Without a voicing the trailing composer stays in the title
+			filename:    "A Holly Jolly Christmas Gilpin.pdf",
+			wantPattern: "title_only",
+			want:        map[string]string{"title": "A Holly Jolly Christmas Gilpin", "ext": "pdf"},
+			wantOK:      true,
+		},
+		{
+			filename:    "Do You Hear What I Hear..pdf",
+			wantPattern: "title_only",
+			want:        map[string]string{"title": "Do You Hear What I Hear.", "ext": "pdf"},
+			wantOK:      true,
+		},
+		{filename: "README"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			got, ok := grammar.Match(tt.filename)
+			if ok != tt.wantOK {
+				t.Fatalf("Match() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got.Pattern != tt.wantPattern {
+				t.Errorf("Match() pattern = %q, want %q", got.Pattern, tt.wantPattern)
+			}
+			if tt.wantOK && !reflect.DeepEqual(got.Fields, tt.want) {
+				t.Errorf("Match() fields = %q, want %q", got.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilenameMatchToken(t *testing.T) {
+	grammar, err := NewFilenameGrammar(DefaultFilenamePatterns(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, _ := grammar.Match("S_SomebodyToLove_SATB_Emerson.pdf")
+	tests := []struct {
+		field string
+		want  int
+	}{
+		{"letter", 1},
+		{"title", 2},
+		{"composer", 4},
+		{"part", 0},
+	}
+	for _, tt := range tests {
+		if got := match.Token(tt.field); got != tt.want {
+			t.Errorf("Token(%q) = %d, want %d", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestNewFilenameGrammar(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []FilenamePattern
+		fields   map[string]string
+		filename string
+		want     map[string]string
+		wantOK   bool
+	}{
+		{
+			name:     "a field expression for every pattern",
+			patterns: []FilenamePattern{{Pattern: "{title}-{part}.{ext}"}},
+			fields:   map[string]string{"part": "(?i:bass|alto)"},
+			filename: "AMerryChristmasWish-Bass.pdf",
+			want:     map[string]string{"title": "AMerryChristmasWish", "part": "Bass", "ext": "pdf"},
+			wantOK:   true,
+		},
+		{
+			name:     "a field expression of one pattern",
+			patterns: []FilenamePattern{{Pattern: "{title}-{voicing}.{ext}", Fields: map[string]string{"voicing": "[SATB]+"}}},
+			filename: "Circle-SSAA.pdf",
+			want:     map[string]string{"title": "Circle", "voicing": "SSAA", "ext": "pdf"},
+			wantOK:   true,
+		},
+		{name: "a field used twice", patterns: []FilenamePattern{{Pattern: "{title}_{title}.{ext}"}}},
+		{name: "no fields", patterns: []FilenamePattern{{Pattern: "score.pdf"}}},
+		{name: "a bad field expression", patterns: []FilenamePattern{{Pattern: "{title}.pdf", Fields: map[string]string{"title": "("}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grammar, err := NewFilenameGrammar(tt.patterns, tt.fields)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("NewFilenameGrammar() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			got, ok := grammar.Match(tt.filename)
+			if !ok || got.Pattern != "pattern_1" || !reflect.DeepEqual(got.Fields, tt.want) {
+				t.Errorf("Match(%q) = %+v, %v, want fields %q", tt.filename, got, ok, tt.want)
+			}
+		})
+	}
+}
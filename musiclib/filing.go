@@ -0,0 +1,165 @@
+package musiclib
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// FilingConfig is the Filing section of config.yml. Articles are the
+// leading words skipped when filing a title; an article ending in an
+// apostrophe, such as "l'", is an elided prefix as in "L'Amour".
+type FilingConfig struct {
+	Articles      []string `yaml:"articles"`
+	SkipBracketed bool     `yaml:"skip_bracketed"`
+	SkipDigits    bool     `yaml:"skip_digits"`
+}
+
+// DefaultFilingConfig returns the filing rules used before they were
+// configured. "O" and "I" are not articles here, so "O Holy Night" and
+// "I Want a Hippopotamus" file under their first word, and neither are
+// Italian "lo" and German "die", which are also English words ("Lo, How a
+// Rose E'er Blooming", "Die Young").
+func DefaultFilingConfig() FilingConfig {
+	return FilingConfig{
+		Articles: []string{
+			"the", "a", "an", // English
+			"le", "la", "les", "l'", "un", "une", // French
+			"el", "los", "las", "una", // Spanish
+			"il", "gli", // Italian
+			"der", "das", "ein", "eine", // German
+		},
+		SkipBracketed: true,
+		SkipDigits:    true,
+	}
+}
+
+// FilingRules decide where a title is filed in the binders and printed
+// catalog: its alphabetizing letter and its sort key
+type FilingRules struct {
+	articles      map[string]bool
+	elisions      []string
+	skipBracketed bool
+	skipDigits    bool
+}
+
+// NewFilingRules builds filing rules from their configuration
+func NewFilingRules(config FilingConfig) *FilingRules {
+	rules := &FilingRules{
+		articles:      make(map[string]bool),
+		skipBracketed: config.SkipBracketed,
+		skipDigits:    config.SkipDigits,
+	}
+	for _, article := range config.Articles {
+		article = strings.ToLower(strings.TrimSpace(article))
+		if strings.HasSuffix(article, "'") {
+			rules.elisions = append(rules.elisions, article)
+		} else if article != "" {
+			rules.articles[article] = true
+		}
+	}
+	return rules
+}
+
+var (
+	bracketedPrefix = regexp.MustCompile(`^[\s_\-.]*(?:\[[^\]]*\]|\([^)]*\)|\{[^}]*\})[\s_\-.]*`)
+	leadingNonWord  = regexp.MustCompile(`^[^\pL\pN]+`)
+	leadingDigits   = regexp.MustCompile(`^(?:[\pN#]+[\s_\-.,:;]+)+`)
+	digitRun        = regexp.MustCompile(`\d+`)
+)
+
+// FilingTitle returns the part of a title it is filed under, with
+// bracketed source prefixes, leading digits and a leading article removed:
+// "[Free-scores.com] Amazing Grace" and "The Presidents" are filed as
+// "Amazing Grace" and "Presidents"
+func (r *FilingRules) FilingTitle(title string) string {
+	filing := strings.TrimSpace(title)
+
+	for {
+		before := filing
+		if r.skipBracketed {
+			filing = bracketedPrefix.ReplaceAllString(filing, "")
+		}
+		filing = leadingNonWord.ReplaceAllString(filing, "")
+		if r.skipDigits {
+			if rest := leadingDigits.ReplaceAllString(filing, ""); rest != "" {
+				filing = rest
+			}
+		}
+		if filing == before {
+			break
+		}
+	}
+	filing = r.skipArticle(filing)
+
+	if filing == "" {
+		return strings.TrimSpace(title)
+	}
+	return filing
+}
+
+// skipArticle removes one leading article when a word follows it
+func (r *FilingRules) skipArticle(title string) string {
+	lower := strings.ToLower(title)
+	for _, elision := range r.elisions {
+		for _, apostrophe := range []string{"'", "’"} {
+			prefix := strings.TrimSuffix(elision, "'") + apostrophe
+			if strings.HasPrefix(lower, prefix) && len(title) > len(prefix) {
+				return title[len(prefix):]
+			}
+		}
+	}
+
+	first, rest, found := strings.Cut(title, " ")
+	if found && strings.TrimSpace(rest) != "" && r.articles[strings.ToLower(first)] {
+		return strings.TrimSpace(rest)
+	}
+	return title
+}
+
+// Letter returns the alphabetizing letter of a title, or "#" when the title
+// has no letters at all
+func (r *FilingRules) Letter(title string) string {
+	for _, c := range foldAccents(r.FilingTitle(title)) {
+		if unicode.IsLetter(c) {
+			return strings.ToUpper(string(c))
+		}
+	}
+	return "#"
+}
+
+// SortKey returns the key a title is sorted by: the filing title folded to
+// lowercase without accents or punctuation, with numbers padded so
+// "Psalm 9" sorts before "Psalm 23"
+func (r *FilingRules) SortKey(title string) string {
+	var words []string
+	for _, word := range strings.FieldsFunc(foldAccents(r.FilingTitle(title)), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '\'' && c != '’'
+	}) {
+		word = strings.NewReplacer("'", "", "’", "").Replace(strings.ToLower(word))
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+
+	key := strings.Join(words, " ")
+	return digitRun.ReplaceAllStringFunc(key, func(digits string) string {
+		if len(digits) >= 8 {
+			return digits
+		}
+		return strings.Repeat("0", 8-len(digits)) + digits
+	})
+}
+
+// foldAccents removes accents, so "Noël" files next to "Noel"
+func foldAccents(s string) string {
+	var b strings.Builder
+	for _, c := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, c) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
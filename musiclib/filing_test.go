@@ -0,0 +1,58 @@
+package musiclib
+
+import "testing"
+
+func TestFilingRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     FilingConfig
+		title      string
+		wantFiling string
+		wantLetter string
+		wantKey    string
+	}{
+		{"bracketed source", DefaultFilingConfig(), "[Free-scores.com] Amazing Grace", "Amazing Grace", "A", "amazing grace"},
+		{"parenthesized credit", DefaultFilingConfig(), "(Arr. Huff) Silent Night", "Silent Night", "S", "silent night"},
+		{"English article", DefaultFilingConfig(), "The Presidents", "Presidents", "P", "presidents"},
+		{"English article a", DefaultFilingConfig(), "A Holly Jolly Christmas", "Holly Jolly Christmas", "H", "holly jolly christmas"},
+		{"French article", DefaultFilingConfig(), "Les Misérables Medley", "Misérables Medley", "M", "miserables medley"},
+		{"Spanish article", DefaultFilingConfig(), "El Grillo", "Grillo", "G", "grillo"},
+		{"elided article", DefaultFilingConfig(), "L'Amour", "Amour", "A", "amour"},
+		{"elided article with a typographic apostrophe", DefaultFilingConfig(), "L’Amour", "Amour", "A", "amour"},
+		{"O is not an article", DefaultFilingConfig(), "O Holy Night", "O Holy Night", "O", "o holy night"},
+		{"Lo is not an article", DefaultFilingConfig(), "Lo, How a Rose E'er Blooming", "Lo, How a Rose E'er Blooming", "L", "lo how a rose eer blooming"},
+		{"an article alone", DefaultFilingConfig(), "The", "The", "T", "the"},
+		{"leading number", DefaultFilingConfig(), "12 Days of Christmas", "Days of Christmas", "D", "days of christmas"},
+		{"leading number sign", DefaultFilingConfig(), "#1 Crush", "Crush", "C", "crush"},
+		{"only digits", DefaultFilingConfig(), "2011", "2011", "#", "00002011"},
+		{"padded numbers", DefaultFilingConfig(), "Psalm 9", "Psalm 9", "P", "psalm 00000009"},
+		{"accents", DefaultFilingConfig(), "Ève", "Ève", "E", "eve"},
+		{"no rules", FilingConfig{}, "The Presidents", "The Presidents", "T", "the presidents"},
+		{"no rules and a bracketed source", FilingConfig{}, "[Free-scores.com] Amazing Grace", "Free-scores.com] Amazing Grace", "F", "free scores com amazing grace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := NewFilingRules(tt.config)
+			if got := rules.FilingTitle(tt.title); got != tt.wantFiling {
+				t.Errorf("FilingTitle(%q) = %q, want %q", tt.title, got, tt.wantFiling)
+			}
+			if got := rules.Letter(tt.title); got != tt.wantLetter {
+				t.Errorf("Letter(%q) = %q, want %q", tt.title, got, tt.wantLetter)
+			}
+			if got := rules.SortKey(tt.title); got != tt.wantKey {
+				t.Errorf("SortKey(%q) = %q, want %q", tt.title, got, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestFilingRulesSortOrder(t *testing.T) {
+	rules := NewFilingRules(DefaultFilingConfig())
+	// In binder order
+	titles := []string{"The Presidents", "Psalm 9", "Psalm 23", "Silent Night", "A Silent Prayer"}
+	for i := 1; i < len(titles); i++ {
+		if rules.SortKey(titles[i-1]) >= rules.SortKey(titles[i]) {
+			t.Errorf("%q sorts after %q", titles[i-1], titles[i])
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package musiclib
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// vorbisCommentFields maps the Vorbis comment names used by Ogg and FLAC
+// files to the tags they hold
+var vorbisCommentFields = map[string]string{
+	"TITLE":       "title",
+	"ARTIST":      "artist",
+	"PERFORMER":   "artist",
+	"ALBUM":       "album",
+	"COMPOSER":    "composer",
+	"TRACKNUMBER": "track",
+	"DATE":        "year",
+	"YEAR":        "year",
+	"COMMENT":     "comment",
+	"DESCRIPTION": "comment",
+	"PART":        "part",
+	"VOICE":       "part",
+}
+
+// parseVorbisComment reads a Vorbis comment block: a vendor string and a
+// list of NAME=value comments, with little-endian lengths
+func parseVorbisComment(data []byte) (AudioTags, error) {
+	var tags AudioTags
+	next := func() ([]byte, bool) {
+		if len(data) < 4 {
+			return nil, false
+		}
+		length := binary.LittleEndian.Uint32(data)
+		if uint64(length) > uint64(len(data)-4) {
+			return nil, false
+		}
+		value := data[4 : 4+length]
+		data = data[4+length:]
+		return value, true
+	}
+
+	if _, ok := next(); !ok {
+		return tags, errors.New("truncated Vorbis comment vendor")
+	}
+	if len(data) < 4 {
+		return tags, errors.New("truncated Vorbis comment list")
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+	for i := uint32(0); i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return tags, errors.New("truncated Vorbis comment")
+		}
+		name, value, ok := strings.Cut(strings.ToValidUTF8(string(comment), "�"), "=")
+		if !ok {
+			continue
+		}
+		if field, ok := vorbisCommentFields[strings.ToUpper(name)]; ok {
+			tags.set(field, value)
+		}
+	}
+	return tags, nil
+}
+
+// FLAC metadata block types
+const (
+	flacStreamInfo    = 0
+	flacVorbisComment = 4
+)
+
+// ReadFLAC reads the STREAMINFO and VORBIS_COMMENT metadata blocks of a
+// FLAC file. An ID3v2 tag some programs put before the stream is skipped.
+func ReadFLAC(filePath string) (AudioInfo, error) {
+	return readAudioFile(filePath, "FLAC", ReadFLACAt)
+}
+
+// ReadFLACAt reads FLAC data of the given size, as ReadFLAC reads a file
+func ReadFLACAt(r io.ReaderAt, size int64) (AudioInfo, error) {
+	info := AudioInfo{Format: "FLAC", TagFormat: "Vorbis comment"}
+	var offset int64
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header, 0); err == nil && string(header[:3]) == "ID3" {
+		offset = int64(syncsafe(header[6:10])) + 10
+	}
+	marker := make([]byte, 4)
+	if _, err := r.ReadAt(marker, offset); err != nil || string(marker) != "fLaC" {
+		return info, errors.New("no fLaC marker")
+	}
+	offset += 4
+
+	var totalSamples int64
+	streamInfo := false
+	for last := false; !last; {
+		blockHeader := make([]byte, 4)
+		if _, err := r.ReadAt(blockHeader, offset); err != nil {
+			return info, errors.New("truncated metadata")
+		}
+		last = blockHeader[0]&0x80 != 0
+		blockType := blockHeader[0] & 0x7F
+		length := int64(blockHeader[1])<<16 | int64(blockHeader[2])<<8 | int64(blockHeader[3])
+		offset += 4
+
+		switch blockType {
+		case flacStreamInfo, flacVorbisComment:
+			block := make([]byte, length)
+			if _, err := r.ReadAt(block, offset); err == io.EOF {
+				return info, errors.New("truncated metadata")
+			} else if err != nil {
+				return info, err
+			}
+			if blockType == flacStreamInfo {
+				if length < 18 {
+					return info, errors.New("short STREAMINFO block")
+				}
+				packed := binary.BigEndian.Uint64(block[10:18])
+				info.Properties.SampleRate = int(packed >> 44)
+				info.Properties.Channels = int(packed>>41&0x07) + 1
+				totalSamples = int64(packed & 0xFFFFFFFFF)
+				streamInfo = true
+			} else {
+				tags, err := parseVorbisComment(block)
+				info.Tags.fillBlank(tags)
+				if err != nil {
+					return info, err
+				}
+			}
+		}
+		offset += length
+	}
+
+	if !streamInfo {
+		return info, errors.New("no STREAMINFO block")
+	}
+	if info.Properties.SampleRate > 0 && totalSamples > 0 {
+		seconds := float64(totalSamples) / float64(info.Properties.SampleRate)
+		info.Properties.Duration = secondsDuration(seconds)
+		// A padding block may claim more bytes than the file has left
+		audioBytes := max(size-offset, 0)
+		info.Properties.Bitrate = int(float64(audioBytes) * 8 / seconds / 1000)
+	}
+	return info, nil
+}
@@ -0,0 +1,57 @@
+package musiclib
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadFLACAt(t *testing.T) {
+	flac := readTestdata(t, "comments.flac")
+	noMetadata := AudioInfo{Format: "FLAC", TagFormat: "Vorbis comment"}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   AudioInfo
+		wantOK bool
+	}{
+		{
+			name: "Vorbis comment",
+			data: flac,
+			want: AudioInfo{Format: "FLAC", TagFormat: "Vorbis comment",
+				Tags:       AudioTags{Title: "Locus Iste", Composer: "Anton Bruckner", Track: "2"},
+				Properties: AudioProperties{Duration: 200000000000, SampleRate: 48000, Channels: 2}},
+			wantOK: true,
+		},
+		{name: "empty", want: noMetadata},
+		{name: "cut after the marker", data: flac[:4], want: noMetadata},
+		{name: "cut in STREAMINFO", data: flac[:20], want: noMetadata},
+		{
+			name: "cut in the Vorbis comment",
+			data: flac[:60],
+			want: AudioInfo{Format: "FLAC", TagFormat: "Vorbis comment", Properties: AudioProperties{SampleRate: 48000, Channels: 2}},
+		},
+		{name: "not FLAC", data: readTestdata(t, "id3v23.mp3"), want: noMetadata},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFLACAt(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadFLACAt() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ReadFLACAt() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReadFLACAt(f *testing.F) {
+	f.Add(readTestdata(f, "comments.flac"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadFLACAt(bytes.NewReader(data), int64(len(data)))
+		if err == nil && (info.Properties.Duration < 0 || info.Properties.Bitrate < 0) {
+			t.Errorf("negative duration %v or bitrate %d", info.Properties.Duration, info.Properties.Bitrate)
+		}
+	})
+}
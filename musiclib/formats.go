@@ -0,0 +1,240 @@
+package musiclib
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Kinds of file a format can hold
+const (
+	KindDocument = "document"
+	KindAudio    = "audio"
+	KindScore    = "score"
+)
+
+// How DetectFormat recognized a file
+const (
+	DetectedBySignature = "signature"
+	DetectedByExtension = "extension"
+)
+
+// sniffLength is how much of the start of a file is read to detect its format
+const sniffLength = 8192
+
+// Format describes one file format the scanner recognizes. Sniff reports
+// whether the start of a file is in the format; a format without Sniff is
+// recognized by its extensions alone. ReadAudio, when set, reads the tags
+// and stream properties of an audio file in the format, and ReadScore the
+// credits, signatures and part ranges of a score.
+type Format struct {
+	Name       string
+	Kind       string
+	Extensions []string
+	Sniff      func(header []byte) bool
+	ReadAudio  func(filePath string) (AudioInfo, error)
+	ReadScore  func(filePath string) (ScoreInfo, error)
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   []Format
+)
+
+// RegisterFormat adds a format to the registry. Formats are sniffed in the
+// order they were registered, so a format with a narrower signature, such
+// as MuseScore's zip archives, must be registered before a broader one.
+func RegisterFormat(format Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats = append(formats, format)
+}
+
+// Formats returns the registered formats in registration order
+func Formats() []Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return append([]Format(nil), formats...)
+}
+
+// FormatByName returns the first registered format with a name
+func FormatByName(name string) (Format, bool) {
+	for _, format := range Formats() {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// DetectFormat returns the format of a file from its contents, or from its
+// extension when no format recognizes the contents, and which of the two
+// gave it away. A file in a folder named "mp3 rehearsal tracks" is not
+// taken for an MP3 file.
+func DetectFormat(filePath string) (Format, string, bool) {
+	var header []byte
+	if file, err := os.Open(filePath); err == nil {
+		buffer := make([]byte, sniffLength)
+		n, _ := io.ReadFull(file, buffer)
+		file.Close()
+		header = buffer[:n]
+	}
+	return DetectFormatFromHeader(filepath.Base(filePath), header)
+}
+
+// DetectFormatFromHeader returns the format of a file from the start of
+// its contents, falling back to the extension of its name
+func DetectFormatFromHeader(name string, header []byte) (Format, string, bool) {
+	registered := Formats()
+
+	if len(header) > 0 {
+		for _, format := range registered {
+			if format.Sniff != nil && format.Sniff(header) {
+				return format, DetectedBySignature, true
+			}
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return Format{}, "", false
+	}
+	for _, format := range registered {
+		for _, formatExt := range format.Extensions {
+			if strings.EqualFold(formatExt, ext) {
+				return format, DetectedByExtension, true
+			}
+		}
+	}
+	return Format{}, "", false
+}
+
+// hasPrefix returns a sniffer for formats that start with a signature
+func hasPrefix(signature string) func([]byte) bool {
+	return func(header []byte) bool {
+		return bytes.HasPrefix(header, []byte(signature))
+	}
+}
+
+var (
+	zipSignature     = []byte("PK\x03\x04")
+	asfHeaderGUID    = []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	musicXMLElements = [][]byte{[]byte("<score-partwise"), []byte("<score-timewise"), []byte("MusicXML")}
+)
+
+// sniffPDF finds the %PDF- marker, which readers accept anywhere in the
+// first kilobyte
+func sniffPDF(header []byte) bool {
+	return bytes.Contains(header[:min(len(header), 1024)], []byte("%PDF-"))
+}
+
+// sniffMP3 recognizes an ID3v2 tag or an MPEG audio frame header
+func sniffMP3(header []byte) bool {
+	if bytes.HasPrefix(header, []byte("ID3")) {
+		return true
+	}
+	if len(header) < 3 || header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return false
+	}
+	version := header[1] >> 3 & 0x03
+	layer := header[1] >> 1 & 0x03
+	bitrate := header[2] >> 4
+	sampleRate := header[2] >> 2 & 0x03
+	return version != 0x01 && layer != 0x00 && bitrate != 0x0F && sampleRate != 0x03
+}
+
+func sniffWAV(header []byte) bool {
+	return len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "WAVE"
+}
+
+// sniffMIDI recognizes a Standard MIDI File or one wrapped in a RIFF RMID
+// chunk
+func sniffMIDI(header []byte) bool {
+	if bytes.HasPrefix(header, []byte("MThd")) {
+		return true
+	}
+	return len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "RMID"
+}
+
+// mp4Brand returns the major brand of an ISO media file's ftyp box
+func mp4Brand(header []byte) (string, bool) {
+	if len(header) < 12 || string(header[4:8]) != "ftyp" {
+		return "", false
+	}
+	return string(header[8:12]), true
+}
+
+func sniffM4A(header []byte) bool {
+	brand, ok := mp4Brand(header)
+	return ok && (brand == "M4A " || brand == "M4B " || brand == "M4P ")
+}
+
+func sniffMP4(header []byte) bool {
+	_, ok := mp4Brand(header)
+	return ok
+}
+
+// sniffXML returns a sniffer for XML documents that name one of elements,
+// such as the root element or doctype of an uncompressed MusicXML score,
+// near the start of the file
+func sniffXML(elements ...[]byte) func([]byte) bool {
+	return func(header []byte) bool {
+		text := bytes.TrimLeft(header, "\xef\xbb\xbf \t\r\n")
+		if !bytes.HasPrefix(text, []byte("<")) {
+			return false
+		}
+		for _, element := range elements {
+			if bytes.Contains(header, element) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sniffZipEntry returns a sniffer for zip archives with an entry name or
+// stored content containing marker. Entry names of the local headers at
+// the start of the archive are stored uncompressed.
+func sniffZipEntry(markers ...string) func([]byte) bool {
+	return func(header []byte) bool {
+		if !bytes.HasPrefix(header, zipSignature) {
+			return false
+		}
+		for _, marker := range markers {
+			if bytes.Contains(header, []byte(marker)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func init() {
+	for _, format := range []Format{
+		{Name: "PDF", Kind: KindDocument, Extensions: []string{".pdf"}, Sniff: sniffPDF},
+		{Name: "OGG", Kind: KindAudio, Extensions: []string{".ogg", ".oga", ".opus"}, Sniff: hasPrefix("OggS"), ReadAudio: ReadOgg},
+		{Name: "FLAC", Kind: KindAudio, Extensions: []string{".flac"}, Sniff: hasPrefix("fLaC"), ReadAudio: ReadFLAC},
+		{Name: "WAV", Kind: KindAudio, Extensions: []string{".wav"}, Sniff: sniffWAV},
+		{Name: "M4A", Kind: KindAudio, Extensions: []string{".m4a", ".m4b"}, Sniff: sniffM4A, ReadAudio: ReadMP4},
+		{Name: "MP4", Kind: KindAudio, Extensions: []string{".mp4"}, Sniff: sniffMP4, ReadAudio: ReadMP4},
+		{Name: "WMA", Kind: KindAudio, Extensions: []string{".wma", ".asf"}, Sniff: hasPrefix(string(asfHeaderGUID)), ReadAudio: ReadASF},
+		{Name: "MIDI", Kind: KindScore, Extensions: []string{".mid", ".midi", ".kar", ".rmi"}, Sniff: sniffMIDI, ReadScore: ReadMIDI},
+		{Name: "MuseScore", Kind: KindScore, Extensions: []string{".mscz"}, Sniff: sniffZipEntry(".mscx"), ReadScore: ReadMuseScore},
+		{Name: "MuseScore", Kind: KindScore, Extensions: []string{".mscx"}, Sniff: sniffXML([]byte("<museScore")), ReadScore: ReadMuseScore},
+		{Name: "Finale", Kind: KindScore, Extensions: []string{".musx"}, Sniff: sniffZipEntry("NotationMetadata.xml")},
+		{Name: "MXL", Kind: KindScore, Extensions: []string{".mxl"}, Sniff: sniffZipEntry("application/vnd.recordare.musicxml", "META-INF/container.xml"), ReadScore: ReadMusicXML},
+		{Name: "Finale", Kind: KindScore, Extensions: []string{".mus"}, Sniff: hasPrefix("ENIGMA")},
+		{Name: "Sibelius", Kind: KindScore, Extensions: []string{".sib"}, Sniff: hasPrefix("\x0fSIBELIUS")},
+		{Name: "MusicXML", Kind: KindScore, Extensions: []string{".musicxml", ".xml"}, Sniff: sniffXML(musicXMLElements...), ReadScore: ReadMusicXML},
+		{Name: "ABC", Kind: KindScore, Extensions: []string{".abc"}, Sniff: sniffABC, ReadScore: ReadABC},
+		{Name: "LilyPond", Kind: KindScore, Extensions: []string{".ly", ".ily"}, Sniff: sniffLilyPond, ReadScore: ReadLilyPond},
+		// MPEG frame headers are short and can turn up by chance, so MP3 is
+		// sniffed after the formats with longer signatures
+		{Name: "MP3", Kind: KindAudio, Extensions: []string{".mp3"}, Sniff: sniffMP3, ReadAudio: ReadMP3},
+	} {
+		RegisterFormat(format)
+	}
+}
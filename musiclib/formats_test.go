@@ -0,0 +1,90 @@
+package musiclib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFormatFromHeader(t *testing.T) {
+	testdata := func(name string) []byte {
+		data := readTestdata(t, name)
+		return data[:min(len(data), sniffLength)]
+	}
+	tests := []struct {
+		name       string
+		filename   string
+		header     []byte
+		wantFormat string
+		wantBy     string
+	}{
+		// Renamed files are known by their contents
+		{"PDF", "renamed.bin", testdata("octavo.pdf"), "PDF", DetectedBySignature},
+		{"MP3 with an ID3v2 tag", "renamed.bin", testdata("id3v23.mp3"), "MP3", DetectedBySignature},
+		{"MP3 with an ID3v1 tag", "renamed.bin", testdata("id3v1.mp3"), "MP3", DetectedBySignature},
+		{"Ogg Vorbis", "renamed.bin", testdata("vorbis.ogg"), "OGG", DetectedBySignature},
+		{"Opus", "renamed.bin", testdata("tags.opus"), "OGG", DetectedBySignature},
+		{"FLAC", "renamed.bin", testdata("comments.flac"), "FLAC", DetectedBySignature},
+		{"M4A", "renamed.bin", testdata("tags.m4a"), "M4A", DetectedBySignature},
+		{"MP4 video", "renamed.bin", testdata("video.mp4"), "MP4", DetectedBySignature},
+		{"WMA", "renamed.bin", testdata("tags.wma"), "WMA", DetectedBySignature},
+		{"WAV", "renamed.bin", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), "WAV", DetectedBySignature},
+		{"MIDI", "renamed.bin", testdata("alto_featured.mid"), "MIDI", DetectedBySignature},
+		{"MuseScore archive", "renamed.bin", testdata("satb.mscz"), "MuseScore", DetectedBySignature},
+		{"MuseScore XML", "renamed.bin", testdata("satb.mscx"), "MuseScore", DetectedBySignature},
+		{"compressed MusicXML", "renamed.bin", testdata("compressed.mxl"), "MXL", DetectedBySignature},
+		{"MusicXML", "renamed.bin", testdata("satb_piano.musicxml"), "MusicXML", DetectedBySignature},
+		{"ABC", "renamed.bin", testdata("water_is_wide.abc"), "ABC", DetectedBySignature},
+		{"LilyPond", "renamed.bin", testdata("shenandoah.ly"), "LilyPond", DetectedBySignature},
+		{"Sibelius", "renamed.bin", []byte("\x0fSIBELIUS"), "Sibelius", DetectedBySignature},
+		{"Finale", "renamed.bin", []byte("ENIGMA"), "Finale", DetectedBySignature},
+		// Empty or unknown contents fall back to the extension
+		{"Finale by extension", "Gloria.musx", nil, "Finale", DetectedByExtension},
+		{"upper case extension", "Track05.MP3", nil, "MP3", DetectedByExtension},
+		{"unknown contents", "Jingle.pdf", []byte("not a PDF"), "PDF", DetectedByExtension},
+		{"unknown format", "notes.txt", []byte("hello"), "", ""},
+		{"no extension", "README", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, by, ok := DetectFormatFromHeader(tt.filename, tt.header)
+			if format.Name != tt.wantFormat || by != tt.wantBy || ok != (tt.wantFormat != "") {
+				t.Errorf("DetectFormatFromHeader() = %q by %q, %v, want %q by %q", format.Name, by, ok, tt.wantFormat, tt.wantBy)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	// A PDF in a folder named for MP3 files is still a PDF
+	dir := filepath.Join(t.TempDir(), "mp3 rehearsal tracks")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "Shenandoah")
+	if err := os.WriteFile(path, readTestdata(t, "octavo.pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if format, by, ok := DetectFormat(path); format.Name != "PDF" || by != DetectedBySignature || !ok {
+		t.Errorf("DetectFormat() = %q by %q, %v, want PDF by signature", format.Name, by, ok)
+	}
+
+	// A file that cannot be read is known by its extension
+	if format, by, ok := DetectFormat(filepath.Join(dir, "missing.mp3")); format.Name != "MP3" || by != DetectedByExtension || !ok {
+		t.Errorf("DetectFormat() of a missing file = %q by %q, %v, want MP3 by extension", format.Name, by, ok)
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat(Format{Name: "Test Format", Kind: KindDocument, Extensions: []string{".testformat"}, Sniff: hasPrefix("TESTFORMAT")})
+
+	if _, ok := FormatByName("Test Format"); !ok {
+		t.Error("FormatByName() did not find a registered format")
+	}
+	if format, by, _ := DetectFormatFromHeader("renamed.bin", []byte("TESTFORMAT 1.0")); format.Name != "Test Format" || by != DetectedBySignature {
+		t.Errorf("DetectFormatFromHeader() = %q by %q, want the registered format by signature", format.Name, by)
+	}
+	if format, _, _ := DetectFormatFromHeader("score.testformat", nil); format.Name != "Test Format" {
+		t.Errorf("DetectFormatFromHeader() = %q, want the registered format by extension", format.Name)
+	}
+}
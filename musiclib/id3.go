@@ -0,0 +1,570 @@
+package musiclib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ReadMP3 reads the ID3v2 and ID3v1 tags and the stream properties of an
+// MP3 file. Values of an ID3v2 tag win over those of an ID3v1 tag.
+func ReadMP3(filePath string) (AudioInfo, error) {
+	return readAudioFile(filePath, "MP3", ReadMP3At)
+}
+
+// ReadMP3At reads MP3 data of the given size, as ReadMP3 reads a file
+func ReadMP3At(r io.ReaderAt, size int64) (AudioInfo, error) {
+	info := AudioInfo{Format: "MP3"}
+	var audioStart int64
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header, 0); err == nil && string(header[:3]) == "ID3" {
+		tagSize := int64(syncsafe(header[6:10])) + 10
+		if header[5]&0x10 != 0 {
+			tagSize += 10 // footer
+		}
+		tag := make([]byte, min(tagSize, size))
+		if _, err := r.ReadAt(tag, 0); err != nil {
+			return info, fmt.Errorf("error reading ID3v2 tag: %v", err)
+		}
+		tags, tagFormat, err := parseID3v2(tag)
+		if err != nil {
+			return info, fmt.Errorf("error reading ID3v2 tag: %v", err)
+		}
+		info.Tags, info.TagFormat = tags, tagFormat
+		// A tag claiming more bytes than the file has leaves no audio
+		audioStart = min(tagSize, size)
+	}
+
+	audioEnd := size
+	if size-audioStart >= 128 {
+		trailer := make([]byte, 128)
+		if _, err := r.ReadAt(trailer, size-128); err == nil {
+			if tags, ok := parseID3v1(trailer); ok {
+				if info.TagFormat == "" {
+					info.TagFormat = "ID3v1"
+				}
+				info.Tags.fillBlank(tags)
+				audioEnd -= 128
+			}
+		}
+	}
+
+	properties, err := readMPEGProperties(r, audioStart, audioEnd)
+	if err != nil {
+		return info, err
+	}
+	info.Properties = properties
+	return info, nil
+}
+
+// syncsafe decodes the 7-bit bytes of the sizes in ID3v2 headers
+func syncsafe(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n = n<<7 | int(c&0x7F)
+	}
+	return n
+}
+
+// removeUnsync undoes the unsynchronisation of ID3v2, which puts a zero
+// byte after each 0xFF
+func removeUnsync(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+}
+
+// id3Frames maps the frame IDs of ID3v2.2 and of ID3v2.3 and later to the
+// tags they hold
+var id3Frames = map[string]string{
+	"TT2": "title", "TIT2": "title",
+	"TP1": "artist", "TPE1": "artist",
+	"TAL": "album", "TALB": "album",
+	"TCM": "composer", "TCOM": "composer",
+	"TRK": "track", "TRCK": "track",
+	"TYE": "year", "TYER": "year", "TDRC": "year",
+	"COM": "comment", "COMM": "comment",
+}
+
+// parseID3v2 reads the text frames of an ID3v2.2, v2.3 or v2.4 tag and
+// returns them with the name of the tag's version
+func parseID3v2(tag []byte) (AudioTags, string, error) {
+	var tags AudioTags
+	major, flags := tag[3], tag[5]
+	if major < 2 || major > 4 {
+		return tags, "", fmt.Errorf("unsupported ID3v2.%d tag", major)
+	}
+	tagFormat := fmt.Sprintf("ID3v2.%d", major)
+
+	body := tag[10:min(len(tag), 10+syncsafe(tag[6:10]))]
+	if flags&0x80 != 0 && major < 4 {
+		// ID3v2.4 unsynchronises each frame instead
+		body = removeUnsync(body)
+	}
+	if flags&0x40 != 0 {
+		if major == 2 {
+			return tags, tagFormat, errors.New("compressed ID3v2.2 tags are not supported")
+		}
+		if len(body) < 4 {
+			return tags, tagFormat, errors.New("truncated extended header")
+		}
+		extended := int(binary.BigEndian.Uint32(body)) + 4
+		if major == 4 {
+			extended = syncsafe(body[:4])
+		}
+		if extended > len(body) {
+			return tags, tagFormat, errors.New("truncated extended header")
+		}
+		body = body[extended:]
+	}
+
+	idLength, headerLength := 4, 10
+	if major == 2 {
+		idLength, headerLength = 3, 6
+	}
+	for len(body) >= headerLength && body[0] != 0 {
+		id := string(body[:idLength])
+		size := id3FrameSize(major, body, headerLength)
+		if size < 0 || headerLength+size > len(body) {
+			break
+		}
+		var frameFlags byte
+		if major > 2 {
+			frameFlags = body[9]
+		}
+		data := body[headerLength : headerLength+size]
+		body = body[headerLength+size:]
+
+		field, ok := id3Frames[id]
+		if !ok {
+			continue
+		}
+		data, ok = id3FrameData(major, frameFlags, data)
+		if !ok || len(data) == 0 {
+			continue
+		}
+		var value string
+		if field == "comment" {
+			value = id3Comment(data)
+		} else {
+			value = id3Text(data)
+		}
+		tags.set(field, value)
+	}
+
+	return tags, tagFormat, nil
+}
+
+// id3FrameSize returns the size of the frame at the start of body. Some
+// writers store ID3v2.4 sizes as plain integers rather than syncsafe ones,
+// so a v2.4 size is checked against where the next frame starts.
+func id3FrameSize(major byte, body []byte, headerLength int) int {
+	switch major {
+	case 2:
+		return int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+	case 3:
+		return int(binary.BigEndian.Uint32(body[4:8]))
+	}
+
+	size := syncsafe(body[4:8])
+	plain := int(binary.BigEndian.Uint32(body[4:8]))
+	if plain != size && !id3FrameStart(body, headerLength+size) && id3FrameStart(body, headerLength+plain) {
+		return plain
+	}
+	return size
+}
+
+// id3FrameStart reports whether a frame or the padding starts at offset
+func id3FrameStart(body []byte, offset int) bool {
+	if offset == len(body) {
+		return true
+	}
+	if offset < 0 || offset+4 > len(body) {
+		return false
+	}
+	if body[offset] == 0 {
+		return true
+	}
+	for _, c := range body[offset : offset+4] {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// id3FrameData removes the extra header bytes of a frame and undoes its
+// unsynchronisation and compression. Encrypted frames are skipped.
+func id3FrameData(major, flags byte, data []byte) ([]byte, bool) {
+	var compressed, encrypted, unsync bool
+	switch major {
+	case 3:
+		compressed, encrypted = flags&0x80 != 0, flags&0x40 != 0
+		skip := 0
+		if compressed {
+			skip += 4 // decompressed size
+		}
+		if encrypted {
+			skip++
+		}
+		if flags&0x20 != 0 {
+			skip++ // group
+		}
+		if skip > len(data) {
+			return nil, false
+		}
+		data = data[skip:]
+	case 4:
+		compressed, encrypted, unsync = flags&0x08 != 0, flags&0x04 != 0, flags&0x02 != 0
+		skip := 0
+		if flags&0x40 != 0 {
+			skip++ // group
+		}
+		if encrypted {
+			skip++
+		}
+		if flags&0x01 != 0 {
+			skip += 4 // data length
+		}
+		if skip > len(data) {
+			return nil, false
+		}
+		data = data[skip:]
+	}
+
+	if encrypted {
+		return nil, false
+	}
+	if unsync {
+		data = removeUnsync(data)
+	}
+	if compressed {
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, false
+		}
+		defer reader.Close()
+		data, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// id3Text decodes a text frame. The values of a frame holding several,
+// as ID3v2.4 allows, are joined with commas.
+func id3Text(data []byte) string {
+	var values []string
+	for _, value := range splitID3Strings(data[0], data[1:]) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// id3Comment decodes a comment frame, skipping the comments with a
+// description, which hold data written by players rather than text
+func id3Comment(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	values := splitID3Strings(data[0], data[4:])
+	if len(values) < 2 || values[0] != "" {
+		return ""
+	}
+	return values[1]
+}
+
+// splitID3Strings decodes the null-terminated strings of a frame in the
+// frame's text encoding: Latin-1, UTF-16 with a byte order mark,
+// UTF-16BE or UTF-8
+func splitID3Strings(encoding byte, data []byte) []string {
+	var values []string
+	if encoding == 1 || encoding == 2 {
+		start := 0
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				values = append(values, decodeUTF16(data[start:i], encoding == 2))
+				start = i + 2
+			}
+		}
+		if start < len(data) {
+			values = append(values, decodeUTF16(data[start:], encoding == 2))
+		}
+		return values
+	}
+
+	for _, part := range bytes.Split(bytes.TrimRight(data, "\x00"), []byte{0}) {
+		if encoding == 3 {
+			values = append(values, strings.ToValidUTF8(string(part), "�"))
+		} else {
+			values = append(values, decodeLatin1(part))
+		}
+	}
+	return values
+}
+
+// decodeUTF16 decodes UTF-16 text, big-endian when a byte order mark says
+// so or bigEndian is set, and little-endian otherwise
+func decodeUTF16(data []byte, bigEndian bool) string {
+	if len(data) >= 2 {
+		switch {
+		case data[0] == 0xFE && data[1] == 0xFF:
+			bigEndian, data = true, data[2:]
+		case data[0] == 0xFF && data[1] == 0xFE:
+			bigEndian, data = false, data[2:]
+		}
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, binary.BigEndian.Uint16(data[i:]))
+		} else {
+			units = append(units, binary.LittleEndian.Uint16(data[i:]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// set stores a tag value under the name id3Frames or vorbisCommentFields
+// gives it. The first value of a field is kept.
+func (t *AudioTags) set(field, value string) {
+	var target *string
+	switch field {
+	case "title":
+		target = &t.Title
+	case "artist":
+		target = &t.Artist
+	case "album":
+		target = &t.Album
+	case "composer":
+		target = &t.Composer
+	case "track":
+		target, value = &t.Track, trackNumber(value)
+	case "year":
+		target = &t.Year
+		if len(value) > 4 {
+			value = value[:4]
+		}
+	case "comment":
+		target = &t.Comment
+	case "part":
+		target = &t.Part
+	default:
+		return
+	}
+	if *target == "" {
+		*target = strings.TrimSpace(value)
+	}
+}
+
+// trackNumber returns the track number of values such as "03" or "3/12"
+func trackNumber(value string) string {
+	number, _, _ := strings.Cut(strings.TrimSpace(value), "/")
+	if n, err := strconv.Atoi(number); err == nil {
+		return strconv.Itoa(n)
+	}
+	return number
+}
+
+// parseID3v1 reads an ID3v1 or ID3v1.1 tag from the last 128 bytes of a file
+func parseID3v1(trailer []byte) (AudioTags, bool) {
+	if len(trailer) != 128 || string(trailer[:3]) != "TAG" {
+		return AudioTags{}, false
+	}
+	field := func(b []byte) string {
+		return strings.TrimSpace(decodeLatin1(bytes.TrimRight(b, "\x00 ")))
+	}
+
+	tags := AudioTags{
+		Title:  field(trailer[3:33]),
+		Artist: field(trailer[33:63]),
+		Album:  field(trailer[63:93]),
+		Year:   field(trailer[93:97]),
+	}
+	comment := trailer[97:127]
+	if comment[28] == 0 && comment[29] != 0 {
+		// ID3v1.1 keeps the track number in the last byte of the comment
+		tags.Track = strconv.Itoa(int(comment[29]))
+		comment = comment[:28]
+	}
+	tags.Comment = field(comment)
+	return tags, true
+}
+
+// MPEG audio bitrates in kbit/s by version and layer, and sample rates of
+// MPEG-1 in Hz; MPEG-2 halves and MPEG-2.5 quarters them
+var (
+	mpeg1Bitrates = [3][14]int{
+		{32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	}
+	mpeg2Bitrates = [2][14]int{
+		{32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	mpegSampleRates = [3]int{44100, 48000, 32000}
+)
+
+// mpegFrame is the header of one frame of MPEG audio
+type mpegFrame struct {
+	mpeg1      bool
+	bitrate    int
+	sampleRate int
+	channels   int
+	samples    int
+	length     int
+}
+
+// parseMPEGFrame decodes a frame header. Free-format frames, which give no
+// bitrate, are not recognized.
+func parseMPEGFrame(h []byte) (mpegFrame, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mpegFrame{}, false
+	}
+	version := h[1] >> 3 & 0x03 // 0: MPEG-2.5, 2: MPEG-2, 3: MPEG-1
+	layerBits := h[1] >> 1 & 0x03
+	bitrateIndex := int(h[2] >> 4)
+	sampleRateIndex := h[2] >> 2 & 0x03
+	if version == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mpegFrame{}, false
+	}
+
+	layer := 4 - int(layerBits)
+	frame := mpegFrame{mpeg1: version == 3, channels: 2}
+	switch {
+	case frame.mpeg1:
+		frame.bitrate = mpeg1Bitrates[layer-1][bitrateIndex-1]
+	case layer == 1:
+		frame.bitrate = mpeg2Bitrates[0][bitrateIndex-1]
+	default:
+		frame.bitrate = mpeg2Bitrates[1][bitrateIndex-1]
+	}
+	frame.sampleRate = mpegSampleRates[sampleRateIndex]
+	switch version {
+	case 2:
+		frame.sampleRate /= 2
+	case 0:
+		frame.sampleRate /= 4
+	}
+	if h[3]>>6 == 3 {
+		frame.channels = 1
+	}
+
+	padding := int(h[2] >> 1 & 0x01)
+	switch {
+	case layer == 1:
+		frame.samples = 384
+		frame.length = (12*frame.bitrate*1000/frame.sampleRate + padding) * 4
+	case layer == 3 && !frame.mpeg1:
+		frame.samples = 576
+		frame.length = 72*frame.bitrate*1000/frame.sampleRate + padding
+	default:
+		frame.samples = 1152
+		frame.length = 144*frame.bitrate*1000/frame.sampleRate + padding
+	}
+	return frame, true
+}
+
+// mpegSearchLength is how far past the tags the first audio frame is
+// looked for
+const mpegSearchLength = 64 * 1024
+
+// readMPEGProperties finds the first audio frame between start and end and
+// works out the duration from its Xing or VBRI header, or from the bitrate
+// when the stream has a constant bitrate
+func readMPEGProperties(r io.ReaderAt, start, end int64) (AudioProperties, error) {
+	if end <= start {
+		return AudioProperties{}, errors.New("no MPEG audio after the tags")
+	}
+	buffer := make([]byte, min(end-start, mpegSearchLength))
+	n, err := r.ReadAt(buffer, start)
+	if err != nil && err != io.EOF {
+		return AudioProperties{}, err
+	}
+	buffer = buffer[:n]
+
+	for offset := 0; offset+4 <= len(buffer); offset++ {
+		frame, ok := parseMPEGFrame(buffer[offset:])
+		if !ok {
+			continue
+		}
+		// A second frame right after the first rules out a chance match
+		if next := offset + frame.length; next+4 <= len(buffer) {
+			if _, ok := parseMPEGFrame(buffer[next:]); !ok {
+				continue
+			}
+		}
+
+		audioBytes := end - start - int64(offset)
+		properties := AudioProperties{SampleRate: frame.sampleRate, Channels: frame.channels, Bitrate: frame.bitrate}
+		frames, vbrBytes := mpegVBRHeader(buffer[offset:min(len(buffer), offset+frame.length)], frame)
+		if frames > 0 {
+			seconds := float64(frames) * float64(frame.samples) / float64(frame.sampleRate)
+			properties.Duration = time.Duration(seconds * float64(time.Second))
+			if vbrBytes > 0 {
+				audioBytes = vbrBytes
+			}
+			properties.Bitrate = int(float64(audioBytes) * 8 / seconds / 1000)
+		} else {
+			seconds := float64(audioBytes) * 8 / float64(frame.bitrate*1000)
+			properties.Duration = time.Duration(seconds * float64(time.Second))
+		}
+		return properties, nil
+	}
+	return AudioProperties{}, errors.New("no MPEG audio frames")
+}
+
+// mpegVBRHeader reads the frame and byte counts of the Xing, Info or VBRI
+// header an encoder puts in the first frame of a variable-bitrate stream
+func mpegVBRHeader(frameData []byte, frame mpegFrame) (int64, int64) {
+	sideInfo := 32
+	switch {
+	case frame.mpeg1 && frame.channels == 1:
+		sideInfo = 17
+	case !frame.mpeg1 && frame.channels == 2:
+		sideInfo = 17
+	case !frame.mpeg1:
+		sideInfo = 9
+	}
+
+	if xing := 4 + sideInfo; len(frameData) >= xing+16 {
+		tag := string(frameData[xing : xing+4])
+		if tag == "Xing" || tag == "Info" {
+			flags := binary.BigEndian.Uint32(frameData[xing+4:])
+			var frames, size int64
+			next := xing + 8
+			if flags&0x01 != 0 {
+				frames = int64(binary.BigEndian.Uint32(frameData[next:]))
+				next += 4
+			}
+			if flags&0x02 != 0 && len(frameData) >= next+4 {
+				size = int64(binary.BigEndian.Uint32(frameData[next:]))
+			}
+			return frames, size
+		}
+	}
+
+	if vbri := 4 + 32; len(frameData) >= vbri+18 && string(frameData[vbri:vbri+4]) == "VBRI" {
+		size := int64(binary.BigEndian.Uint32(frameData[vbri+10:]))
+		frames := int64(binary.BigEndian.Uint32(frameData[vbri+14:]))
+		return frames, size
+	}
+	return 0, 0
+}
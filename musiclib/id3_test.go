@@ -0,0 +1,108 @@
+package musiclib
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTemp writes data to a file of the given name in a temporary
+// directory, for the readers that take a path
+func writeTemp(t testing.TB, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadMP3At(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		want   AudioInfo
+		wantOK bool
+	}{
+		{
+			name: "id3v23.mp3",
+			data: readTestdata(t, "id3v23.mp3"),
+			want: AudioInfo{Format: "MP3", TagFormat: "ID3v2.3",
+				Tags:       AudioTags{Title: "Ave Maria - Soprano", Artist: "Chorus", Album: "Rehearsal 2019", Composer: "Franz Biebl", Track: "3", Year: "2019", Comment: "Learn the notes"},
+				Properties: AudioProperties{Duration: 156375000, Bitrate: 128, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{
+			name: "id3v24_vbr.mp3",
+			data: readTestdata(t, "id3v24_vbr.mp3"),
+			want: AudioInfo{Format: "MP3", TagFormat: "ID3v2.4",
+				Tags:       AudioTags{Title: "Ave Maria, Alto", Artist: "Biebl"},
+				Properties: AudioProperties{Duration: 5224489795, Bitrate: 127, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{
+			name: "id3v22.mp3",
+			data: readTestdata(t, "id3v22.mp3"),
+			want: AudioInfo{Format: "MP3", TagFormat: "ID3v2.2",
+				Tags:       AudioTags{Title: "Locus Iste", Composer: "Anton Bruckner"},
+				Properties: AudioProperties{Duration: 78187500, Bitrate: 128, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{
+			name: "id3v1.mp3",
+			data: readTestdata(t, "id3v1.mp3"),
+			want: AudioInfo{Format: "MP3", TagFormat: "ID3v1",
+				Tags:       AudioTags{Title: "V1 title", Artist: "Chorus", Album: "Rehearsal 2019", Track: "7", Year: "2019", Comment: "c"},
+				Properties: AudioProperties{Duration: 78187500, Bitrate: 128, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{
+			name: "empty",
+			want: AudioInfo{Format: "MP3"},
+		},
+		{
+			name: "cut inside the ID3v2 tag",
+			data: readTestdata(t, "id3v23.mp3")[:20],
+			want: AudioInfo{Format: "MP3", TagFormat: "ID3v2.3"},
+		},
+		{
+			// The ID3v1 trailer is cut off with the end of the audio
+			name: "cut in the audio",
+			data: readTestdata(t, "id3v23.mp3")[:2000],
+			want: AudioInfo{Format: "MP3", TagFormat: "ID3v2.3",
+				Tags:       AudioTags{Title: "Ave Maria - Soprano", Composer: "Franz Biebl", Track: "3", Comment: "Learn the notes"},
+				Properties: AudioProperties{Duration: 115687500, Bitrate: 128, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{
+			// The tag claims 16 KiB of the 326-byte file
+			name: "truncated_tag.mp3",
+			data: readTestdata(t, "truncated_tag.mp3"),
+			want: AudioInfo{Format: "MP3", TagFormat: "ID3v2.3", Tags: AudioTags{Title: "Hello"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMP3At(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadMP3At() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ReadMP3At() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReadMP3At(f *testing.F) {
+	for _, name := range []string{"id3v23.mp3", "id3v24_vbr.mp3", "id3v22.mp3", "id3v1.mp3", "truncated_tag.mp3"} {
+		f.Add(readTestdata(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadMP3At(bytes.NewReader(data), int64(len(data)))
+		if err == nil && (info.Properties.Duration < 0 || info.Properties.Bitrate < 0) {
+			t.Errorf("negative duration %v or bitrate %d", info.Properties.Duration, info.Properties.Bitrate)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package musiclib
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Instrument is one entry of the instrument vocabulary in config.yml. The
+// name is what the catalog shows; aliases are other ways the part is
+// written in filenames.
+type Instrument struct {
+	Name    string   `yaml:"name"`
+	Family  string   `yaml:"family"`
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+// DefaultInstruments returns the vocal and instrumental parts found in the
+// library before a vocabulary was configured
+func DefaultInstruments() []Instrument {
+	return []Instrument{
+		{Name: "Soprano", Family: "vocal", Aliases: []string{"Sopranos", "Sop"}},
+		{Name: "Alto", Family: "vocal", Aliases: []string{"Altos"}},
+		{Name: "Tenor", Family: "vocal", Aliases: []string{"Tenors"}},
+		{Name: "Baritone", Family: "vocal", Aliases: []string{"Bari"}},
+		{Name: "Bass", Family: "vocal", Aliases: []string{"Basses"}},
+		{Name: "Solo", Family: "vocal", Aliases: []string{"Solos", "Soloist"}},
+		{Name: "Duet", Family: "vocal"},
+		{Name: "Piano/Vocal", Family: "vocal", Aliases: []string{"Piano Vocal"}},
+		{Name: "Leadsheet", Family: "vocal", Aliases: []string{"Lead Sheet"}},
+		{Name: "Piano", Family: "keyboard", Aliases: []string{"Pno"}},
+		{Name: "Organ", Family: "keyboard"},
+		{Name: "Electric Bass", Family: "rhythm", Aliases: []string{"Bass Guitar", "E Bass"}},
+		{Name: "Guitar", Family: "rhythm", Aliases: []string{"Electric Guitar", "Acoustic Guitar"}},
+		{Name: "Drums", Family: "percussion", Aliases: []string{"Drum Set", "Drumset", "Drum Kit"}},
+		{Name: "Snare", Family: "percussion", Aliases: []string{"Snare Drum"}},
+		{Name: "Percussion", Family: "percussion", Aliases: []string{"Perc"}},
+		{Name: "Timpani", Family: "percussion"},
+		{Name: "Violin", Family: "strings", Aliases: []string{"Violins"}},
+		{Name: "Violin I", Family: "strings", Aliases: []string{"Violin 1", "Violin One"}},
+		{Name: "Violin II", Family: "strings", Aliases: []string{"Violin 2", "Violin Two"}},
+		{Name: "Viola", Family: "strings", Aliases: []string{"Violas"}},
+		{Name: "Cello", Family: "strings", Aliases: []string{"Cellos", "Violoncello"}},
+		{Name: "String Bass", Family: "strings", Aliases: []string{"Double Bass", "Contrabass", "Upright Bass"}},
+		{Name: "Harp", Family: "strings"},
+		{Name: "Flute", Family: "woodwinds", Aliases: []string{"Flutes"}},
+		{Name: "Oboe", Family: "woodwinds"},
+		{Name: "Clarinet", Family: "woodwinds", Aliases: []string{"Clarinets"}},
+		{Name: "Bassoon", Family: "woodwinds"},
+		{Name: "Saxophone", Family: "woodwinds", Aliases: []string{"Sax", "Alto Sax", "Tenor Sax"}},
+		{Name: "Trumpet", Family: "brass", Aliases: []string{"Trumpets"}},
+		{Name: "Horn", Family: "brass", Aliases: []string{"French Horn"}},
+		{Name: "Trombone", Family: "brass", Aliases: []string{"Trombones"}},
+		{Name: "Tuba", Family: "brass"},
+		{Name: "Brass", Family: "brass", Aliases: []string{"Brass Quintet"}},
+		{Name: "Instrumental Parts", Family: "score"},
+		{Name: "Score", Family: "score", Aliases: []string{"Full Score"}},
+	}
+}
+
+// PartDetector finds instrument and voice part names in filenames
+type PartDetector struct {
+	parts    map[string]string // folded alias -> instrument name
+	aliases  []string
+	maxWords int
+}
+
+// NewPartDetector builds a detector from an instrument vocabulary
+func NewPartDetector(instruments []Instrument) *PartDetector {
+	detector := &PartDetector{parts: make(map[string]string)}
+
+	for _, instrument := range instruments {
+		for _, alias := range append([]string{instrument.Name}, instrument.Aliases...) {
+			folded := foldName(alias)
+			if folded == "" {
+				continue
+			}
+			if _, ok := detector.parts[folded]; !ok {
+				detector.parts[folded] = instrument.Name
+				detector.aliases = append(detector.aliases, alias)
+			}
+			if words := len(wordSpans(alias)); words > detector.maxWords {
+				detector.maxWords = words
+			}
+		}
+	}
+
+	return detector
+}
+
+// Match returns the instrument name for text when all of text is a part name
+func (d *PartDetector) Match(text string) (string, bool) {
+	name, ok := d.parts[foldName(text)]
+	return name, ok
+}
+
+// FindParts returns every part named among the words of text, longest names
+// first, so "Electric_Bass" is one part and not "Bass"
+func (d *PartDetector) FindParts(text string) []string {
+	return findPhrases(text, d.maxWords, d.Match)
+}
+
+// OnlyParts returns the parts named in text when every word of it belongs
+// to a part name, as in a composer field holding "Bass" or "Bass_Drums"
+func (d *PartDetector) OnlyParts(text string) ([]string, bool) {
+	rest, parts := removePhrases(text, d.maxWords, d.Match)
+	return parts, rest == "" && len(parts) > 0
+}
+
+// ExtractFromTitle removes part names from the end of a raw title, as in
+// "HeatMiser_Bass" or "TheChristmasWaltzBassDrums". The parts are returned
+// in the order they appear. At least one word is always left for the title.
+func (d *PartDetector) ExtractFromTitle(title string) (string, []string) {
+	return extractPhrasesFromEnd(title, d.maxWords, d.Match)
+}
+
+// FieldExpression returns a regular expression matching one or more part
+// names, used for the {part} field of filename patterns
+func (d *PartDetector) FieldExpression() string {
+	aliases := append([]string(nil), d.aliases...)
+	// Longest first so "Violin II" is preferred over "Violin"
+	sort.Slice(aliases, func(i, j int) bool { return len(aliases[i]) > len(aliases[j]) })
+
+	var alternatives []string
+	for _, alias := range aliases {
+		var words []string
+		for _, span := range wordSpans(alias) {
+			words = append(words, regexp.QuoteMeta(span.Text))
+		}
+		alternatives = append(alternatives, strings.Join(words, `[ _.-]*`))
+	}
+
+	one := "(?:" + strings.Join(alternatives, "|") + ")"
+	return `(?i:` + one + `(?:[ _,-]+` + one + `)*)`
+}
+
+// JoinParts joins part names for display, dropping duplicates and names
+// that are contained in a more specific one ("Bass" next to "Electric Bass")
+func JoinParts(parts []string) string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, part := range parts {
+		if seen[part] || containedInOther(part, parts) {
+			continue
+		}
+		seen[part] = true
+		unique = append(unique, part)
+	}
+	return strings.Join(unique, ", ")
+}
+
+func containedInOther(part string, parts []string) bool {
+	folded := foldName(part)
+	for _, other := range parts {
+		if other != part && strings.Contains(foldName(other), folded) {
+			return true
+		}
+	}
+	return false
+}
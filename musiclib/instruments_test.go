@@ -0,0 +1,123 @@
+package musiclib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPartDetectorExtractFromTitle(t *testing.T) {
+	detector := NewPartDetector(DefaultInstruments())
+	tests := []struct {
+		title     string
+		want      string
+		wantParts []string
+	}{
+		{"HeatMiser_Bass", "HeatMiser", []string{"Bass"}},
+		{"BreathOfHeaven-Bass", "BreathOfHeaven", []string{"Bass"}},
+		{"TheChristmasWaltzBassDrums", "TheChristmasWaltz", []string{"Bass", "Drums"}},
+		{"The_Hands_That_First_Held_Marys_Child_Cello", "The_Hands_That_First_Held_Marys_Child", []string{"Cello"}},
+		{"huron_carol_quartet_-_violin_i", "huron_carol_quartet", []string{"Violin I"}},
+		// At least one word is left for the title
+		{"Bass_Drums", "Bass", []string{"Drums"}},
+		{"Bass", "Bass", nil},
+		{"YuleBeRockin", "YuleBeRockin", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got, parts := detector.ExtractFromTitle(tt.title)
+			if got != tt.want || !reflect.DeepEqual(parts, tt.wantParts) {
+				t.Errorf("ExtractFromTitle() = %q, %q, want %q, %q", got, parts, tt.want, tt.wantParts)
+			}
+		})
+	}
+}
+
+func TestPartDetectorOnlyParts(t *testing.T) {
+	detector := NewPartDetector(DefaultInstruments())
+	tests := []struct {
+		text   string
+		want   []string
+		wantOK bool
+	}{
+		{"Bass", []string{"Bass"}, true},
+		{"Bass_Drums", []string{"Bass", "Drums"}, true},
+		{"Electric_Bass", []string{"Electric Bass"}, true},
+		{"Bass Guitar", []string{"Electric Bass"}, true},
+		{"violin 2", []string{"Violin II"}, true},
+		// Composer fields the old token positions produced
+		{"Merry", nil, false},
+		{"Outside", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := detector.OnlyParts(tt.text)
+			if ok != tt.wantOK || (ok && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("OnlyParts() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPartDetectorFindParts(t *testing.T) {
+	detector := NewPartDetector(DefaultInstruments())
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"AHollyJollyChristmas_Bass - Electric Bass.pdf", "Electric Bass"},
+		{"Piano Vocal score", "Piano/Vocal, Score"},
+		{"huron_carol_quartet_-_violin_i.pdf", "Violin I"},
+		{"Fruitcake.pdf", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := JoinParts(detector.FindParts(tt.text)); got != tt.want {
+				t.Errorf("JoinParts(FindParts()) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartDetectorFieldExpression(t *testing.T) {
+	detector := NewPartDetector(DefaultInstruments())
+	grammar, err := NewFilenameGrammar([]FilenamePattern{{Pattern: "{title}_{part}.{ext}"}}, map[string]string{"part": detector.FieldExpression()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		filename  string
+		wantTitle string
+		wantPart  string
+	}{
+		{"Blue_Christmas_Bass.pdf", "Blue_Christmas", "Bass"},
+		{"Were_You_There_on_That_Christmas_Night_Bass.pdf", "Were_You_There_on_That_Christmas_Night", "Bass"},
+		{"HeatMiser_Bass_Drums.pdf", "HeatMiser", "Bass_Drums"},
+		{"Mary_Violin_2.pdf", "Mary", "Violin_2"},
+		{"Fruitcake.pdf", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			match, _ := grammar.Match(tt.filename)
+			if match.Field("title") != tt.wantTitle || match.Field("part") != tt.wantPart {
+				t.Errorf("Match() = %q, want title %q, part %q", match.Fields, tt.wantTitle, tt.wantPart)
+			}
+		})
+	}
+}
+
+func TestJoinParts(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"Bass", "Electric Bass"}, "Electric Bass"},
+		{[]string{"Alto", "Alto", "Tenor"}, "Alto, Tenor"},
+		{[]string{"Violin", "Violin II"}, "Violin II"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := JoinParts(tt.parts); got != tt.want {
+			t.Errorf("JoinParts(%q) = %q, want %q", tt.parts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package musiclib
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Key is a musical key, such as Eb Major or D Dorian
+type Key struct {
+	Tonic string
+	Mode  string
+}
+
+// String returns the key as shown in the catalog, or "" for no key
+func (k Key) String() string {
+	if k.Tonic == "" {
+		return ""
+	}
+	return k.Tonic + " " + k.Mode
+}
+
+// KeyResult is a key found in a filename with how sure the parser is of it.
+// Start and End are the byte offsets of the words that named the key.
+type KeyResult struct {
+	Key        Key
+	Confidence float64
+	Matched    string
+	Start, End int
+}
+
+// Confidence of each way a key can be written
+const (
+	confidenceKeyWithMode  = 1.0
+	confidenceKeyAfterIn   = 0.8
+	confidenceKeySignature = 1.0
+)
+
+var keyModes = map[string]string{
+	"major": "Major", "maj": "Major", "dur": "Major",
+	"minor": "Minor", "min": "Minor", "moll": "Minor",
+	"ionian": "Major", "aeolian": "Minor",
+	"dorian": "Dorian", "phrygian": "Phrygian", "lydian": "Lydian",
+	"mixolydian": "Mixolydian", "locrian": "Locrian",
+}
+
+var (
+	tonicWord    = regexp.MustCompile(`^([A-Ga-g])(b|#|♭|♯|flat|sharp)?$`)
+	accidentals  = map[string]string{"": "", "b": "b", "♭": "b", "flat": "b", "#": "#", "♯": "#", "sharp": "#"}
+	keyLeadWords = map[string]bool{"in": true, "key": true}
+)
+
+// parseTonic reads a tonic starting at spans[i], such as "Eb", "F#" or
+// "B flat", returning it and the number of words it used
+func parseTonic(spans []wordSpan, i int) (string, int, bool) {
+	groups := tonicWord.FindStringSubmatch(spans[i].Text)
+	if groups == nil {
+		return "", 0, false
+	}
+	accidental := groups[2]
+	n := 1
+	if accidental == "" && i+1 < len(spans) {
+		if next := strings.ToLower(spans[i+1].Text); next == "flat" || next == "sharp" {
+			accidental = next
+			n = 2
+		}
+	}
+	return strings.ToUpper(groups[1]) + accidentals[accidental], n, true
+}
+
+// ParseKey finds a key among the words of a filename or title. A tonic
+// followed by a mode ("F_Major", "Bb min") is certain; a tonic after "in"
+// or "key" ("in Eb") is taken as major. A lone "A" after "in" is only a key
+// at the end of the text, so "BornInAManger" has no key.
+func ParseKey(text string) (KeyResult, bool) {
+	spans := wordSpans(text)
+
+	for i := range spans {
+		tonic, n, ok := parseTonic(spans, i)
+		if ok && i+n < len(spans) {
+			if mode, ok := keyModes[strings.ToLower(spans[i+n].Text)]; ok {
+				return newKeyResult(text, spans[i], spans[i+n], Key{Tonic: tonic, Mode: mode}, confidenceKeyWithMode), true
+			}
+		}
+
+		if !keyLeadWords[strings.ToLower(spans[i].Text)] || i+1 >= len(spans) {
+			continue
+		}
+		tonic, n, ok = parseTonic(spans, i+1)
+		if !ok || !unicode.IsUpper(rune(spans[i+1].Text[0])) {
+			continue
+		}
+		last := spans[i+n]
+		if i+1+n < len(spans) {
+			if mode, ok := keyModes[strings.ToLower(spans[i+1+n].Text)]; ok {
+				return newKeyResult(text, spans[i], spans[i+1+n], Key{Tonic: tonic, Mode: mode}, confidenceKeyWithMode), true
+			}
+			if tonic == "A" {
+				continue
+			}
+		}
+		return newKeyResult(text, spans[i], last, Key{Tonic: tonic, Mode: "Major"}, confidenceKeyAfterIn), true
+	}
+
+	return KeyResult{}, false
+}
+
+func newKeyResult(text string, first, last wordSpan, key Key, confidence float64) KeyResult {
+	return KeyResult{
+		Key:        key,
+		Confidence: confidence,
+		Matched:    text[first.Start:last.End],
+		Start:      first.Start,
+		End:        last.End,
+	}
+}
+
+// ExtractKey removes a key from a raw title, so "Jingle_Bells_F_Major"
+// gives "Jingle_Bells" and "Only Us - Eb Major - MN0174554" gives
+// "Only Us - MN0174554". The title is returned unchanged when it has no key
+// or the key is all it has.
+func ExtractKey(title string) (string, KeyResult, bool) {
+	result, ok := ParseKey(title)
+	if !ok {
+		return title, KeyResult{}, false
+	}
+
+	before := title[:result.Start]
+	after := strings.TrimLeft(title[result.End:], " _.-+")
+	if strings.TrimLeft(before, " _.-+") == "" {
+		if after == "" {
+			return title, KeyResult{}, false
+		}
+		return after, result, true
+	}
+	if after == "" {
+		return strings.TrimRight(before, " _.-+"), result, true
+	}
+	return before + after, result, true
+}
+
+// circleOfFifths names the major keys from eight flats to twelve sharps,
+// enough to place every mode of every key signature
+var circleOfFifths = []string{
+	"Fb", "Cb", "Gb", "Db", "Ab", "Eb", "Bb", "F", "C", "G", "D",
+	"A", "E", "B", "F#", "C#", "G#", "D#", "A#", "E#", "B#",
+}
+
+// modeOffsets is how far round the circle of fifths each mode's tonic is
+// from the major key with the same signature
+var modeOffsets = map[string]int{
+	"Major": 0, "Lydian": -1, "Mixolydian": 1, "Dorian": 2,
+	"Minor": 3, "Phrygian": 4, "Locrian": 5,
+}
+
+// KeyFromSignature names the key of a key signature given as the number of
+// sharps (positive) or flats (negative), as stored in MusicXML and MIDI
+func KeyFromSignature(fifths int, mode string) (Key, bool) {
+	name, ok := keyModes[strings.ToLower(mode)]
+	if !ok {
+		name = "Major"
+	}
+	index := fifths + modeOffsets[name] + 8
+	if fifths < -7 || fifths > 7 || index < 0 || index >= len(circleOfFifths) {
+		return Key{}, false
+	}
+	return Key{Tonic: circleOfFifths[index], Mode: name}, true
+}
@@ -0,0 +1,64 @@
+package musiclib
+
+import "testing"
+
+func TestExtractKey(t *testing.T) {
+	tests := []struct {
+		title      string
+		want       string
+		wantKey    string
+		confidence float64
+		matched    string
+	}{
+		{"Jingle_Bells_F_Major", "Jingle_Bells", "F Major", confidenceKeyWithMode, "F_Major"},
+		{"Only Us - Eb Major - MN0174554", "Only Us - MN0174554", "Eb Major", confidenceKeyWithMode, "Eb Major"},
+		{"Ave Maria Bb_min", "Ave Maria", "Bb Minor", confidenceKeyWithMode, "Bb_min"},
+		{"Greensleeves D Dorian", "Greensleeves", "D Dorian", confidenceKeyWithMode, "D Dorian"},
+		{"Song in C# minor", "Song", "C# Minor", confidenceKeyWithMode, "in C# minor"},
+		{"OHolyNight in Eb", "OHolyNight", "Eb Major", confidenceKeyAfterIn, "in Eb"},
+		{"Sonata in B flat", "Sonata", "Bb Major", confidenceKeyAfterIn, "in B flat"},
+		{"Prelude in A", "Prelude", "A Major", confidenceKeyAfterIn, "in A"},
+		// A lone "A" after "in" is a word unless it ends the title
+		{"BornInAManger", "BornInAManger", "", 0, ""},
+		// A tonic without a mode or "in" is not a key
+		{"OHolyNight_Solo_Bb", "OHolyNight_Solo_Bb", "", 0, ""},
+		// The key is not all a title has
+		{"F_Major", "F_Major", "", 0, ""},
+		{"Fruitcake", "Fruitcake", "", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got, result, ok := ExtractKey(tt.title)
+			if got != tt.want || ok != (tt.wantKey != "") || result.Key.String() != tt.wantKey ||
+				result.Confidence != tt.confidence || result.Matched != tt.matched {
+				t.Errorf("ExtractKey() = %q, %q at %v from %q, want %q, %q at %v from %q",
+					got, result.Key, result.Confidence, result.Matched, tt.want, tt.wantKey, tt.confidence, tt.matched)
+			}
+		})
+	}
+}
+
+func TestKeyFromSignature(t *testing.T) {
+	tests := []struct {
+		fifths int
+		mode   string
+		want   Key
+		wantOK bool
+	}{
+		{0, "major", Key{Tonic: "C", Mode: "Major"}, true},
+		{1, "", Key{Tonic: "G", Mode: "Major"}, true},
+		{-3, "minor", Key{Tonic: "C", Mode: "Minor"}, true},
+		{2, "dorian", Key{Tonic: "E", Mode: "Dorian"}, true},
+		{-1, "phrygian", Key{Tonic: "A", Mode: "Phrygian"}, true},
+		{7, "major", Key{Tonic: "C#", Mode: "Major"}, true},
+		{-7, "minor", Key{Tonic: "Ab", Mode: "Minor"}, true},
+		{8, "major", Key{}, false},
+		{-8, "minor", Key{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := KeyFromSignature(tt.fifths, tt.mode)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("KeyFromSignature(%d, %q) = %v, %v, want %v, %v", tt.fifths, tt.mode, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package musiclib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// lilyPondHeaderFields maps the \header fields of a LilyPond file to the
+// ScoreInfo field they fill
+var lilyPondHeaderFields = map[string]string{
+	"title":     "title",
+	"piece":     "piece",
+	"composer":  "composer",
+	"arranger":  "arranger",
+	"poet":      "lyricist",
+	"lyricist":  "lyricist",
+	"copyright": "copyright",
+}
+
+var (
+	lilyPondHeader     = regexp.MustCompile(`\\header\s*\{`)
+	lilyPondAssignment = regexp.MustCompile(`([A-Za-z][\w-]*)\s*=\s*`)
+	lilyPondString     = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+	lilyPondMarkupWord = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"|\\[A-Za-z-]+|#\S+|([^\s{}"\\#]+)`)
+	lilyPondKey        = regexp.MustCompile(`\\key\s+([a-g])((?:is|es|s|f|sharp|flat)*)\s*\\(\w+)`)
+	lilyPondTime       = regexp.MustCompile(`\\time\s+(\d+/\d+)`)
+	lilyPondTempo      = regexp.MustCompile(`\\tempo\s+(?:"[^"]*"\s*|\\markup\s*\{[^}]*\}\s*)?\d+\.?\s*=\s*(\d+)`)
+	lilyPondInstrument = regexp.MustCompile(`\binstrumentName\s*=\s*#?"((?:[^"\\]|\\.)*)"`)
+	lilyPondFile       = regexp.MustCompile(`\\(?:version|header|score|relative|new)\b`)
+)
+
+// ReadLilyPond reads the \header block of a LilyPond source (title,
+// composer, arranger, poet and copyright), the first \key, \time and
+// \tempo of its music, and the instrument names of its staves. A field set
+// with \markup is read for its words. LilyPond sources are programs that
+// can compute any of these, so only the values written out are read.
+func ReadLilyPond(filePath string) (ScoreInfo, error) {
+	info := ScoreInfo{Format: "LilyPond"}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return info, fmt.Errorf("error reading LilyPond '%s': %v", filePath, err)
+	}
+	if err := readLilyPondSource(string(data), &info); err != nil {
+		return info, fmt.Errorf("error reading LilyPond '%s': %v", filePath, err)
+	}
+	return info, nil
+}
+
+func readLilyPondSource(source string, info *ScoreInfo) error {
+	source = stripLilyPondComments(source)
+	if !lilyPondFile.MatchString(source) {
+		return errors.New("not a LilyPond source")
+	}
+
+	if loc := lilyPondHeader.FindStringIndex(source); loc != nil {
+		fields := readLilyPondHeader(source[loc[1]:])
+		for _, title := range []string{fields["title"], fields["piece"]} {
+			if title != "" {
+				info.Title = title
+				break
+			}
+		}
+		info.Composer, info.Arranger = cleanCredit(fields["composer"]), cleanCredit(fields["arranger"])
+		info.Lyricist, info.Copyright = cleanCredit(fields["lyricist"]), fields["copyright"]
+	}
+
+	if groups := lilyPondKey.FindStringSubmatch(source); groups != nil {
+		info.Key, _ = lilyPondKeyOf(groups[1], groups[2], groups[3])
+	}
+	if groups := lilyPondTime.FindStringSubmatch(source); groups != nil {
+		info.Time = groups[1]
+	}
+	for _, groups := range lilyPondTempo.FindAllStringSubmatch(source, -1) {
+		if bpm, err := strconv.ParseFloat(groups[1], 64); err == nil && bpm > 0 {
+			info.Tempos = append(info.Tempos, bpm)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, groups := range lilyPondInstrument.FindAllStringSubmatch(source, -1) {
+		if name := joinLines(groups[1]); name != "" && !seen[name] {
+			seen[name] = true
+			info.Parts = append(info.Parts, ScorePart{Name: name})
+		}
+	}
+	return nil
+}
+
+// readLilyPondHeader reads the assignments of a \header block, starting
+// just inside its opening brace, up to its closing brace
+func readLilyPondHeader(block string) map[string]string {
+	fields := make(map[string]string)
+	for pos := 0; pos < len(block); {
+		rest := strings.TrimLeft(block[pos:], " \t\r\n")
+		pos = len(block) - len(rest)
+		if rest == "" || rest[0] == '}' {
+			break
+		}
+		loc := lilyPondAssignment.FindStringSubmatchIndex(rest)
+		if loc == nil || loc[0] != 0 {
+			// Skip anything that is not an assignment, such as a Scheme
+			// expression, up to the next line
+			next := strings.IndexByte(rest, '\n')
+			if next < 0 {
+				break
+			}
+			pos += next + 1
+			continue
+		}
+		name := rest[loc[2]:loc[3]]
+		value, n := lilyPondValue(rest[loc[1]:])
+		pos += loc[1] + n
+		if field, ok := lilyPondHeaderFields[name]; ok && fields[field] == "" {
+			fields[field] = value
+		}
+	}
+	return fields
+}
+
+// lilyPondValue reads the value of a header field: a string, as in title =
+// "Ave Maria" or title = #"Ave Maria", or the words of a markup, as in
+// composer = \markup { \bold "Franz Biebl" }. It returns the value and how
+// much of text it used.
+func lilyPondValue(text string) (string, int) {
+	switch {
+	case strings.HasPrefix(text, "#\""):
+		value, n := lilyPondValue(text[1:])
+		return value, n + 1
+	case strings.HasPrefix(text, "\""):
+		loc := lilyPondString.FindStringSubmatchIndex(text)
+		if loc == nil || loc[0] != 0 {
+			return "", len(text)
+		}
+		return lilyPondUnescape(text[loc[2]:loc[3]]), loc[1]
+	case strings.HasPrefix(text, `\markup`):
+		end := lilyPondMarkupEnd(text)
+		var words []string
+		for _, groups := range lilyPondMarkupWord.FindAllStringSubmatch(text[len(`\markup`):end], -1) {
+			if word := lilyPondUnescape(groups[1]) + groups[2]; word != "" {
+				words = append(words, word)
+			}
+		}
+		return joinLines(strings.Join(words, " ")), end
+	}
+	end := strings.IndexAny(text, "\n}")
+	if end < 0 {
+		end = len(text)
+	}
+	return "", end
+}
+
+// lilyPondMarkupEnd finds the end of a markup: its braced argument, or the
+// first string after its commands
+func lilyPondMarkupEnd(text string) int {
+	depth := 0
+	inString := false
+	for i := len(`\markup`); i < len(text); i++ {
+		switch c := text[i]; {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		case c == '"':
+			inString = true
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		case c == '\n' && depth == 0:
+			return i
+		}
+	}
+	return len(text)
+}
+
+// lilyPondUnescape undoes the backslash escapes of a LilyPond string
+func lilyPondUnescape(text string) string {
+	if !strings.Contains(text, `\`) {
+		return text
+	}
+	var unescaped strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+			if text[i] == 'n' {
+				unescaped.WriteByte('\n')
+				continue
+			}
+		}
+		unescaped.WriteByte(text[i])
+	}
+	return unescaped.String()
+}
+
+// lilyPondKeyOf reads the key of \key, whose tonic is a note name in Dutch
+// (bes, fis, es) or English (bf, fs, bflat) and whose mode is a command
+// such as \major or \dorian
+func lilyPondKeyOf(letter, accidental, mode string) (Key, bool) {
+	name, ok := keyModes[mode]
+	if !ok {
+		return Key{}, false
+	}
+	tonic := strings.ToUpper(letter)
+	switch {
+	case accidental == "":
+	case strings.HasPrefix(accidental, "is") || strings.HasPrefix(accidental, "s") && letter != "a" && letter != "e" || strings.HasPrefix(accidental, "sharp"):
+		tonic += "#"
+	default:
+		tonic += "b"
+	}
+	return Key{Tonic: tonic, Mode: name}, true
+}
+
+// sniffLilyPond recognizes a LilyPond source by the \version statement
+// that starts most of them, or by the commands of its music outside
+// comments
+func sniffLilyPond(header []byte) bool {
+	return lilyPondFile.MatchString(stripLilyPondComments(string(header)))
+}
+
+// stripLilyPondComments removes the % line comments and %{ %} block
+// comments of a LilyPond source, leaving a % inside a string, as in title =
+// "100% Pure Love", where it is
+func stripLilyPondComments(source string) string {
+	var stripped strings.Builder
+	stripped.Grow(len(source))
+	for i := 0; i < len(source); i++ {
+		switch c := source[i]; {
+		case c == '"':
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(source))
+			stripped.WriteString(source[i:end])
+			i = end - 1
+		case c == '%' && strings.HasPrefix(source[i:], "%{"):
+			end := strings.Index(source[i+2:], "%}")
+			if end < 0 {
+				return stripped.String()
+			}
+			i += 2 + end + 1
+		case c == '%':
+			end := strings.IndexByte(source[i:], '\n')
+			if end < 0 {
+				return stripped.String()
+			}
+			i += end - 1
+		default:
+			stripped.WriteByte(c)
+		}
+	}
+	return stripped.String()
+}
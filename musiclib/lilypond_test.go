@@ -0,0 +1,52 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadLilyPond(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		want   ScoreInfo
+		wantOK bool
+	}{
+		{
+			name: "choir staff",
+			path: filepath.Join("testdata", "shenandoah.ly"),
+			want: ScoreInfo{Format: "LilyPond", Title: "Shenandoah", Composer: "American Folk Song", Arranger: "James Erb", Lyricist: "Anonymous", Copyright: "© 1975 Lawson-Gould",
+				Key: Key{Tonic: "Db", Mode: "Major"}, Time: "4/4", Tempos: []float64{60},
+				Parts: []ScorePart{{Name: "Soprano"}, {Name: "Alto"}, {Name: "Tenor"}, {Name: "Bass"}}},
+			wantOK: true,
+		},
+		{
+			name: "percent signs in strings",
+			path: writeTemp(t, "percent.ly", []byte(`\version "2.24.0" % 100% sure
+\header {
+  title = "100% Pure Love" %{ a "quoted" %} comment
+  composer = "Crystal Waters"
+  %{ arranger = "Nobody"
+  %}
+  copyright = "\"50%\" off"
+}
+`)),
+			want:   ScoreInfo{Format: "LilyPond", Title: "100% Pure Love", Composer: "Crystal Waters", Copyright: `"50%" off`},
+			wantOK: true,
+		},
+		{"only comments", writeTemp(t, "comments.ly", []byte("% \\version \"2.24.0\"\n%{ \\header { } %}\n")), ScoreInfo{}, false},
+		{"not LilyPond", filepath.Join("testdata", "water_is_wide.abc"), ScoreInfo{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadLilyPond(tt.path)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadLilyPond() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if tt.wantOK && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadLilyPond() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,387 @@
+package musiclib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// MIDI meta events that are read
+const (
+	midiMetaCopyright  = 0x02
+	midiMetaTrackName  = 0x03
+	midiMetaInstrument = 0x04
+	midiMetaChannel    = 0x20
+	midiMetaEndOfTrack = 0x2F
+	midiMetaTempo      = 0x51
+	midiMetaTimeSig    = 0x58
+	midiMetaKeySig     = 0x59
+)
+
+// midiPercussionChannel is channel 10, whose notes are drum sounds rather
+// than pitches
+const midiPercussionChannel = 9
+
+// midiDefaultVolume is the volume of a channel with no volume controller
+const midiDefaultVolume = 100
+
+// midiChange is a tempo or time signature change at a tick. Value is the
+// microseconds per quarter note of a tempo, or the quarter notes per
+// measure of a time signature.
+type midiChange struct {
+	tick  int
+	value float64
+}
+
+// midiTrack is what is read from one track of a MIDI file. A track of a
+// format 0 file holds every channel, so its notes, names and volumes are
+// kept for each channel; a volume of -1 was never set. An instrument name
+// is pending until the channel it names is known.
+type midiTrack struct {
+	name        string
+	instruments [16]string
+	instrument  string
+	volumes     [16]int
+	ranges      [16]ScorePart
+	end         int
+}
+
+// ReadMIDI reads a Standard MIDI File of format 0 or 1, or one wrapped in a
+// RIFF RMID chunk: the names and note ranges of its tracks, its copyright,
+// key signature, time signature and tempo changes, and its duration. The
+// part of a rehearsal file is the one vocal track, or the vocal track mixed
+// louder than the others.
+func ReadMIDI(filePath string) (ScoreInfo, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return ScoreInfo{Format: "MIDI"}, fmt.Errorf("error reading MIDI '%s': %v", filePath, err)
+	}
+	info, err := ReadMIDIData(data)
+	if err != nil {
+		return info, fmt.Errorf("error reading MIDI '%s': %v", filePath, err)
+	}
+	return info, nil
+}
+
+// ReadMIDIData reads MIDI data, as ReadMIDI reads a file
+func ReadMIDIData(data []byte) (ScoreInfo, error) {
+	info := ScoreInfo{Format: "MIDI"}
+	err := readMIDIScore(data, &info)
+	return info, err
+}
+
+func readMIDIScore(data []byte, info *ScoreInfo) error {
+	if len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "RMID" {
+		i := bytes.Index(data[12:], []byte("MThd"))
+		if i < 0 {
+			return errors.New("no MIDI data in RMID file")
+		}
+		data = data[12+i:]
+	}
+	if len(data) < 14 || string(data[:4]) != "MThd" {
+		return errors.New("not a standard MIDI file")
+	}
+	format := binary.BigEndian.Uint16(data[8:])
+	division := int(binary.BigEndian.Uint16(data[12:]))
+	if division == 0 {
+		return errors.New("no time division")
+	}
+
+	var tracks []midiTrack
+	var tempos, meters []midiChange
+	pos := 8 + int(binary.BigEndian.Uint32(data[4:8]))
+	for pos+8 <= len(data) {
+		chunkType := string(data[pos : pos+4])
+		length := int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		pos += 8
+		end := min(pos+length, len(data))
+		if chunkType == "MTrk" {
+			tracks = append(tracks, readMIDITrack(data[pos:end], info, &tempos, &meters))
+		}
+		pos = end
+	}
+	if len(tracks) == 0 {
+		return errors.New("no tracks")
+	}
+
+	end := 0
+	for _, track := range tracks {
+		end = max(end, track.end)
+	}
+	info.Duration = midiDuration(end, division, tempos)
+	if division&0x8000 == 0 {
+		info.Measures = midiMeasures(end, division, meters)
+	}
+	sort.SliceStable(tempos, func(i, j int) bool { return tempos[i].tick < tempos[j].tick })
+	for _, tempo := range tempos {
+		info.Tempos = append(info.Tempos, 60e6/tempo.value)
+	}
+
+	// The first track of a format 1 file names the sequence when it holds
+	// no notes of its own, as its conductor track usually does
+	first := 0
+	if format != 0 && len(tracks) > 1 && !tracks[0].hasNotes() {
+		info.Title = tracks[0].name
+		first = 1
+	} else if format == 0 {
+		info.Title = tracks[0].name
+	}
+
+	var volumes []int
+	for t, track := range tracks[first:] {
+		for channel := range track.ranges {
+			part := track.ranges[channel]
+			if part.Notes == 0 {
+				continue
+			}
+			part.Name = track.partName(format, channel, first+t)
+			info.Parts = append(info.Parts, part)
+			volume := track.volumes[channel]
+			if volume < 0 {
+				volume = midiDefaultVolume
+			}
+			volumes = append(volumes, volume)
+		}
+	}
+	info.Part = featuredPart(info.Parts, volumes)
+	return nil
+}
+
+// readMIDITrack walks the events of one track, collecting the tempo and
+// time signature changes of every track and the text of the file
+func readMIDITrack(data []byte, info *ScoreInfo, tempos, meters *[]midiChange) midiTrack {
+	var track midiTrack
+	for channel := range track.volumes {
+		track.volumes[channel] = -1
+	}
+
+	pos, tick, prefix := 0, 0, -1
+	var status byte
+	for pos < len(data) {
+		delta, n := readVarLen(data[pos:])
+		if n == 0 {
+			return track
+		}
+		pos += n
+		tick += delta
+		track.end = tick
+		if pos >= len(data) {
+			return track
+		}
+		if data[pos]&0x80 != 0 {
+			status = data[pos]
+			pos++
+		}
+
+		switch {
+		case status == 0xFF:
+			if pos >= len(data) {
+				return track
+			}
+			metaType := data[pos]
+			length, n := readVarLen(data[pos+1:])
+			start := pos + 1 + n
+			if n == 0 || start+length > len(data) {
+				return track
+			}
+			value := data[start : start+length]
+			pos = start + length
+			switch metaType {
+			case midiMetaTrackName:
+				if track.name == "" {
+					track.name = midiText(value)
+				}
+			case midiMetaInstrument:
+				// The instrument names the channel of a channel prefix, or
+				// else the channel of the next channel event
+				track.instrument = midiText(value)
+				if prefix >= 0 {
+					track.nameChannel(prefix)
+				}
+			case midiMetaChannel:
+				if length == 1 && value[0] < 16 {
+					prefix = int(value[0])
+				}
+			case midiMetaCopyright:
+				if info.Copyright == "" {
+					info.Copyright = midiText(value)
+				}
+			case midiMetaTempo:
+				if length == 3 {
+					if microseconds := int(value[0])<<16 | int(value[1])<<8 | int(value[2]); microseconds > 0 {
+						*tempos = append(*tempos, midiChange{tick, float64(microseconds)})
+					}
+				}
+			case midiMetaTimeSig:
+				if length >= 2 && value[0] > 0 && value[1] < 8 {
+					beatType := 1 << value[1]
+					*meters = append(*meters, midiChange{tick, float64(value[0]) * 4 / float64(beatType)})
+					if info.Time == "" {
+						info.Time = strconv.Itoa(int(value[0])) + "/" + strconv.Itoa(beatType)
+					}
+				}
+			case midiMetaKeySig:
+				if length >= 2 && info.Key.Tonic == "" {
+					mode := "major"
+					if value[1] == 1 {
+						mode = "minor"
+					}
+					info.Key, _ = KeyFromSignature(int(int8(value[0])), mode)
+				}
+			case midiMetaEndOfTrack:
+				return track
+			}
+		case status == 0xF0 || status == 0xF7:
+			length, n := readVarLen(data[pos:])
+			if n == 0 {
+				return track
+			}
+			pos += n + length
+		case status&0xF0 == 0xC0 || status&0xF0 == 0xD0:
+			track.nameChannel(int(status & 0x0F))
+			prefix = -1
+			pos++
+		case status >= 0x80:
+			if pos+2 > len(data) {
+				return track
+			}
+			channel := status & 0x0F
+			track.nameChannel(int(channel))
+			prefix = -1
+			switch status & 0xF0 {
+			case 0x90:
+				if data[pos+1] > 0 && channel != midiPercussionChannel {
+					track.ranges[channel].addNote(Pitch(data[pos] & 0x7F))
+				}
+			case 0xB0:
+				if data[pos] == 7 && track.volumes[channel] < 0 {
+					track.volumes[channel] = int(data[pos+1])
+				}
+			}
+			pos += 2
+		default:
+			return track
+		}
+	}
+	return track
+}
+
+// nameChannel gives a pending instrument name to a channel not yet named
+func (t *midiTrack) nameChannel(channel int) {
+	if t.instrument == "" {
+		return
+	}
+	if t.instruments[channel] == "" {
+		t.instruments[channel] = t.instrument
+	}
+	t.instrument = ""
+}
+
+func (t midiTrack) hasNotes() bool {
+	for _, part := range t.ranges {
+		if part.Notes > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// partName names the part of a channel of a track: the track name in a
+// format 1 file, and the instrument name of the channel in a format 0 file
+func (t midiTrack) partName(format uint16, channel, index int) string {
+	if format != 0 && t.name != "" {
+		return t.name
+	}
+	if t.instruments[channel] != "" {
+		return t.instruments[channel]
+	}
+	if format == 0 {
+		return "Channel " + strconv.Itoa(channel+1)
+	}
+	return "Track " + strconv.Itoa(index+1)
+}
+
+// featuredPart returns the name of the part a rehearsal file is for: its
+// only vocal part, or the vocal part mixed louder than all the others
+func featuredPart(parts []ScorePart, volumes []int) string {
+	featured, loudest, tied := -1, -1, false
+	vocal := 0
+	for i, part := range parts {
+		if PartVoices(part.Name) == "" {
+			continue
+		}
+		vocal++
+		switch {
+		case volumes[i] > loudest:
+			featured, loudest, tied = i, volumes[i], false
+		case volumes[i] == loudest:
+			tied = true
+		}
+	}
+	if featured < 0 || (vocal > 1 && tied) {
+		return ""
+	}
+	return parts[featured].Name
+}
+
+// midiDuration converts a tick to a time, following the tempo changes, or
+// the frames per second of a SMPTE time division
+func midiDuration(end, division int, tempos []midiChange) time.Duration {
+	if division&0x8000 != 0 {
+		framesPerSecond := -int(int8(division >> 8))
+		ticksPerFrame := division & 0xFF
+		if framesPerSecond <= 0 || ticksPerFrame == 0 {
+			return 0
+		}
+		return secondsDuration(float64(end) / float64(framesPerSecond*ticksPerFrame))
+	}
+
+	sort.SliceStable(tempos, func(i, j int) bool { return tempos[i].tick < tempos[j].tick })
+	var microseconds float64
+	tick, tempo := 0, 500000.0
+	for _, change := range tempos {
+		if change.tick > end {
+			break
+		}
+		microseconds += float64(change.tick-tick) * tempo / float64(division)
+		tick, tempo = change.tick, change.value
+	}
+	microseconds += float64(end-tick) * tempo / float64(division)
+	return secondsDuration(microseconds / 1e6)
+}
+
+// midiMeasures counts the measures up to a tick, following the time
+// signature changes. A final partial measure counts as a measure.
+func midiMeasures(end, division int, meters []midiChange) int {
+	sort.SliceStable(meters, func(i, j int) bool { return meters[i].tick < meters[j].tick })
+	var measures float64
+	tick, quarters := 0, 4.0
+	for _, change := range meters {
+		if change.tick > end {
+			break
+		}
+		measures += float64(change.tick-tick) / float64(division) / quarters
+		tick, quarters = change.tick, change.value
+	}
+	if quarters <= 0 {
+		return 0
+	}
+	measures += float64(end-tick) / float64(division) / quarters
+	return int(measures + 0.999)
+}
+
+// midiText decodes the text of a meta event, which is UTF-8 in newer files
+// and Latin-1 in older ones
+func midiText(data []byte) string {
+	data = bytes.TrimRight(data, "\x00")
+	if utf8.Valid(data) {
+		return string(bytes.TrimSpace(data))
+	}
+	return decodeLatin1(bytes.TrimSpace(data))
+}
@@ -0,0 +1,123 @@
+package musiclib
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// midiFile builds a format 0 Standard MIDI File of 96 ticks a quarter note
+// from the events of its one track, each led by its delta time
+func midiFile(events ...string) []byte {
+	var track []byte
+	for _, event := range events {
+		track = append(track, event...)
+	}
+	track = append(track, "\x00\xff\x2f\x00"...)
+	data := []byte("MThd\x00\x00\x00\x06\x00\x00\x00\x01\x00\x60MTrk")
+	data = binary.BigEndian.AppendUint32(data, uint32(len(track)))
+	return append(data, track...)
+}
+
+func TestReadMIDIData(t *testing.T) {
+	alto := ScoreInfo{Format: "MIDI", Title: "Ave Maria", Copyright: "© 2019 Hal Leonard",
+		Key: Key{Tonic: "G", Mode: "Major"}, Time: "3/4", Tempos: []float64{96, 72.00002880001152}, Duration: 34999992000, Measures: 16,
+		Parts: []ScorePart{
+			{Name: "Soprano", Lowest: 67, Highest: 79, Notes: 48},
+			{Name: "Alto", Lowest: 59, Highest: 62, Notes: 32},
+			{Name: "Tenor", Lowest: 55, Highest: 59, Notes: 32},
+			{Name: "Bass", Lowest: 43, Highest: 50, Notes: 32},
+			{Name: "Piano", Lowest: 36, Highest: 90, Notes: 32},
+		},
+		Part: "Alto"}
+	featured := readTestdata(t, "alto_featured.mid")
+	riff := append([]byte("RIFF\x00\x00\x00\x00RMIDdata\x00\x00\x00\x00"), featured...)
+	tests := []struct {
+		name   string
+		data   []byte
+		want   ScoreInfo
+		wantOK bool
+	}{
+		{"featured part louder", featured, alto, true},
+		{"RIFF wrapper", riff, alto, true},
+		{
+			name: "format 0 named by instrument",
+			data: readTestdata(t, "format0.mid"),
+			want: ScoreInfo{Format: "MIDI", Title: "Ave Maria", Copyright: "© 2019 Hal Leonard",
+				Key: Key{Tonic: "G", Mode: "Major"}, Time: "3/4", Tempos: []float64{96, 72.00002880001152}, Duration: 54999984000, Measures: 24,
+				Parts: []ScorePart{{Name: "Alto", Lowest: 59, Highest: 62, Notes: 32}},
+				Part:  "Alto"},
+			wantOK: true,
+		},
+		{
+			name: "format 0 instruments name their own channels",
+			data: midiFile(
+				"\x00\xff\x04\x04Alto", "\x00\xc0\x34",
+				"\x00\xff\x04\x05Piano", "\x00\xc1\x00",
+				"\x00\x90\x3c\x50", "\x00\x91\x30\x50",
+				"\x60\x80\x3c\x00", "\x00\x81\x30\x00",
+			),
+			want: ScoreInfo{Format: "MIDI", Duration: 500000000, Measures: 1,
+				Parts: []ScorePart{{Name: "Alto", Lowest: 60, Highest: 60, Notes: 1}, {Name: "Piano", Lowest: 48, Highest: 48, Notes: 1}},
+				Part:  "Alto"},
+			wantOK: true,
+		},
+		{
+			name: "format 0 instruments named by channel prefix",
+			data: midiFile(
+				"\x00\xff\x20\x01\x01", "\x00\xff\x04\x05Piano",
+				"\x00\xff\x20\x01\x00", "\x00\xff\x04\x05Tenor",
+				"\x00\x91\x30\x50", "\x00\x90\x3c\x50",
+				"\x60\x80\x3c\x00", "\x00\x81\x30\x00",
+			),
+			want: ScoreInfo{Format: "MIDI", Duration: 500000000, Measures: 1,
+				Parts: []ScorePart{{Name: "Tenor", Lowest: 60, Highest: 60, Notes: 1}, {Name: "Piano", Lowest: 48, Highest: 48, Notes: 1}},
+				Part:  "Tenor"},
+			wantOK: true,
+		},
+		{"empty", nil, ScoreInfo{}, false},
+		{"not MIDI", readTestdata(t, "octavo.pdf"), ScoreInfo{}, false},
+		{"truncated header", featured[:10], ScoreInfo{}, false},
+		{"RIFF wrapper without MIDI data", riff[:20], ScoreInfo{}, false},
+		{"cut after the header", featured[:14], ScoreInfo{}, false},
+		{
+			// A cut track is read as far as it goes
+			name: "cut in the conductor track",
+			data: featured[:80],
+			want: ScoreInfo{Format: "MIDI", Copyright: "© 2019 Hal Leonard",
+				Key: Key{Tonic: "G", Mode: "Major"}, Time: "3/4", Tempos: []float64{96}, Duration: 15000000000, Measures: 8},
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMIDIData(tt.data)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadMIDIData() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if tt.wantOK && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadMIDIData() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMIDIDurationOverflow(t *testing.T) {
+	// The slowest tempo at one tick a beat, for longer than a Duration holds
+	tempos := []midiChange{{tick: 0, value: 1<<24 - 1}}
+	if got := midiDuration(1<<40, 1, tempos); got != 0 {
+		t.Errorf("midiDuration() = %v, want unknown", got)
+	}
+}
+
+func FuzzReadMIDIData(f *testing.F) {
+	for _, name := range []string{"alto_featured.mid", "format0.mid"} {
+		f.Add(readTestdata(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadMIDIData(data)
+		if err == nil && (info.Duration < 0 || info.Measures < 0) {
+			t.Errorf("negative duration %v or measure count %d", info.Duration, info.Measures)
+		}
+	})
+}
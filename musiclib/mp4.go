@@ -0,0 +1,267 @@
+package musiclib
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMP4MovieLength limits the moov atom read into memory. It holds the
+// sample tables, which grow with the length of a recording, but not this
+// much.
+const maxMP4MovieLength = 64 << 20
+
+// mp4Epoch is the start of the times in ISO media files
+var mp4Epoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// mp4Items maps the iTunes-style metadata items of an ilst atom, and the
+// QuickTime user data atoms of the same names, to the tags they hold
+var mp4Items = map[string]string{
+	"\xa9nam": "title",
+	"\xa9ART": "artist",
+	"aART":    "artist",
+	"\xa9alb": "album",
+	"\xa9wrt": "composer",
+	"\xa9day": "year",
+	"\xa9cmt": "comment",
+	"desc":    "comment",
+	"trkn":    "track",
+}
+
+// mp4Atom is one atom (box) of an ISO media file: its type and payload
+type mp4Atom struct {
+	kind string
+	data []byte
+}
+
+// mp4Atoms splits data into the atoms it holds
+func mp4Atoms(data []byte) []mp4Atom {
+	var atoms []mp4Atom
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		kind := string(data[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return atoms
+			}
+			size, header = binary.BigEndian.Uint64(data[8:]), 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return atoms
+		}
+		atoms = append(atoms, mp4Atom{kind: kind, data: data[header:size]})
+		data = data[size:]
+	}
+	return atoms
+}
+
+// child returns the first atom of a type inside an atom
+func (a mp4Atom) child(kind string) (mp4Atom, bool) {
+	for _, atom := range mp4Atoms(a.data) {
+		if atom.kind == kind {
+			return atom, true
+		}
+	}
+	return mp4Atom{}, false
+}
+
+// path returns the atom found by following a path of atom types
+func (a mp4Atom) path(kinds ...string) (mp4Atom, bool) {
+	atom := a
+	for _, kind := range kinds {
+		var ok bool
+		if atom, ok = atom.child(kind); !ok {
+			return mp4Atom{}, false
+		}
+	}
+	return atom, true
+}
+
+// ReadMP4 reads the metadata of an MP4 or M4A file: the iTunes-style tags
+// of its ilst atom or QuickTime user data, the creation time and duration
+// of the movie header, and whether it has a video track
+func ReadMP4(filePath string) (AudioInfo, error) {
+	return readAudioFile(filePath, "MP4", ReadMP4At)
+}
+
+// ReadMP4At reads MP4 data of the given size, as ReadMP4 reads a file
+func ReadMP4At(r io.ReaderAt, size int64) (AudioInfo, error) {
+	info := AudioInfo{Format: "MP4", TagFormat: "MP4 metadata"}
+
+	movie, err := readMP4Movie(r, size)
+	if err != nil {
+		return info, err
+	}
+
+	if header, ok := movie.child("mvhd"); ok {
+		created, duration, ok := parseMP4MovieHeader(header.data)
+		if ok {
+			info.Created = created
+			info.Properties.Duration = duration
+		}
+	}
+
+	for _, track := range mp4Atoms(movie.data) {
+		if track.kind != "trak" {
+			continue
+		}
+		handler, ok := track.path("mdia", "hdlr")
+		if !ok || len(handler.data) < 12 {
+			continue
+		}
+		switch string(handler.data[8:12]) {
+		case "vide":
+			info.HasVideo = true
+		case "soun":
+			if info.Properties.SampleRate == 0 {
+				info.Properties.SampleRate, info.Properties.Channels = mp4AudioEntry(track)
+			}
+		}
+	}
+
+	if list, ok := movie.path("udta", "meta"); ok {
+		if items, ok := mp4MetaChildren(list).child("ilst"); ok {
+			for _, item := range mp4Atoms(items.data) {
+				if field, ok := mp4Items[item.kind]; ok {
+					info.Tags.set(field, mp4ItemValue(item))
+				}
+			}
+		}
+	}
+	if userData, ok := movie.child("udta"); ok {
+		for _, atom := range mp4Atoms(userData.data) {
+			if field, ok := mp4Items[atom.kind]; ok && len(atom.data) > 4 {
+				// QuickTime text: a 16-bit length and language, then the text
+				length := int(binary.BigEndian.Uint16(atom.data))
+				text := atom.data[4:min(len(atom.data), 4+length)]
+				info.Tags.set(field, strings.ToValidUTF8(string(text), "�"))
+			}
+		}
+	}
+
+	if seconds := info.Properties.Duration.Seconds(); seconds > 0 {
+		info.Properties.Bitrate = int(float64(size) * 8 / seconds / 1000)
+	}
+	return info, nil
+}
+
+// readMP4Movie finds the moov atom among the top-level atoms, which may
+// come after the media data, and reads it
+func readMP4Movie(r io.ReaderAt, size int64) (mp4Atom, error) {
+	header := make([]byte, 16)
+	for offset := int64(0); offset+8 <= size; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return mp4Atom{}, err
+		}
+		atomSize := int64(binary.BigEndian.Uint32(header))
+		kind := string(header[4:8])
+		headerLength := int64(8)
+		switch atomSize {
+		case 0:
+			atomSize = size - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return mp4Atom{}, err
+			}
+			atomSize, headerLength = int64(binary.BigEndian.Uint64(header[8:])), 16
+		}
+		if atomSize < headerLength || offset+atomSize > size {
+			return mp4Atom{}, fmt.Errorf("bad size of atom %q", kind)
+		}
+
+		if kind == "moov" {
+			if atomSize > maxMP4MovieLength {
+				return mp4Atom{}, errors.New("moov atom too long")
+			}
+			data := make([]byte, atomSize-headerLength)
+			if _, err := r.ReadAt(data, offset+headerLength); err != nil {
+				return mp4Atom{}, err
+			}
+			return mp4Atom{kind: kind, data: data}, nil
+		}
+		offset += atomSize
+	}
+	return mp4Atom{}, errors.New("no moov atom")
+}
+
+// parseMP4MovieHeader reads the creation time and duration of an mvhd atom
+func parseMP4MovieHeader(data []byte) (time.Time, time.Duration, bool) {
+	var created, timescale, duration uint64
+	switch {
+	case len(data) >= 32 && data[0] == 1:
+		created = binary.BigEndian.Uint64(data[4:])
+		timescale = uint64(binary.BigEndian.Uint32(data[20:]))
+		duration = binary.BigEndian.Uint64(data[24:])
+	case len(data) >= 20 && data[0] == 0:
+		created = uint64(binary.BigEndian.Uint32(data[4:]))
+		timescale = uint64(binary.BigEndian.Uint32(data[12:]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:]))
+	default:
+		return time.Time{}, 0, false
+	}
+	if timescale == 0 {
+		return time.Time{}, 0, false
+	}
+
+	var createdTime time.Time
+	if created > 0 && created < uint64(math.MaxInt64/time.Second) {
+		createdTime = mp4Epoch.Add(time.Duration(created) * time.Second)
+	}
+	seconds := float64(duration) / float64(timescale)
+	return createdTime, secondsDuration(seconds), true
+}
+
+// mp4AudioEntry reads the sample rate and channels of the first sample
+// entry of an audio track
+func mp4AudioEntry(track mp4Atom) (int, int) {
+	descriptions, ok := track.path("mdia", "minf", "stbl", "stsd")
+	if !ok || len(descriptions.data) < 8 {
+		return 0, 0
+	}
+	entries := mp4Atoms(descriptions.data[8:])
+	if len(entries) == 0 || len(entries[0].data) < 28 {
+		return 0, 0
+	}
+	entry := entries[0].data
+	channels := int(binary.BigEndian.Uint16(entry[16:]))
+	sampleRate := int(binary.BigEndian.Uint32(entry[24:]) >> 16)
+	return sampleRate, channels
+}
+
+// mp4MetaChildren returns a meta atom with its children in data. The meta
+// atom of MP4 files starts with a version and flags, but QuickTime's does
+// not.
+func mp4MetaChildren(meta mp4Atom) mp4Atom {
+	if len(meta.data) >= 4 && binary.BigEndian.Uint32(meta.data) == 0 {
+		meta.data = meta.data[4:]
+	}
+	return meta
+}
+
+// mp4ItemValue reads the data atom of an ilst item as text
+func mp4ItemValue(item mp4Atom) string {
+	data, ok := item.child("data")
+	if !ok || len(data.data) < 8 {
+		return ""
+	}
+	value := data.data[8:]
+	if item.kind == "trkn" {
+		if len(value) < 4 {
+			return ""
+		}
+		if track := binary.BigEndian.Uint16(value[2:]); track > 0 {
+			return strconv.Itoa(int(track))
+		}
+		return ""
+	}
+	return strings.ToValidUTF8(string(value), "�")
+}
@@ -0,0 +1,82 @@
+package musiclib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestReadMP4At(t *testing.T) {
+	m4a := readTestdata(t, "tags.m4a")
+	// The movie header's duration is longer than a Duration holds
+	overflow := bytes.Clone(m4a)
+	header := bytes.Index(overflow, []byte("mvhd")) + 4
+	binary.BigEndian.PutUint32(overflow[header+20:], 1)
+	binary.BigEndian.PutUint64(overflow[header+24:], 1<<62)
+	m4aTags := AudioTags{Title: "Ave Maria - Tenor 2", Album: "Learning Tracks", Composer: "Franz Biebl", Track: "5"}
+	noMovie := AudioInfo{Format: "MP4", TagFormat: "MP4 metadata"}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   AudioInfo
+		wantOK bool
+	}{
+		{
+			name: "video",
+			data: readTestdata(t, "video.mp4"),
+			want: AudioInfo{Format: "MP4", TagFormat: "MP4 metadata",
+				Tags:       AudioTags{Title: "Concert 2019 - Shenandoah", Artist: "Forty Niners Chorus"},
+				Properties: AudioProperties{Duration: 3600000000000, SampleRate: 48000, Channels: 2},
+				Created:    time.Date(2019, time.April, 10, 18, 0, 0, 0, time.UTC),
+				HasVideo:   true},
+			wantOK: true,
+		},
+		{
+			// Version 1 movie header and a 64-bit mdat size
+			name: "audio",
+			data: m4a,
+			want: AudioInfo{Format: "MP4", TagFormat: "MP4 metadata", Tags: m4aTags,
+				Properties: AudioProperties{Duration: 95000000000, SampleRate: 44100, Channels: 1},
+				Created:    time.Date(2004, time.January, 1, 0, 0, 0, 0, time.UTC)},
+			wantOK: true,
+		},
+		{
+			name: "duration overflow",
+			data: overflow,
+			want: AudioInfo{Format: "MP4", TagFormat: "MP4 metadata", Tags: m4aTags,
+				Properties: AudioProperties{SampleRate: 44100, Channels: 1},
+				Created:    time.Date(2004, time.January, 1, 0, 0, 0, 0, time.UTC)},
+			wantOK: true,
+		},
+		{name: "empty", want: noMovie},
+		{name: "cut after an atom header", data: m4a[:8], want: noMovie},
+		{name: "cut in the file type", data: m4a[:40], want: noMovie},
+		{name: "cut before the movie", data: m4a[:200], want: noMovie},
+		{name: "not MP4", data: readTestdata(t, "id3v23.mp3"), want: noMovie},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMP4At(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadMP4At() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ReadMP4At() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReadMP4At(f *testing.F) {
+	for _, name := range []string{"video.mp4", "tags.m4a"} {
+		f.Add(readTestdata(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadMP4At(bytes.NewReader(data), int64(len(data)))
+		if err == nil && (info.Properties.Duration < 0 || info.Properties.Bitrate < 0) {
+			t.Errorf("negative duration %v or bitrate %d", info.Properties.Duration, info.Properties.Bitrate)
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package musiclib
+
+import (
+	"archive/zip"
+	"bufio"
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// museScoreMetaTags maps the metaTags of a MuseScore score, and the styles
+// of the text frames on its first page, to the ScoreInfo field they fill
+var museScoreMetaTags = map[string]string{
+	"worktitle":     "title",
+	"movementtitle": "movement",
+	"title":         "title",
+	"composer":      "composer",
+	"arranger":      "arranger",
+	"lyricist":      "lyricist",
+	"poet":          "lyricist",
+	"copyright":     "copyright",
+}
+
+// museScoreMarkup matches the formatting tags, such as <b> and <font
+// size="18"/>, inside the text of a MuseScore text frame
+var museScoreMarkup = regexp.MustCompile(`<[^>]*>`)
+
+// museScorePart is a <Part> of a MuseScore score: the staves it is written
+// on and the names of its instrument
+type museScorePart struct {
+	Staves []struct {
+		ID string `xml:"id,attr"`
+	} `xml:"Staff"`
+	TrackName  string `xml:"trackName"`
+	Instrument struct {
+		LongName   string `xml:"longName"`
+		ShortName  string `xml:"shortName"`
+		TrackName  string `xml:"trackName"`
+		UseDrumset int    `xml:"useDrumset"`
+	} `xml:"Instrument"`
+}
+
+// ReadMuseScore reads the metaTags, title frame, key and time signatures,
+// tempos, length, page breaks and part ranges of a MuseScore score, either
+// an uncompressed .mscx file or the .mscx inside a .mscz archive. MuseScore
+// lays out pages when it opens a score, so the page count is only known
+// for a score whose pages end in page breaks.
+func ReadMuseScore(filePath string) (ScoreInfo, error) {
+	info := ScoreInfo{Format: "MuseScore"}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return info, fmt.Errorf("error reading MuseScore '%s': %v", filePath, err)
+	}
+	defer file.Close()
+
+	header := make([]byte, len(zipSignature))
+	if _, err = io.ReadFull(file, header); err == nil && string(header) == string(zipSignature) {
+		err = readMSCZScore(filePath, &info)
+	} else if _, err = file.Seek(0, io.SeekStart); err == nil {
+		err = readMuseScoreXML(file, &info)
+	}
+	if err != nil {
+		return info, fmt.Errorf("error reading MuseScore '%s': %v", filePath, err)
+	}
+	return info, nil
+}
+
+// readMSCZScore reads the score named in the container of a .mscz archive.
+// Parts extracted from the score are kept beside it, under Excerpts/.
+func readMSCZScore(filename string, info *ScoreInfo) error {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	file, err := zipRootFile(&archive.Reader, ".mscx")
+	if err != nil {
+		return err
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return readMuseScoreXML(reader, info)
+}
+
+// readMuseScoreXML reads a MuseScore document. The parts are listed first,
+// then the music of each staff; MuseScore 3 files also hold the extracted
+// parts as nested scores, which are skipped so no note is counted twice.
+func readMuseScoreXML(r io.Reader, info *ScoreInfo) error {
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	decoder.Strict = false
+
+	tags := make(map[string]string)
+	frames := make(map[string]string)
+	var parts []*ScorePart
+	staffParts := make(map[string]int)
+	drumset := make(map[int]bool)
+	measures := make(map[string]int)
+	inScore := false
+	root, firstStaff, currentStaff := "", "", ""
+	pageBreaks := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF && root == "" {
+			return errors.New("not a MuseScore document")
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = start.Name.Local
+			if root != "museScore" {
+				return fmt.Errorf("not a MuseScore document but <%s>", root)
+			}
+		}
+
+		switch start.Name.Local {
+		case "Score":
+			if inScore {
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+			}
+			inScore = true
+		case "metaTag":
+			var tag struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			}
+			if err := decoder.DecodeElement(&tag, &start); err != nil {
+				return err
+			}
+			if field, ok := museScoreMetaTags[strings.ToLower(tag.Name)]; ok && tags[field] == "" {
+				tags[field] = strings.TrimSpace(tag.Value)
+			}
+		case "Part":
+			var part museScorePart
+			if err := decoder.DecodeElement(&part, &start); err != nil {
+				return err
+			}
+			name := ""
+			for _, candidate := range []string{part.TrackName, part.Instrument.LongName, part.Instrument.TrackName, part.Instrument.ShortName} {
+				if name = joinLines(candidate); name != "" {
+					break
+				}
+			}
+			if name == "" {
+				name = fmt.Sprintf("Part %d", len(parts)+1)
+			}
+			for _, staff := range part.Staves {
+				staffParts[staff.ID] = len(parts)
+			}
+			drumset[len(parts)] = part.Instrument.UseDrumset != 0
+			parts = append(parts, &ScorePart{Name: name})
+		case "Staff":
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "id" {
+					currentStaff = attr.Value
+				}
+			}
+			if firstStaff == "" {
+				firstStaff = currentStaff
+			}
+		case "Text":
+			var text struct {
+				Style string `xml:"style"`
+				Text  struct {
+					Markup string `xml:",innerxml"`
+				} `xml:"text"`
+			}
+			if err := decoder.DecodeElement(&text, &start); err != nil {
+				return err
+			}
+			style := strings.ToLower(strings.TrimSpace(text.Style))
+			if field, ok := museScoreMetaTags[style]; ok && frames[field] == "" {
+				frames[field] = html.UnescapeString(museScoreMarkup.ReplaceAllString(text.Text.Markup, ""))
+			}
+		case "Measure":
+			measures[currentStaff]++
+		case "LayoutBreak":
+			var layoutBreak struct {
+				Subtype string `xml:"subtype"`
+			}
+			if err := decoder.DecodeElement(&layoutBreak, &start); err != nil {
+				return err
+			}
+			if layoutBreak.Subtype == "page" && currentStaff == firstStaff {
+				pageBreaks++
+			}
+		case "KeySig":
+			var key struct {
+				ConcertKey *int   `xml:"concertKey"`
+				Accidental *int   `xml:"accidental"`
+				Mode       string `xml:"mode"`
+			}
+			if err := decoder.DecodeElement(&key, &start); err != nil {
+				return err
+			}
+			fifths := key.ConcertKey
+			if fifths == nil {
+				fifths = key.Accidental
+			}
+			if fifths != nil && info.Key.Tonic == "" {
+				info.Key, _ = KeyFromSignature(*fifths, key.Mode)
+			}
+		case "TimeSig":
+			var time struct {
+				SigN string `xml:"sigN"`
+				SigD string `xml:"sigD"`
+			}
+			if err := decoder.DecodeElement(&time, &start); err != nil {
+				return err
+			}
+			if time.SigN != "" && time.SigD != "" && info.Time == "" {
+				info.Time = time.SigN + "/" + time.SigD
+			}
+		case "Tempo":
+			// MuseScore keeps a tempo in quarter notes per second
+			var tempo struct {
+				Tempo float64 `xml:"tempo"`
+			}
+			if err := decoder.DecodeElement(&tempo, &start); err != nil {
+				return err
+			}
+			if tempo.Tempo > 0 && currentStaff == firstStaff {
+				info.Tempos = append(info.Tempos, tempo.Tempo*60)
+			}
+		case "Note":
+			var note struct {
+				Pitch *int `xml:"pitch"`
+			}
+			if err := decoder.DecodeElement(&note, &start); err != nil {
+				return err
+			}
+			if index, ok := staffParts[currentStaff]; ok && note.Pitch != nil && !drumset[index] {
+				parts[index].addNote(Pitch(*note.Pitch))
+			}
+		}
+	}
+
+	for _, title := range []string{tags["title"], tags["movement"], frames["title"]} {
+		if title = joinLines(title); title != "" {
+			info.Title = title
+			break
+		}
+	}
+	info.Composer, info.Arranger, info.Lyricist = tags["composer"], tags["arranger"], tags["lyricist"]
+	if info.Composer == "" || info.Arranger == "" {
+		composer, arranger := splitCredits(frames["composer"])
+		info.Composer = cmp.Or(info.Composer, composer)
+		info.Arranger = cmp.Or(info.Arranger, arranger)
+	}
+	if info.Lyricist == "" {
+		info.Lyricist = cleanCredit(frames["lyricist"])
+	}
+	info.Copyright = joinLines(tags["copyright"])
+	for _, count := range measures {
+		info.Measures = max(info.Measures, count)
+	}
+	if pageBreaks > 0 {
+		info.Pages = pageBreaks + 1
+	}
+	for _, part := range parts {
+		info.Parts = append(info.Parts, *part)
+	}
+	return nil
+}
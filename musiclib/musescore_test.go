@@ -0,0 +1,56 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadMuseScore(t *testing.T) {
+	// The excerpt nested in the score is skipped, and the page break on
+	// the second staff makes two pages
+	satb := ScoreInfo{Format: "MuseScore", Title: "Ave Maria", Composer: "Franz Biebl", Arranger: "Mac Huff", Copyright: "© 2019 Santa Barbara Music",
+		Key: Key{Tonic: "G", Mode: "Major"}, Time: "3/4", Tempos: []float64{96, 72}, Measures: 4, Pages: 2,
+		Parts: []ScorePart{
+			{Name: "Soprano", Lowest: 67, Highest: 79, Notes: 8},
+			{Name: "Alto", Lowest: 59, Highest: 62, Notes: 8},
+			{Name: "Tenor", Lowest: 55, Highest: 59, Notes: 8},
+			{Name: "Bass", Lowest: 43, Highest: 50, Notes: 8},
+			{Name: "Piano", Lowest: 36, Highest: 72, Notes: 16},
+		}}
+	tests := []struct {
+		name   string
+		path   string
+		want   ScoreInfo
+		wantOK bool
+	}{
+		{"uncompressed", filepath.Join("testdata", "satb.mscx"), satb, true},
+		{"compressed", filepath.Join("testdata", "satb.mscz"), satb, true},
+		{"archive without a score", filepath.Join("testdata", "compressed.mxl"), ScoreInfo{}, false},
+		{"MusicXML", filepath.Join("testdata", "satb_piano.musicxml"), ScoreInfo{}, false},
+		{"truncated archive", writeTemp(t, "truncated.mscz", readTestdata(t, "satb.mscz")[:600]), ScoreInfo{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMuseScore(tt.path)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadMuseScore() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if tt.wantOK && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadMuseScore() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReadMuseScore(f *testing.F) {
+	for _, name := range []string{"satb.mscx", "satb.mscz"} {
+		f.Add(readTestdata(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadMuseScore(writeTemp(t, "fuzz.mscz", data))
+		if err == nil && (info.Measures < 0 || info.Pages < 0) {
+			t.Errorf("negative measure count %d or page count %d", info.Measures, info.Pages)
+		}
+	})
+}
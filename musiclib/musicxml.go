@@ -0,0 +1,284 @@
+package musiclib
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// musicXMLCreators maps the creator types of a MusicXML identification, and
+// the credit types of its credits, to the ScoreInfo field they fill
+var musicXMLCreators = map[string]string{
+	"composer": "composer",
+	"arranger": "arranger",
+	"lyricist": "lyricist",
+	"poet":     "lyricist",
+	"words":    "lyricist",
+}
+
+// ReadMusicXML reads the title, credits, key and time signatures, length
+// and part ranges of a MusicXML score, or of the score inside a compressed
+// MusicXML (.mxl) archive
+func ReadMusicXML(filePath string) (ScoreInfo, error) {
+	info := ScoreInfo{Format: "MusicXML"}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return info, fmt.Errorf("error reading MusicXML '%s': %v", filePath, err)
+	}
+	defer file.Close()
+
+	header := make([]byte, len(zipSignature))
+	if _, err = io.ReadFull(file, header); err == nil && string(header) == string(zipSignature) {
+		info.Format = "MXL"
+		err = readMXLScore(filePath, &info)
+	} else if _, err = file.Seek(0, io.SeekStart); err == nil {
+		err = readMusicXMLScore(file, &info)
+	}
+	if err != nil {
+		return info, fmt.Errorf("error reading MusicXML '%s': %v", filePath, err)
+	}
+	return info, nil
+}
+
+// readMXLScore reads the score named in the container of a compressed
+// MusicXML file
+func readMXLScore(filename string, info *ScoreInfo) error {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	file, err := zipRootFile(&archive.Reader, ".xml", ".musicxml")
+	if err != nil {
+		return err
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return readMusicXMLScore(reader, info)
+}
+
+// musicXMLNote is the part of a <note> element the range is read from.
+// Rests and unpitched percussion notes have no <pitch>.
+type musicXMLNote struct {
+	Pitch *struct {
+		Step   string  `xml:"step"`
+		Alter  float64 `xml:"alter"`
+		Octave int     `xml:"octave"`
+	} `xml:"pitch"`
+}
+
+// readMusicXMLScore reads a partwise or timewise MusicXML document. The
+// measures of a partwise score are counted in each part, and those of a
+// timewise score once for all of them.
+func readMusicXMLScore(r io.Reader, info *ScoreInfo) error {
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	decoder.Strict = false
+
+	var workTitle, movementTitle, creditTitle string
+	credits := make(map[string]string)
+	parts := make(map[string]*ScorePart)
+	var partIDs []string
+	measures := make(map[string]int)
+	timewise := false
+	root, currentPart := "", ""
+	part := func(id string) *ScorePart {
+		if parts[id] == nil {
+			parts[id] = &ScorePart{Name: id}
+			partIDs = append(partIDs, id)
+		}
+		return parts[id]
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF && root == "" {
+			return errors.New("not a MusicXML score")
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = start.Name.Local
+			if root != "score-partwise" && root != "score-timewise" {
+				return fmt.Errorf("not a MusicXML score but <%s>", root)
+			}
+		}
+
+		switch start.Name.Local {
+		case "score-timewise":
+			timewise = true
+		case "work-title":
+			if err := decoder.DecodeElement(&workTitle, &start); err != nil {
+				return err
+			}
+		case "movement-title":
+			if err := decoder.DecodeElement(&movementTitle, &start); err != nil {
+				return err
+			}
+		case "creator":
+			var creator struct {
+				Type string `xml:"type,attr"`
+				Name string `xml:",chardata"`
+			}
+			if err := decoder.DecodeElement(&creator, &start); err != nil {
+				return err
+			}
+			if field, ok := musicXMLCreators[strings.ToLower(creator.Type)]; ok && credits[field] == "" {
+				credits[field] = cleanCredit(creator.Name)
+			}
+		case "credit":
+			var credit struct {
+				Types []string `xml:"credit-type"`
+				Words []string `xml:"credit-words"`
+			}
+			if err := decoder.DecodeElement(&credit, &start); err != nil {
+				return err
+			}
+			text := strings.Join(credit.Words, " ")
+			for _, creditType := range credit.Types {
+				if creditType == "title" && creditTitle == "" {
+					creditTitle = text
+				} else if field, ok := musicXMLCreators[creditType]; ok && credits[field] == "" {
+					credits[field] = cleanCredit(text)
+				}
+			}
+		case "score-part":
+			var scorePart struct {
+				ID           string `xml:"id,attr"`
+				Name         string `xml:"part-name"`
+				Abbreviation string `xml:"part-abbreviation"`
+			}
+			if err := decoder.DecodeElement(&scorePart, &start); err != nil {
+				return err
+			}
+			p := part(scorePart.ID)
+			for _, name := range []string{scorePart.Name, scorePart.Abbreviation} {
+				if name = joinLines(name); name != "" {
+					p.Name = name
+					break
+				}
+			}
+		case "part":
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "id" {
+					currentPart = attr.Value
+				}
+			}
+		case "measure":
+			if timewise {
+				measures[""]++
+			} else {
+				measures[currentPart]++
+			}
+		case "key":
+			var key struct {
+				Fifths *int   `xml:"fifths"`
+				Mode   string `xml:"mode"`
+			}
+			if err := decoder.DecodeElement(&key, &start); err != nil {
+				return err
+			}
+			if key.Fifths != nil && info.Key.Tonic == "" {
+				info.Key, _ = KeyFromSignature(*key.Fifths, key.Mode)
+			}
+		case "time":
+			var time struct {
+				Beats    string `xml:"beats"`
+				BeatType string `xml:"beat-type"`
+			}
+			if err := decoder.DecodeElement(&time, &start); err != nil {
+				return err
+			}
+			if time.Beats != "" && time.BeatType != "" && info.Time == "" {
+				info.Time = time.Beats + "/" + time.BeatType
+			}
+		case "note":
+			var note musicXMLNote
+			if err := decoder.DecodeElement(&note, &start); err != nil {
+				return err
+			}
+			if pitch, ok := note.pitch(); ok {
+				part(currentPart).addNote(pitch)
+			}
+		}
+	}
+
+	for _, title := range []string{workTitle, movementTitle, creditTitle} {
+		if title = joinLines(title); title != "" {
+			info.Title = title
+			break
+		}
+	}
+	info.Composer, info.Arranger, info.Lyricist = credits["composer"], credits["arranger"], credits["lyricist"]
+	for _, count := range measures {
+		info.Measures = max(info.Measures, count)
+	}
+	for _, id := range partIDs {
+		info.Parts = append(info.Parts, *parts[id])
+	}
+	return nil
+}
+
+// pitch returns the MIDI note of a pitched note. A microtonal alteration
+// is rounded to the nearest semitone.
+func (n musicXMLNote) pitch() (Pitch, bool) {
+	if n.Pitch == nil || len(n.Pitch.Step) != 1 {
+		return 0, false
+	}
+	step, ok := pitchSteps[strings.ToUpper(n.Pitch.Step)[0]]
+	if !ok {
+		return 0, false
+	}
+	return Pitch((n.Pitch.Octave+1)*12 + step + int(math.Round(n.Pitch.Alter))), true
+}
+
+// joinLines joins the lines of a title or name into one
+func joinLines(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// cleanCredit joins the lines of a credit into one, and drops the words it
+// starts with, as in "Arr. Mac Huff"
+func cleanCredit(text string) string {
+	text = joinLines(text)
+	for _, prefix := range []string{"words and music by ", "music by ", "composed by ", "arranged by ", "arr. ", "arr ", "words by ", "lyrics by ", "text by ", "by "} {
+		if len(text) > len(prefix) && strings.EqualFold(text[:len(prefix)], prefix) {
+			return strings.TrimSpace(text[len(prefix):])
+		}
+	}
+	return text
+}
+
+// splitCredits reads credit lines, such as the composer text of a title
+// frame or the C: fields of an ABC tune, which often credit the composer
+// and the arranger in turn, as in "Franz Biebl" over "arr. Mac Huff"
+func splitCredits(text string) (composer, arranger string) {
+	for _, line := range strings.Split(text, "\n") {
+		if groups := arrangerCredit.FindStringSubmatch(line); groups != nil {
+			if arranger == "" {
+				arranger = creditName(groups[1])
+			}
+		} else if line = cleanCredit(line); line != "" && composer == "" {
+			composer = line
+		}
+	}
+	return composer, arranger
+}
@@ -0,0 +1,93 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// timewiseScore is a timewise MusicXML score of two measures for two parts
+const timewiseScore = `<?xml version="1.0" encoding="UTF-8"?>
+<score-timewise version="4.0">
+<movement-title>Locus Iste</movement-title>
+<identification><creator type="composer">Anton Bruckner</creator></identification>
+<part-list><score-part id="P1"><part-name>Tenor 1</part-name></score-part><score-part id="P2"><part-name>Bass</part-name></score-part></part-list>
+<measure number="1">
+<part id="P1"><attributes><key><fifths>0</fifths></key><time><beats>4</beats><beat-type>4</beat-type></time></attributes><note><pitch><step>C</step><octave>4</octave></pitch></note></part>
+<part id="P2"><note><pitch><step>C</step><octave>3</octave></pitch></note></part>
+</measure>
+<measure number="2">
+<part id="P1"><note><pitch><step>E</step><octave>4</octave></pitch></note></part>
+<part id="P2"><note><pitch><step>G</step><alter>-1</alter><octave>2</octave></pitch></note></part>
+</measure>
+</score-timewise>
+`
+
+func TestReadMusicXML(t *testing.T) {
+	satb := ScoreInfo{Title: "Ave Maria", Composer: "Franz Biebl", Arranger: "Mac Huff", Lyricist: "Traditional",
+		Key: Key{Tonic: "G", Mode: "Major"}, Time: "3/4", Measures: 3,
+		Parts: []ScorePart{
+			{Name: "Soprano", Lowest: 67, Highest: 79, Notes: 9},
+			{Name: "Alto", Lowest: 59, Highest: 62, Notes: 6},
+			{Name: "Tenor", Lowest: 55, Highest: 59, Notes: 6},
+			{Name: "Bass", Lowest: 43, Highest: 50, Notes: 6},
+			{Name: "Piano", Lowest: 36, Highest: 90, Notes: 6},
+		}}
+	withFormat := func(info ScoreInfo, format string) ScoreInfo {
+		info.Format = format
+		return info
+	}
+	tests := []struct {
+		name   string
+		path   string
+		want   ScoreInfo
+		wantOK bool
+	}{
+		{"partwise", filepath.Join("testdata", "satb_piano.musicxml"), withFormat(satb, "MusicXML"), true},
+		{"compressed", filepath.Join("testdata", "compressed.mxl"), withFormat(satb, "MXL"), true},
+		{
+			name: "timewise",
+			path: writeTemp(t, "timewise.musicxml", []byte(timewiseScore)),
+			want: ScoreInfo{Format: "MusicXML", Title: "Locus Iste", Composer: "Anton Bruckner",
+				Key: Key{Tonic: "C", Mode: "Major"}, Time: "4/4", Measures: 2,
+				Parts: []ScorePart{{Name: "Tenor 1", Lowest: 60, Highest: 64, Notes: 2}, {Name: "Bass", Lowest: 42, Highest: 48, Notes: 2}}},
+			wantOK: true,
+		},
+		{
+			name: "other XML",
+			path: writeTemp(t, "container.xml", []byte(`<?xml version="1.0"?><container><rootfiles/></container>`)),
+		},
+		{
+			name: "not XML",
+			path: filepath.Join("testdata", "octavo.pdf"),
+		},
+		{
+			name: "truncated archive",
+			path: writeTemp(t, "truncated.mxl", readTestdata(t, "compressed.mxl")[:300]),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMusicXML(tt.path)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadMusicXML() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if tt.wantOK && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadMusicXML() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReadMusicXML(f *testing.F) {
+	for _, name := range []string{"satb_piano.musicxml", "compressed.mxl"} {
+		f.Add(readTestdata(f, name))
+	}
+	f.Add([]byte(timewiseScore))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadMusicXML(writeTemp(t, "fuzz.musicxml", data))
+		if err == nil && info.Measures < 0 {
+			t.Errorf("negative measure count %d", info.Measures)
+		}
+	})
+}
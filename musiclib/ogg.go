@@ -0,0 +1,169 @@
+package musiclib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// maxOggHeaderLength limits the header packets read from an Ogg file. The
+// comment packet can hold cover art, but not this much.
+const maxOggHeaderLength = 16 << 20
+
+// oggTailLength is how much of the end of an Ogg file is searched for the
+// last page, whose granule position gives the duration
+const oggTailLength = 64 * 1024
+
+// oggPage is one page of an Ogg stream
+type oggPage struct {
+	granule  int64
+	serial   uint32
+	segments []byte
+	data     []byte
+}
+
+// readOggPage reads the page at the reader's position
+func readOggPage(r io.Reader) (oggPage, error) {
+	header := make([]byte, 27)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return oggPage{}, err
+	}
+	if string(header[:4]) != "OggS" {
+		return oggPage{}, errors.New("lost page sync")
+	}
+
+	page := oggPage{
+		granule: int64(binary.LittleEndian.Uint64(header[6:])),
+		serial:  binary.LittleEndian.Uint32(header[14:]),
+	}
+	page.segments = make([]byte, header[26])
+	if _, err := io.ReadFull(r, page.segments); err != nil {
+		return oggPage{}, err
+	}
+	length := 0
+	for _, lacing := range page.segments {
+		length += int(lacing)
+	}
+	page.data = make([]byte, length)
+	if _, err := io.ReadFull(r, page.data); err != nil {
+		return oggPage{}, err
+	}
+	return page, nil
+}
+
+// ReadOgg reads the identification and comment headers of an Ogg Vorbis or
+// Opus file, and its duration from the granule position of its last page
+func ReadOgg(filePath string) (AudioInfo, error) {
+	return readAudioFile(filePath, "Ogg", ReadOggAt)
+}
+
+// ReadOggAt reads Ogg data of the given size, as ReadOgg reads a file
+func ReadOggAt(r io.ReaderAt, size int64) (AudioInfo, error) {
+	info := AudioInfo{Format: "OGG", TagFormat: "Vorbis comment"}
+
+	// The first two packets of the first logical stream are its headers
+	reader := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	var packets [][]byte
+	var packet []byte
+	var serial uint32
+	for pages := 0; len(packets) < 2; pages++ {
+		page, err := readOggPage(reader)
+		if err != nil {
+			return info, err
+		}
+		if pages == 0 {
+			serial = page.serial
+		} else if page.serial != serial {
+			continue
+		}
+
+		start := 0
+		for _, lacing := range page.segments {
+			packet = append(packet, page.data[start:start+int(lacing)]...)
+			start += int(lacing)
+			if lacing < 255 {
+				packets = append(packets, packet)
+				packet = nil
+			}
+		}
+		if len(packet) > maxOggHeaderLength {
+			return info, errors.New("header packet too long")
+		}
+	}
+
+	identification, comments := packets[0], packets[1]
+	var granuleRate, preSkip, nominalBitrate int64
+	var commentData []byte
+	switch {
+	case bytes.HasPrefix(identification, []byte("\x01vorbis")) && len(identification) >= 30:
+		info.Properties.Channels = int(identification[11])
+		info.Properties.SampleRate = int(binary.LittleEndian.Uint32(identification[12:]))
+		nominalBitrate = int64(int32(binary.LittleEndian.Uint32(identification[20:])))
+		granuleRate = int64(info.Properties.SampleRate)
+		if bytes.HasPrefix(comments, []byte("\x03vorbis")) {
+			commentData = comments[7:]
+		}
+	case bytes.HasPrefix(identification, []byte("OpusHead")) && len(identification) >= 19:
+		info.Properties.Channels = int(identification[9])
+		preSkip = int64(binary.LittleEndian.Uint16(identification[10:]))
+		// Opus always runs at 48 kHz; the header keeps the rate of the input
+		info.Properties.SampleRate = int(binary.LittleEndian.Uint32(identification[12:]))
+		if info.Properties.SampleRate == 0 {
+			info.Properties.SampleRate = 48000
+		}
+		granuleRate = 48000
+		if bytes.HasPrefix(comments, []byte("OpusTags")) {
+			commentData = comments[8:]
+		}
+	default:
+		return info, errors.New("not a Vorbis or Opus stream")
+	}
+	if commentData == nil {
+		return info, errors.New("no comment header")
+	}
+	tags, err := parseVorbisComment(commentData)
+	info.Tags = tags
+	if err != nil {
+		return info, err
+	}
+
+	granule, ok := lastOggGranule(r, size, serial)
+	if ok && granuleRate > 0 && granule > preSkip {
+		seconds := float64(granule-preSkip) / float64(granuleRate)
+		info.Properties.Duration = secondsDuration(seconds)
+		info.Properties.Bitrate = int(float64(size) * 8 / seconds / 1000)
+	}
+	if nominalBitrate > 0 {
+		info.Properties.Bitrate = int(nominalBitrate / 1000)
+	}
+	return info, nil
+}
+
+// lastOggGranule returns the granule position of the last page of a
+// logical stream, which counts the samples before its end
+func lastOggGranule(r io.ReaderAt, size int64, serial uint32) (int64, bool) {
+	length := min(size, oggTailLength)
+	tail := make([]byte, length)
+	if _, err := r.ReadAt(tail, size-length); err != nil && err != io.EOF {
+		return 0, false
+	}
+
+	for end := len(tail); end > 0; {
+		i := bytes.LastIndex(tail[:end], []byte("OggS"))
+		if i < 0 {
+			return 0, false
+		}
+		if i+27 > len(tail) {
+			end = i
+			continue
+		}
+		granule := int64(binary.LittleEndian.Uint64(tail[i+6:]))
+		if binary.LittleEndian.Uint32(tail[i+14:]) == serial && granule >= 0 {
+			return granule, true
+		}
+		end = i
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+package musiclib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadOggAt(t *testing.T) {
+	vorbis := readTestdata(t, "vorbis.ogg")
+	// The granule of the last page counts more samples than a Duration holds
+	overflow := bytes.Clone(vorbis)
+	binary.LittleEndian.PutUint64(overflow[bytes.LastIndex(overflow, []byte("OggS"))+6:], 1<<62)
+	vorbisTags := AudioTags{Title: "Ave Maria", Album: "Spring Rehearsal", Composer: "Franz Biebl", Track: "4", Part: "Tenor 1"}
+	noHeaders := AudioInfo{Format: "OGG", TagFormat: "Vorbis comment"}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   AudioInfo
+		wantOK bool
+	}{
+		{
+			name: "Vorbis",
+			data: vorbis,
+			want: AudioInfo{Format: "OGG", TagFormat: "Vorbis comment", Tags: vorbisTags,
+				Properties: AudioProperties{Duration: 125000000000, Bitrate: 128, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{
+			name: "Opus",
+			data: readTestdata(t, "tags.opus"),
+			want: AudioInfo{Format: "OGG", TagFormat: "Vorbis comment",
+				Tags:       AudioTags{Title: "Bogoroditse Devo - Bass", Artist: "Rachmaninoff"},
+				Properties: AudioProperties{Duration: 61000000000, SampleRate: 44100, Channels: 1}},
+			wantOK: true,
+		},
+		{
+			name: "granule overflow",
+			data: overflow,
+			want: AudioInfo{Format: "OGG", TagFormat: "Vorbis comment", Tags: vorbisTags,
+				Properties: AudioProperties{Bitrate: 128, SampleRate: 44100, Channels: 2}},
+			wantOK: true,
+		},
+		{name: "empty", want: noHeaders},
+		{name: "cut in the first page header", data: vorbis[:27], want: noHeaders},
+		{name: "cut in the identification header", data: vorbis[:60], want: noHeaders},
+		{name: "cut in the comment header", data: vorbis[:200], want: noHeaders},
+		{name: "not Ogg", data: readTestdata(t, "id3v23.mp3"), want: noHeaders},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadOggAt(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadOggAt() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ReadOggAt() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReadOggAt(f *testing.F) {
+	for _, name := range []string{"vorbis.ogg", "tags.opus"} {
+		f.Add(readTestdata(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadOggAt(bytes.NewReader(data), int64(len(data)))
+		if err == nil && (info.Properties.Duration < 0 || info.Properties.Bitrate < 0) {
+			t.Errorf("negative duration %v or bitrate %d", info.Properties.Duration, info.Properties.Bitrate)
+		}
+	})
+}
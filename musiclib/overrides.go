@@ -0,0 +1,204 @@
+package musiclib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OverridesColumns is the schema of the overrides table. Each row is a
+// librarian's correction of one field of one file, known by the hash of
+// the file's contents and by its path below the library root.
+const OverridesColumns = "content_hash TEXT, path TEXT, field TEXT, value TEXT, updated TEXT"
+
+// How an override was matched to a file
+const (
+	OverrideByHash = "content hash"
+	OverrideByPath = "path"
+)
+
+// FileOverride is the corrections of one file, as exported to YAML
+type FileOverride struct {
+	Path   string            `yaml:"path"`
+	Hash   string            `yaml:"hash,omitempty"`
+	Fields map[string]string `yaml:"fields"`
+}
+
+// OverrideValue is a corrected value and how its override was matched
+type OverrideValue struct {
+	Value     string
+	MatchedBy string
+}
+
+// Overrides holds the corrections in the overrides table
+type Overrides struct {
+	byHash map[string]map[string]string // content hash -> field -> value
+	byPath map[string]map[string]string // relative path -> field -> value
+	files  []FileOverride
+}
+
+// NewOverrides returns an empty set of overrides
+func NewOverrides() *Overrides {
+	return &Overrides{
+		byHash: make(map[string]map[string]string),
+		byPath: make(map[string]map[string]string),
+	}
+}
+
+// ContentHash returns the SHA-256 of a file's contents, which stays the
+// same when the file is renamed or moved. An empty file has no hash, since
+// every empty file would share it.
+func ContentHash(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("error hashing '%s': %v", filePath, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("error hashing '%s': %v", filePath, err)
+	}
+	if n == 0 {
+		return "", nil
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CreateOverridesTable creates the overrides table if it does not exist
+func CreateOverridesTable(db LibraryStore) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS overrides (" + OverridesColumns + ")")
+	if err != nil {
+		return fmt.Errorf("error creating overrides table: %v", err)
+	}
+	return nil
+}
+
+// SaveOverride stores the corrections of one file, replacing earlier
+// corrections of the same fields of the same file
+func SaveOverride(db LibraryStore, override FileOverride) error {
+	updated := time.Now().Format("2006-01-02")
+	for field, value := range override.Fields {
+		_, err := db.Exec("DELETE FROM overrides WHERE field = ? AND ((content_hash = ? AND content_hash <> '') OR (path = ? AND path <> ''))",
+			field, override.Hash, override.Path)
+		if err != nil {
+			return fmt.Errorf("error replacing override of '%s': %v", override.Path, err)
+		}
+		_, err = db.Exec("INSERT INTO overrides VALUES (?, ?, ?, ?, ?)", override.Hash, override.Path, field, value, updated)
+		if err != nil {
+			return fmt.Errorf("error adding override of '%s': %v", override.Path, err)
+		}
+	}
+	return nil
+}
+
+// LoadOverrides reads every correction from the overrides table
+func LoadOverrides(db LibraryStore) (*Overrides, error) {
+	rows, err := db.Query("SELECT content_hash, path, field, value FROM overrides ORDER BY path, content_hash, field")
+	if err != nil {
+		return nil, fmt.Errorf("error reading overrides: %v", err)
+	}
+	defer rows.Close()
+
+	overrides := NewOverrides()
+	files := make(map[[2]string]int)
+	for rows.Next() {
+		var hash, path, field, value string
+		if err := rows.Scan(&hash, &path, &field, &value); err != nil {
+			return nil, err
+		}
+		if hash != "" {
+			setOverride(overrides.byHash, hash, field, value)
+		}
+		if path != "" {
+			setOverride(overrides.byPath, path, field, value)
+		}
+
+		key := [2]string{hash, path}
+		i, ok := files[key]
+		if !ok {
+			i = len(overrides.files)
+			files[key] = i
+			overrides.files = append(overrides.files, FileOverride{Path: path, Hash: hash, Fields: make(map[string]string)})
+		}
+		overrides.files[i].Fields[field] = value
+	}
+
+	return overrides, rows.Err()
+}
+
+func setOverride(index map[string]map[string]string, key, field, value string) {
+	if index[key] == nil {
+		index[key] = make(map[string]string)
+	}
+	index[key][field] = value
+}
+
+// Lookup returns the corrected fields of a file. A correction made for the
+// file's contents wins over one made for its path, so a fix follows a
+// renamed file, and a file whose contents changed keeps the fixes made
+// for its path.
+func (o *Overrides) Lookup(hash, path string) map[string]OverrideValue {
+	values := make(map[string]OverrideValue)
+	for field, value := range o.byPath[path] {
+		values[field] = OverrideValue{Value: value, MatchedBy: OverrideByPath}
+	}
+	if hash != "" {
+		for field, value := range o.byHash[hash] {
+			values[field] = OverrideValue{Value: value, MatchedBy: OverrideByHash}
+		}
+	}
+	return values
+}
+
+// Len returns the number of files with corrections
+func (o *Overrides) Len() int {
+	return len(o.files)
+}
+
+// Files returns the corrections grouped by file, in path order
+func (o *Overrides) Files() []FileOverride {
+	return append([]FileOverride(nil), o.files...)
+}
+
+// WriteOverridesFile writes corrections to a YAML file for review
+func WriteOverridesFile(filename string, overrides []FileOverride) error {
+	sorted := append([]FileOverride(nil), overrides...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	data, err := yaml.Marshal(sorted)
+	if err != nil {
+		return fmt.Errorf("error writing overrides file '%s': %v", filename, err)
+	}
+	header := "# Manual overrides of scanned fields, keyed by content hash and path\n"
+	if err := os.WriteFile(filename, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("error writing overrides file '%s': %v", filename, err)
+	}
+	return nil
+}
+
+// ReadOverridesFile reads corrections from a YAML file
+func ReadOverridesFile(filename string) ([]FileOverride, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading overrides file '%s': %v", filename, err)
+	}
+
+	var overrides []FileOverride
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("error parsing overrides file '%s': %v", filename, err)
+	}
+	for _, override := range overrides {
+		if override.Path == "" && override.Hash == "" {
+			return nil, fmt.Errorf("error in overrides file '%s': an entry has neither a path nor a hash", filename)
+		}
+	}
+	return overrides, nil
+}
@@ -0,0 +1,262 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openTestStores opens an empty store of each backend
+func openTestStores(t *testing.T) map[string]LibraryStore {
+	t.Helper()
+	stores := make(map[string]LibraryStore)
+	for _, backend := range []string{BackendMemory, BackendSQLite, BackendDuckDB} {
+		filename := ""
+		switch backend {
+		case BackendSQLite:
+			filename = filepath.Join(t.TempDir(), "library.db")
+		case BackendDuckDB:
+			filename = filepath.Join(t.TempDir(), "library.duckdb")
+		}
+		store, err := OpenStore(backend, filename)
+		if err != nil {
+			t.Fatalf("OpenStore(%q): %v", backend, err)
+		}
+		t.Cleanup(func() { store.Close() })
+		stores[backend] = store
+	}
+	return stores
+}
+
+func TestSaveOverride(t *testing.T) {
+	tests := []struct {
+		name  string
+		saves []FileOverride
+		want  []FileOverride
+	}{
+		{
+			name: "hash-only overrides of the same field are kept apart",
+			saves: []FileOverride{
+				{Hash: "aaa", Fields: map[string]string{"song title": "Ave Maria"}},
+				{Hash: "bbb", Fields: map[string]string{"song title": "Locus Iste"}},
+			},
+			want: []FileOverride{
+				{Hash: "aaa", Fields: map[string]string{"song title": "Ave Maria"}},
+				{Hash: "bbb", Fields: map[string]string{"song title": "Locus Iste"}},
+			},
+		},
+		{
+			name: "path-only overrides of the same field are kept apart",
+			saves: []FileOverride{
+				{Path: "2019 Spring/AveMaria_Biebl.pdf", Fields: map[string]string{"voicing": "TTBB"}},
+				{Path: "2019 Spring/LocusIste.pdf", Fields: map[string]string{"voicing": "SATB"}},
+			},
+			want: []FileOverride{
+				{Path: "2019 Spring/AveMaria_Biebl.pdf", Fields: map[string]string{"voicing": "TTBB"}},
+				{Path: "2019 Spring/LocusIste.pdf", Fields: map[string]string{"voicing": "SATB"}},
+			},
+		},
+		{
+			name: "a later correction of a field replaces the earlier one",
+			saves: []FileOverride{
+				{Path: "2019 Spring/LocusIste.pdf", Hash: "ccc", Fields: map[string]string{"voicing": "SAB", "key": "C major"}},
+				{Path: "2019 Spring/LocusIste.pdf", Hash: "ccc", Fields: map[string]string{"voicing": "SATB"}},
+			},
+			want: []FileOverride{
+				{Path: "2019 Spring/LocusIste.pdf", Hash: "ccc", Fields: map[string]string{"voicing": "SATB", "key": "C major"}},
+			},
+		},
+		{
+			name: "a hash-only correction replaces the one saved with the file's path",
+			saves: []FileOverride{
+				{Path: "2019 Spring/LocusIste.pdf", Hash: "ccc", Fields: map[string]string{"voicing": "SAB"}},
+				{Hash: "ccc", Fields: map[string]string{"voicing": "SATB"}},
+			},
+			want: []FileOverride{
+				{Hash: "ccc", Fields: map[string]string{"voicing": "SATB"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		for backend, store := range openTestStores(t) {
+			t.Run(tt.name+"/"+backend, func(t *testing.T) {
+				if _, err := store.Exec("DROP TABLE IF EXISTS overrides"); err != nil {
+					t.Fatal(err)
+				}
+				if err := CreateOverridesTable(store); err != nil {
+					t.Fatal(err)
+				}
+				for _, override := range tt.saves {
+					if err := SaveOverride(store, override); err != nil {
+						t.Fatal(err)
+					}
+				}
+				overrides, err := LoadOverrides(store)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := overrides.Files(); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("overrides = %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestOverridesLookup(t *testing.T) {
+	store, err := OpenStore(BackendMemory, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := CreateOverridesTable(store); err != nil {
+		t.Fatal(err)
+	}
+	for _, override := range []FileOverride{
+		{Path: "2016 Christmas/TheMostWonderfulDayOfTheYear_SATB_Wonderful.pdf", Hash: "aaa", Fields: map[string]string{"composer or arranger": "Huff"}},
+		{Path: "2014 Christmas/TaylorTheLatteBoy_SSA_Huff.pdf", Fields: map[string]string{"voicing": "SSA", "song title": "Taylor the Latte Boy"}},
+		{Hash: "bbb", Fields: map[string]string{"song title": "Taylor, the Latte Boy"}},
+	} {
+		if err := SaveOverride(store, override); err != nil {
+			t.Fatal(err)
+		}
+	}
+	overrides, err := LoadOverrides(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overrides.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", overrides.Len())
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		path string
+		want map[string]OverrideValue
+	}{
+		{
+			name: "by path",
+			hash: "ccc",
+			path: "2014 Christmas/TaylorTheLatteBoy_SSA_Huff.pdf",
+			want: map[string]OverrideValue{
+				"voicing":    {Value: "SSA", MatchedBy: OverrideByPath},
+				"song title": {Value: "Taylor the Latte Boy", MatchedBy: OverrideByPath},
+			},
+		},
+		{
+			name: "a renamed file by its contents",
+			hash: "aaa",
+			path: "2016 Christmas/MostWonderfulDay.pdf",
+			want: map[string]OverrideValue{"composer or arranger": {Value: "Huff", MatchedBy: OverrideByHash}},
+		},
+		{
+			name: "the contents win over the path",
+			hash: "bbb",
+			path: "2014 Christmas/TaylorTheLatteBoy_SSA_Huff.pdf",
+			want: map[string]OverrideValue{
+				"voicing":    {Value: "SSA", MatchedBy: OverrideByPath},
+				"song title": {Value: "Taylor, the Latte Boy", MatchedBy: OverrideByHash},
+			},
+		},
+		{name: "an empty file has no hash to match", path: "2011 Christmas/Christmas 2011.pdf", want: map[string]OverrideValue{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overrides.Lookup(tt.hash, tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	pdf := readTestdata(t, "octavo.pdf")
+	original, err := ContentHash(writeTemp(t, "JingleBellls_BC.pdf", pdf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"renamed copy", writeTemp(t, "JingleBells_BC.pdf", pdf), original, true},
+		{"empty file", writeTemp(t, "empty.pdf", nil), "", true},
+		{"missing file", filepath.Join(t.TempDir(), "missing.pdf"), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ContentHash(tt.path)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ContentHash() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ContentHash() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if len(original) != 64 {
+		t.Errorf("ContentHash() = %q, want a SHA-256 in hex", original)
+	}
+}
+
+func TestReadOverridesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []FileOverride
+		wantOK  bool
+	}{
+		{
+			name:    "path and hash",
+			content: "- path: 2014 Christmas/TaylorTheLatteBoy_SSA_Huff.pdf\n  hash: aaa\n  fields:\n    voicing: SSA\n",
+			want:    []FileOverride{{Path: "2014 Christmas/TaylorTheLatteBoy_SSA_Huff.pdf", Hash: "aaa", Fields: map[string]string{"voicing": "SSA"}}},
+			wantOK:  true,
+		},
+		{
+			name:    "hash only",
+			content: "- path: \"\"\n  hash: bbb\n  fields:\n    song title: Taylor, the Latte Boy\n",
+			want:    []FileOverride{{Hash: "bbb", Fields: map[string]string{"song title": "Taylor, the Latte Boy"}}},
+			wantOK:  true,
+		},
+		{name: "neither path nor hash", content: "- fields:\n    voicing: SSA\n"},
+		{name: "not YAML", content: "- path: [unclosed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadOverridesFile(writeTemp(t, "overrides.yml", []byte(tt.content)))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadOverridesFile() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadOverridesFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadOverridesFile(filepath.Join(t.TempDir(), "overrides.yml")); err == nil {
+		t.Error("ReadOverridesFile() of a missing file succeeded")
+	}
+}
+
+func TestWriteOverridesFile(t *testing.T) {
+	overrides := []FileOverride{
+		{Path: "2016 Christmas/TheMostWonderfulDayOfTheYear_SATB_Wonderful.pdf", Hash: "aaa", Fields: map[string]string{"composer or arranger": "Huff"}},
+		{Path: "2014 Christmas/TaylorTheLatteBoy_SSA_Huff.pdf", Fields: map[string]string{"voicing": "SSA"}},
+	}
+	filename := filepath.Join(t.TempDir(), "overrides.yml")
+	if err := WriteOverridesFile(filename, overrides); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadOverridesFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Written in path order for review
+	want := []FileOverride{overrides[1], overrides[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadOverridesFile() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,202 @@
+package musiclib
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// PageCredits is what the first page of a score says about the piece.
+// Lines holds, for each of "title", "voicing", "composer", "arranger" and
+// "accompaniment" found, the line of text it was read from.
+type PageCredits struct {
+	Title         string
+	Voicing       VoicingResult
+	Composer      string
+	Arranger      string
+	Accompaniment string
+	Lines         map[string]string
+}
+
+// accompanimentInstruments are the instruments named in the accompaniment
+// of a choral octavo, as in "SATB and Piano" or "with Organ"
+const accompanimentInstruments = `piano|organ|keyboard|guitar|harp|orchestra|strings|string quartet|brass|brass quintet|percussion|handbells|flute|violin|cello|band|rhythm section`
+
+var (
+	composerCredit     = regexp.MustCompile(`(?i)(?:\b(?:music|composed|melody)\s+by|^by)\s*:?\s*(.+)`)
+	arrangerCredit     = regexp.MustCompile(`(?i)(?:\b(?:arranged|arrangement|adapted)\s+by|\barr\.?\s+(?:by\b)?)\s*:?\s*(.+)`)
+	creditEnd          = regexp.MustCompile(`(?i)\s+(?:arr\b|arr\.|arranged|adapted|edited|words|lyrics|text|with)\b|[;/(\[]|,\s|\s[-–—]\s`)
+	copyrightLine      = regexp.MustCompile(`(?i)©|\(c\)|copyright|all rights reserved|printed in|reproduction|photocopy`)
+	unaccompaniedWord  = regexp.MustCompile(`(?i)\b(a\s*cappella|unaccompanied)\b`)
+	accompaniedBy      = regexp.MustCompile(`(?i)(?:\b(?:with|and|for)\s+|&\s*)(?:optional\s+)?((?:` + accompanimentInstruments + `)(?:\s*(?:,|and|&)\s*(?:optional\s+)?(?:` + accompanimentInstruments + `))*)\b`)
+	accompanimentSplit = regexp.MustCompile(`(?i)\s*(?:,|\band\b|&)\s*(?:optional\s+)?`)
+)
+
+// titleSizeRatio is how much larger than the body text a line must be set
+// to be taken for the title
+const titleSizeRatio = 1.25
+
+// maxCreditWords limits the lines searched for a voicing, accompaniment or
+// bare composer name, which are set on short lines of their own
+const maxCreditWords = 10
+
+// ReadPageCredits finds the title, voicing, composer, arranger and
+// accompaniment among the lines of a first page. The title is the line set
+// largest, well above the body text. Credited names are matched against the
+// lexicon where it knows them, and a short line holding only a known name is
+// taken for the composer when no line credits one.
+func ReadPageCredits(lines []TextLine, lexicon *ComposerLexicon) PageCredits {
+	credits := PageCredits{Voicing: VoicingResult{Voicing: VoicingUnknown}, Lines: make(map[string]string)}
+	normalize := func(name string) string {
+		if lexicon != nil {
+			if match, ok := lexicon.Match(name); ok && match.Confidence >= titleMatchConfidence {
+				return match.Name
+			}
+		}
+		return name
+	}
+
+	credit := make([]bool, len(lines))
+	for i, line := range lines {
+		if copyrightLine.MatchString(line.Text) {
+			credit[i] = true
+			continue
+		}
+		if groups := arrangerCredit.FindStringSubmatch(line.Text); groups != nil && credits.Arranger == "" {
+			if name := creditName(groups[1]); name != "" {
+				credits.Arranger = normalize(name)
+				credits.Lines["arranger"] = line.Text
+				credit[i] = true
+			}
+		}
+		if groups := composerCredit.FindStringSubmatch(line.Text); groups != nil && credits.Composer == "" {
+			if name := creditName(groups[1]); name != "" {
+				credits.Composer = normalize(name)
+				credits.Lines["composer"] = line.Text
+				credit[i] = true
+			}
+		}
+	}
+
+	for i, line := range lines {
+		if credit[i] || len(strings.Fields(line.Text)) > maxCreditWords {
+			continue
+		}
+		if voicing := ParseVoicing(line.Text); voicing.Confidence > credits.Voicing.Confidence {
+			credits.Voicing = voicing
+			credits.Lines["voicing"] = line.Text
+			credit[i] = true
+		}
+		if credits.Accompaniment == "" {
+			if accompaniment := parseAccompaniment(line.Text); accompaniment != "" {
+				credits.Accompaniment = accompaniment
+				credits.Lines["accompaniment"] = line.Text
+				credit[i] = true
+			}
+		}
+	}
+
+	if title, used := pageTitle(lines, credit); title != "" {
+		credits.Title = title
+		credits.Lines["title"] = title
+		for _, i := range used {
+			credit[i] = true
+		}
+	}
+
+	if credits.Composer == "" && lexicon != nil {
+		for i, line := range lines {
+			if credit[i] || len(strings.Fields(line.Text)) > maxCreditWords/2 {
+				continue
+			}
+			if match, ok := lexicon.FindInFilename(line.Text); ok {
+				credits.Composer = match.Name
+				credits.Lines["composer"] = line.Text
+				break
+			}
+		}
+	}
+
+	return credits
+}
+
+// creditName cuts the name out of the rest of a credit line, as in "John
+// Rutter (b. 1945)" or "Franz Biebl; words by ...". A name set in capitals
+// is given in title case.
+func creditName(text string) string {
+	if loc := creditEnd.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+	name := strings.Trim(text, " .,:;-–—")
+	if name == "" || len(strings.Fields(name)) > 6 || !strings.ContainsFunc(name, unicode.IsLetter) {
+		return ""
+	}
+	if isAllCaps(name) {
+		name = titleCaseWords(name)
+	}
+	return name
+}
+
+// parseAccompaniment finds the accompaniment named in a line, as in
+// "SATB and Piano, 4-hands" or "SSA a cappella", and writes it as
+// "Piano", "Piano, Flute" or "A cappella"
+func parseAccompaniment(text string) string {
+	if unaccompaniedWord.MatchString(text) {
+		return "A cappella"
+	}
+	groups := accompaniedBy.FindStringSubmatch(text)
+	if groups == nil {
+		return ""
+	}
+	var instruments []string
+	for _, instrument := range accompanimentSplit.Split(groups[1], -1) {
+		if instrument = titleCaseWords(instrument); instrument != "" && !slices.Contains(instruments, instrument) {
+			instruments = append(instruments, instrument)
+		}
+	}
+	return strings.Join(instruments, ", ")
+}
+
+// pageTitle returns the title of a page: the lines set in its largest type
+// that are not credits, joined when the title runs over several lines. It
+// returns nothing when no line stands out from the body text.
+func pageTitle(lines []TextLine, credit []bool) (string, []int) {
+	var sizes []float64
+	largest := -1
+	for i, line := range lines {
+		sizes = append(sizes, line.Size)
+		if credit[i] || !strings.ContainsFunc(line.Text, unicode.IsLetter) {
+			continue
+		}
+		if largest < 0 || line.Size > lines[largest].Size {
+			largest = i
+		}
+	}
+	if largest < 0 {
+		return "", nil
+	}
+	slices.Sort(sizes)
+	if lines[largest].Size < titleSizeRatio*sizes[len(sizes)/2] {
+		return "", nil
+	}
+
+	sameSize := func(i int) bool {
+		return !credit[i] && lines[i].Size >= 0.95*lines[largest].Size
+	}
+	first, last := largest, largest
+	for first > 0 && sameSize(first-1) {
+		first--
+	}
+	for last+1 < len(lines) && sameSize(last+1) {
+		last++
+	}
+
+	var words []string
+	var used []int
+	for i := first; i <= last; i++ {
+		words = append(words, lines[i].Text)
+		used = append(used, i)
+	}
+	return strings.Join(words, " "), used
+}
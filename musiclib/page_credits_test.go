@@ -0,0 +1,63 @@
+package musiclib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadPageCredits(t *testing.T) {
+	lexicon := NewComposerLexicon([]string{"Franz Biebl", "Mac Huff", "John Rutter", "Moses Hogan"})
+	tests := []struct {
+		name  string
+		lines []TextLine
+		want  PageCredits
+	}{
+		{
+			name: "octavo",
+			lines: []TextLine{
+				{Text: "SHENANDOAH", Size: 24, Top: 76},
+				{Text: "SATB and Piano", Size: 11, Top: 116},
+				{Text: "Music by FRANZ BIEBL Arranged by Mac Huff", Size: 11, Top: 130},
+				{Text: "© 2019 Hal Leonard. All Rights Reserved.", Size: 8, Top: 696},
+			},
+			want: PageCredits{Title: "SHENANDOAH", Voicing: VoicingResult{Voicing: VoicingSATB, Confidence: 1, Matched: "SATB"},
+				Composer: "Franz Biebl", Arranger: "Mac Huff", Accompaniment: "Piano"},
+		},
+		{
+			name: "title over two lines and a bare composer name",
+			lines: []TextLine{
+				{Text: "The Lord Bless You", Size: 20, Top: 80},
+				{Text: "and Keep You", Size: 20, Top: 102},
+				{Text: "SSA, a cappella", Size: 10, Top: 140},
+				{Text: "John Rutter", Size: 10, Top: 160},
+				{Text: "Copyright 1981 Oxford University Press", Size: 7, Top: 700},
+			},
+			want: PageCredits{Title: "The Lord Bless You and Keep You", Voicing: VoicingResult{Voicing: "SSA", Confidence: 1, Matched: "SSA"},
+				Composer: "John Rutter", Accompaniment: "A cappella"},
+		},
+		{
+			name: "arranger only",
+			lines: []TextLine{
+				{Text: "Elijah Rock", Size: 18, Top: 70},
+				{Text: "for SATB chorus with optional piano", Size: 10, Top: 100},
+				{Text: "Arr. by Moses Hogan", Size: 10, Top: 120},
+			},
+			want: PageCredits{Title: "Elijah Rock", Voicing: VoicingResult{Voicing: VoicingSATB, Confidence: 1, Matched: "SATB"},
+				Arranger: "Moses Hogan", Accompaniment: "Piano"},
+		},
+		{
+			name:  "no text",
+			lines: nil,
+			want:  PageCredits{Voicing: VoicingResult{Voicing: VoicingUnknown}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReadPageCredits(tt.lines, lexicon)
+			got.Lines = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadPageCredits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package musiclib
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// PathRule derives library fields from the name of one folder. Pattern is
+// matched against each folder between the library root and the file. The
+// field values are templates that may use the pattern's named groups, as
+// in "${season}". AcquiredDate is a date such as "${year}-${month}-01";
+// without it the acquired date is the start of the season of the concert.
+type PathRule struct {
+	Name         string `yaml:"name"`
+	Pattern      string `yaml:"pattern"`
+	LibraryType  string `yaml:"library_type,omitempty"`
+	Season       string `yaml:"season,omitempty"`
+	ConcertYear  string `yaml:"concert_year,omitempty"`
+	AcquiredDate string `yaml:"acquired_date,omitempty"`
+}
+
+// DefaultPathRules returns the folder rules for the library layout used
+// before rules were configured: concert folders such as "2011 Christmas",
+// "2019 Spring Pops" or "Christmas 2023", festival folders, repertoire and
+// the library scans
+func DefaultPathRules() []PathRule {
+	return []PathRule{
+		{
+			Name:        "year_season",
+			Pattern:     `(?i)^(?P<year>(?:19|20)\d{2})[ _-]+(?P<season>christmas|spring|summer|fall|autumn|winter|holiday)(?:[ _-]+(?P<event>.+))?$`,
+			LibraryType: "${season}",
+			Season:      "${season}",
+			ConcertYear: "${year}",
+		},
+		{
+			Name:        "season_year",
+			Pattern:     `(?i)^(?P<season>christmas|spring|summer|fall|autumn|winter|holiday)[ _-]+(?P<year>(?:19|20)\d{2})$`,
+			LibraryType: "${season}",
+			Season:      "${season}",
+			ConcertYear: "${year}",
+		},
+		{
+			Name:        "festival",
+			Pattern:     `(?i)^(?:(?P<year>(?:19|20)\d{2})[ _-]+)?(?P<season>summer|spring|fall|winter)?[ _-]*festival$`,
+			LibraryType: "Festival",
+			Season:      "${season}",
+			ConcertYear: "${year}",
+		},
+		{
+			Name:        "repertoire",
+			Pattern:     `(?i)^(?:(?P<year>(?:19|20)\d{2})[ _-]+)?repertoire$`,
+			LibraryType: "Repertoire",
+			ConcertYear: "${year}",
+		},
+		{
+			Name:        "library_scans",
+			Pattern:     `(?i)library[ _-]*scans`,
+			LibraryType: "Library Scans",
+		},
+		{
+			Name:        "administrative",
+			Pattern:     `(?i)^(?:contracts|administrative[ _-]*docs)$`,
+			LibraryType: "Administrative",
+		},
+	}
+}
+
+// PathMatch holds the fields the path rules derived for one folder.
+// Origins tells, for each field given a value, the rule and folder it came
+// from.
+type PathMatch struct {
+	LibraryType  string
+	Season       string
+	ConcertYear  string
+	AcquiredDate string
+	Rules        []string
+	Origins      map[string]string
+}
+
+// Matched reports whether any rule matched a folder of the path
+func (m PathMatch) Matched() bool {
+	return len(m.Rules) > 0
+}
+
+// PathRules derive library type, season and concert year from folder names
+type PathRules struct {
+	rules []compiledPathRule
+}
+
+type compiledPathRule struct {
+	PathRule
+	re *regexp.Regexp
+}
+
+// NewPathRules compiles the folder rules
+func NewPathRules(rules []PathRule) (*PathRules, error) {
+	pathRules := &PathRules{}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling path rule '%s': %v", rule.Name, err)
+		}
+		pathRules.rules = append(pathRules.rules, compiledPathRule{PathRule: rule, re: re})
+	}
+	return pathRules, nil
+}
+
+// Match applies the rules to each folder of dir below baseDir, outermost
+// first. Each field keeps the first value a rule gives it, so files in
+// "2017 Spring/PDFs/Repertoire" belong to the 2017 Spring concert.
+func (r *PathRules) Match(baseDir, dir string) PathMatch {
+	match := PathMatch{Origins: make(map[string]string)}
+	set := func(field *string, name, value, origin string) {
+		if *field == "" && value != "" {
+			*field = value
+			match.Origins[name] = origin
+		}
+	}
+
+	for _, folder := range FolderComponents(baseDir, dir) {
+		for _, rule := range r.rules {
+			groups := rule.re.FindStringSubmatchIndex(folder)
+			if groups == nil {
+				continue
+			}
+			match.Rules = append(match.Rules, rule.Name)
+			expand := func(template string) string {
+				return string(rule.re.ExpandString(nil, template, folder, groups))
+			}
+			origin := fmt.Sprintf("rule %s on folder %q", rule.Name, folder)
+			set(&match.LibraryType, "library type", titleCaseWords(expand(rule.LibraryType)), origin)
+			set(&match.Season, "season", titleCaseWords(expand(rule.Season)), origin)
+			set(&match.ConcertYear, "concert year", titleCaseWords(expand(rule.ConcertYear)), origin)
+			set(&match.AcquiredDate, "acquired date", strings.TrimSpace(expand(rule.AcquiredDate)), origin)
+			break
+		}
+	}
+	if match.AcquiredDate == "" {
+		// Not from a rule, so it has no origin
+		match.AcquiredDate = seasonStart(match.ConcertYear, match.Season)
+	}
+
+	return match
+}
+
+// seasonMonths are the months music for each concert season is handed out
+var seasonMonths = map[string]string{
+	"winter":    "01",
+	"spring":    "03",
+	"summer":    "06",
+	"fall":      "09",
+	"autumn":    "09",
+	"christmas": "10",
+	"holiday":   "10",
+}
+
+// seasonStart returns the first day of a concert season as the date its
+// music was acquired, or the first day of the year for a folder without a
+// season, so "2011 Christmas" gives "2011-10-01"
+func seasonStart(year, season string) string {
+	if !concertYear.MatchString(year) {
+		return ""
+	}
+	month, ok := seasonMonths[strings.ToLower(season)]
+	if !ok {
+		month = "01"
+	}
+	return year + "-" + month + "-01"
+}
+
+var concertYear = regexp.MustCompile(`^\d{4}$`)
+
+// FolderComponents returns the folder names of dir below baseDir. A dir
+// outside baseDir is split whole.
+func FolderComponents(baseDir, dir string) []string {
+	if rel, err := filepath.Rel(baseDir, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		dir = rel
+	}
+
+	var folders []string
+	for _, folder := range strings.Split(filepath.ToSlash(dir), "/") {
+		if folder != "" && folder != "." {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+// titleCaseWords capitalizes each word of a folder value, so "christmas"
+// and "CHRISTMAS" both give "Christmas"
+func titleCaseWords(s string) string {
+	words := strings.Fields(strings.NewReplacer("_", " ", "-", " ").Replace(s))
+	for i, word := range words {
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,119 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPathRulesMatch(t *testing.T) {
+	rules, err := NewPathRules(DefaultPathRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join("library", "49ersMusicLibrary")
+	tests := []struct {
+		dir          string
+		libraryType  string
+		season       string
+		concertYear  string
+		acquiredDate string
+		rules        []string
+	}{
+		{"2011 Christmas", "Christmas", "Christmas", "2011", "2011-10-01", []string{"year_season"}},
+		{"2019 Spring Pops", "Spring", "Spring", "2019", "2019-03-01", []string{"year_season"}},
+		{"Christmas 2023", "Christmas", "Christmas", "2023", "2023-10-01", []string{"season_year"}},
+		{"Summer Festival", "Festival", "Summer", "", "", []string{"festival"}},
+		{"2017 Repertoire/PDFs", "Repertoire", "", "2017", "2017-01-01", []string{"repertoire"}},
+		// The concert folder outside the repertoire folder decides
+		{"2017 Spring/PDFs/Repertoire", "Spring", "Spring", "2017", "2017-03-01", []string{"year_season", "repertoire"}},
+		{"Library Scans/Box 3", "Library Scans", "", "", "", []string{"library_scans"}},
+		{"Contracts", "Administrative", "", "", "", []string{"administrative"}},
+		{"Misc", "", "", "", "", nil},
+		{"", "", "", "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got := rules.Match(base, filepath.Join(base, filepath.FromSlash(tt.dir)))
+			if got.LibraryType != tt.libraryType || got.Season != tt.season || got.ConcertYear != tt.concertYear || got.AcquiredDate != tt.acquiredDate {
+				t.Errorf("Match() = %q, %q, %q, %q, want %q, %q, %q, %q", got.LibraryType, got.Season, got.ConcertYear, got.AcquiredDate,
+					tt.libraryType, tt.season, tt.concertYear, tt.acquiredDate)
+			}
+			if !reflect.DeepEqual(got.Rules, tt.rules) || got.Matched() != (tt.rules != nil) {
+				t.Errorf("Match() rules = %q, matched %v, want %q", got.Rules, got.Matched(), tt.rules)
+			}
+		})
+	}
+}
+
+func TestPathRulesOrigins(t *testing.T) {
+	rules, err := NewPathRules([]PathRule{{
+		Name:         "concert",
+		Pattern:      `^(?P<year>\d{4})-(?P<month>\d{2}) (?P<season>\w+)$`,
+		LibraryType:  "Concert",
+		Season:       "${season}",
+		ConcertYear:  "${year}",
+		AcquiredDate: "${year}-${month}-01",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rules.Match("", filepath.Join("2019-04 spring_pops", "Scores"))
+	want := PathMatch{
+		LibraryType:  "Concert",
+		Season:       "Spring Pops",
+		ConcertYear:  "2019",
+		AcquiredDate: "2019-04-01",
+		Rules:        []string{"concert"},
+		Origins: map[string]string{
+			"library type":  `rule concert on folder "2019-04 spring_pops"`,
+			"season":        `rule concert on folder "2019-04 spring_pops"`,
+			"concert year":  `rule concert on folder "2019-04 spring_pops"`,
+			"acquired date": `rule concert on folder "2019-04 spring_pops"`,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %+v, want %+v", got, want)
+	}
+
+	if _, err := NewPathRules([]PathRule{{Name: "broken", Pattern: `(\d{4}`}}); err == nil {
+		t.Error("NewPathRules() of a bad pattern succeeded")
+	}
+}
+
+func TestSeasonStart(t *testing.T) {
+	tests := []struct {
+		year   string
+		season string
+		want   string
+	}{
+		{"2011", "Christmas", "2011-10-01"},
+		{"2019", "Spring", "2019-03-01"},
+		{"2018", "Autumn", "2018-09-01"},
+		{"2017", "", "2017-01-01"},
+		{"", "Summer", ""},
+		{"Pops", "Spring", ""},
+	}
+	for _, tt := range tests {
+		if got := seasonStart(tt.year, tt.season); got != tt.want {
+			t.Errorf("seasonStart(%q, %q) = %q, want %q", tt.year, tt.season, got, tt.want)
+		}
+	}
+}
+
+func TestFolderComponents(t *testing.T) {
+	base := filepath.Join("library", "49ersMusicLibrary")
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{filepath.Join(base, "2017 Spring", "PDFs"), []string{"2017 Spring", "PDFs"}},
+		{base, nil},
+		{filepath.Join("other", "2011 Christmas"), []string{"other", "2011 Christmas"}},
+	}
+	for _, tt := range tests {
+		if got := FolderComponents(base, tt.dir); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FolderComponents(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,944 @@
+package musiclib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// The PDF object model, as much of it as is needed to read metadata, the
+// page tree and content streams. Integers are int and reals float64.
+type (
+	pdfName    string
+	pdfString  string
+	pdfKeyword string
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ Num, Gen int }
+	pdfStream  struct {
+		Dict pdfDict
+		Raw  []byte
+	}
+)
+
+// maxPDFDepth bounds nesting and reference chains, so a malformed or
+// hostile file cannot recurse forever
+const maxPDFDepth = 64
+
+// maxPDFStreamSize bounds the decoded size of a stream, so a small
+// compressed stream cannot inflate to fill memory. Content and metadata
+// streams of real scores are far smaller.
+const maxPDFStreamSize = 64 << 20
+
+var errPDFSyntax = errors.New("PDF syntax error")
+
+// pdfParser reads PDF objects from a byte slice. doc resolves indirect
+// stream lengths and may be nil.
+type pdfParser struct {
+	data  []byte
+	pos   int
+	doc   *pdfDocument
+	depth int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if isPDFSpace(c) {
+			p.pos++
+		} else if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// readRegular reads a run of regular characters: a number or keyword
+func (p *pdfParser) readRegular() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isPDFSpace(p.data[p.pos]) && !isPDFDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// readObject reads the next object. Keywords other than true, false and
+// null are returned as pdfKeyword, so callers can find "obj", "R" or the
+// operators of a content stream.
+func (p *pdfParser) readObject() (any, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxPDFDepth {
+		return nil, fmt.Errorf("%w: objects nested too deeply", errPDFSyntax)
+	}
+
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		p.pos++
+		return p.readName(), nil
+	case c == '(':
+		p.pos++
+		return p.readLiteralString()
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		p.pos += 2
+		dict, err := p.readDict()
+		if err != nil {
+			return nil, err
+		}
+		return p.maybeStream(dict)
+	case c == '<':
+		p.pos++
+		return p.readHexString()
+	case c == '[':
+		p.pos++
+		return p.readArray()
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		p.pos++
+		return pdfKeyword(c), nil
+	}
+
+	token := p.readRegular()
+	switch token {
+	case "":
+		p.pos++
+		return nil, fmt.Errorf("%w: unexpected %q at %d", errPDFSyntax, p.data[p.pos-1], p.pos-1)
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	if n, err := strconv.Atoi(token); err == nil {
+		return p.maybeReference(n), nil
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f, nil
+	}
+	return pdfKeyword(token), nil
+}
+
+// maybeReference turns "n g R" into a reference, leaving a lone integer
+func (p *pdfParser) maybeReference(n int) any {
+	save := p.pos
+	p.skipSpace()
+	gen, err := strconv.Atoi(p.readRegular())
+	if err == nil && gen >= 0 {
+		p.skipSpace()
+		if p.readRegular() == "R" {
+			return pdfRef{Num: n, Gen: gen}
+		}
+	}
+	p.pos = save
+	return n
+}
+
+func (p *pdfParser) readName() pdfName {
+	start := p.pos
+	for p.pos < len(p.data) && !isPDFSpace(p.data[p.pos]) && !isPDFDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	raw := p.data[start:p.pos]
+	if !bytes.Contains(raw, []byte("#")) {
+		return pdfName(raw)
+	}
+
+	var name []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if b, err := hex.DecodeString(string(raw[i+1 : i+3])); err == nil {
+				name = append(name, b[0])
+				i += 2
+				continue
+			}
+		}
+		name = append(name, raw[i])
+	}
+	return pdfName(name)
+}
+
+func (p *pdfParser) readLiteralString() (pdfString, error) {
+	var s []byte
+	depth := 1
+
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(s), nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				return "", io.ErrUnexpectedEOF
+			}
+			c = p.data[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					value := int(c - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						value = value*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(value)
+				}
+			}
+		}
+		s = append(s, c)
+	}
+
+	return "", io.ErrUnexpectedEOF
+}
+
+func (p *pdfParser) readHexString() (pdfString, error) {
+	var digits []byte
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			s, err := hex.DecodeString(string(digits))
+			if err != nil {
+				return "", fmt.Errorf("%w: bad hex string", errPDFSyntax)
+			}
+			return pdfString(s), nil
+		}
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	return "", io.ErrUnexpectedEOF
+}
+
+func (p *pdfParser) readArray() (pdfArray, error) {
+	var array pdfArray
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return array, nil
+		}
+		obj, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, obj)
+	}
+}
+
+func (p *pdfParser) readDict() (pdfDict, error) {
+	dict := make(pdfDict)
+	for {
+		p.skipSpace()
+		if p.pos+1 < len(p.data) && p.data[p.pos] == '>' && p.data[p.pos+1] == '>' {
+			p.pos += 2
+			return dict, nil
+		}
+		key, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, fmt.Errorf("%w: dictionary key is not a name", errPDFSyntax)
+		}
+		value, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		dict[name] = value
+	}
+}
+
+// maybeStream reads the data of a stream following a dictionary
+func (p *pdfParser) maybeStream(dict pdfDict) (any, error) {
+	save := p.pos
+	p.skipSpace()
+	if !bytes.HasPrefix(p.data[p.pos:], []byte("stream")) {
+		p.pos = save
+		return dict, nil
+	}
+	p.pos += len("stream")
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+
+	// Trust /Length when "endstream" follows it, and search for
+	// "endstream" when the length is wrong or missing
+	if length, ok := p.doc.resolveInt(dict["Length"]); ok && length >= 0 && start+length <= len(p.data) {
+		rest := p.data[start+length:]
+		trimmed := bytes.TrimLeft(rest, "\r\n \t")
+		if bytes.HasPrefix(trimmed, []byte("endstream")) {
+			p.pos = start + length + (len(rest) - len(trimmed)) + len("endstream")
+			return &pdfStream{Dict: dict, Raw: p.data[start : start+length]}, nil
+		}
+	}
+
+	end := bytes.Index(p.data[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, fmt.Errorf("%w: stream without endstream", errPDFSyntax)
+	}
+	raw := bytes.TrimRight(p.data[start:start+end], "\r\n")
+	p.pos = start + end + len("endstream")
+	return &pdfStream{Dict: dict, Raw: raw}, nil
+}
+
+// pdfDocument is an opened PDF file with its cross-reference table
+type pdfDocument struct {
+	data       []byte
+	offsets    map[int]int    // object number -> byte offset
+	compressed map[int][2]int // object number -> object stream number, index
+	trailer    pdfDict
+	cache      map[int]any
+	loading    map[int]bool
+	repaired   bool
+}
+
+var (
+	startXRefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+	objectPattern    = regexp.MustCompile(`(?m)(?:^|[^0-9])(\d{1,10})\s+(\d{1,5})\s+obj\b`)
+	headerPattern    = regexp.MustCompile(`%PDF-(\d\.\d)`)
+)
+
+// openPDF reads the cross-reference table of a PDF file. A file whose
+// table is damaged is repaired by scanning it for objects, as PDF viewers
+// do, and marked as repaired.
+func openPDF(data []byte) (*pdfDocument, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty file")
+	}
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+
+	doc := &pdfDocument{
+		data:       data,
+		offsets:    make(map[int]int),
+		compressed: make(map[int][2]int),
+		cache:      make(map[int]any),
+		loading:    make(map[int]bool),
+	}
+
+	err := doc.readXRef()
+	if err == nil {
+		if _, ok := doc.trailer["Root"]; !ok {
+			err = errors.New("trailer has no /Root")
+		}
+	}
+	if err != nil {
+		if repairErr := doc.repair(); repairErr != nil {
+			return nil, fmt.Errorf("damaged cross-reference table (%v) could not be repaired: %v", err, repairErr)
+		}
+	}
+	return doc, nil
+}
+
+// version returns the PDF version of the header, such as "1.4"
+func (d *pdfDocument) version() string {
+	if groups := headerPattern.FindSubmatch(d.data[:min(len(d.data), 1024)]); groups != nil {
+		return string(groups[1])
+	}
+	return ""
+}
+
+// readXRef follows the chain of cross-reference sections from the last
+// startxref, newest first
+func (d *pdfDocument) readXRef() error {
+	matches := startXRefPattern.FindAllSubmatch(d.data[max(0, len(d.data)-4096):], -1)
+	if len(matches) == 0 {
+		return errors.New("no startxref")
+	}
+	offset, _ := strconv.Atoi(string(matches[len(matches)-1][1]))
+
+	visited := make(map[int]bool)
+	for offset > 0 && !visited[offset] {
+		visited[offset] = true
+		if offset >= len(d.data) {
+			return fmt.Errorf("cross-reference offset %d is past the end of the file", offset)
+		}
+
+		var trailer pdfDict
+		var err error
+		if bytes.HasPrefix(d.data[offset:], []byte("xref")) {
+			trailer, err = d.readXRefTable(offset)
+			if err == nil {
+				if stm, ok := trailer["XRefStm"].(int); ok && !visited[stm] {
+					visited[stm] = true
+					_, err = d.readXRefStream(stm)
+				}
+			}
+		} else {
+			trailer, err = d.readXRefStream(offset)
+		}
+		if err != nil {
+			return err
+		}
+
+		if d.trailer == nil {
+			d.trailer = trailer
+		} else {
+			for key, value := range trailer {
+				if _, ok := d.trailer[key]; !ok && key != "Prev" && key != "XRefStm" {
+					d.trailer[key] = value
+				}
+			}
+		}
+
+		prev, _ := trailer["Prev"].(int)
+		offset = prev
+	}
+
+	// An object past the end of the file means the table is damaged or
+	// the file truncated, and is better found by repairing
+	for num, offset := range d.offsets {
+		if offset < 0 || offset >= len(d.data) {
+			return fmt.Errorf("object %d offset %d is past the end of the file", num, offset)
+		}
+	}
+	return nil
+}
+
+// setOffset records an object's offset unless a newer section did
+func (d *pdfDocument) setOffset(num, offset int) {
+	if _, ok := d.offsets[num]; ok {
+		return
+	}
+	if _, ok := d.compressed[num]; ok {
+		return
+	}
+	d.offsets[num] = offset
+}
+
+// readXRefTable reads a classic "xref" section and the trailer after it
+func (d *pdfDocument) readXRefTable(offset int) (pdfDict, error) {
+	p := &pdfParser{data: d.data, pos: offset + len("xref"), doc: d}
+
+	for {
+		p.skipSpace()
+		if bytes.HasPrefix(d.data[p.pos:], []byte("trailer")) {
+			p.pos += len("trailer")
+			obj, err := p.readObject()
+			if err != nil {
+				return nil, err
+			}
+			trailer, ok := obj.(pdfDict)
+			if !ok {
+				return nil, fmt.Errorf("%w: trailer is not a dictionary", errPDFSyntax)
+			}
+			return trailer, nil
+		}
+
+		first, err1 := strconv.Atoi(p.readRegular())
+		p.skipSpace()
+		count, err2 := strconv.Atoi(p.readRegular())
+		if err1 != nil || err2 != nil || first < 0 || count < 0 {
+			return nil, fmt.Errorf("%w: bad xref subsection at %d", errPDFSyntax, p.pos)
+		}
+
+		for i := 0; i < count; i++ {
+			p.skipSpace()
+			objOffset, err1 := strconv.Atoi(p.readRegular())
+			p.skipSpace()
+			_, err2 := strconv.Atoi(p.readRegular())
+			p.skipSpace()
+			kind := p.readRegular()
+			if err1 != nil || err2 != nil || (kind != "n" && kind != "f") {
+				return nil, fmt.Errorf("%w: bad xref entry at %d", errPDFSyntax, p.pos)
+			}
+			if kind == "n" && objOffset > 0 {
+				d.setOffset(first+i, objOffset)
+			}
+		}
+	}
+}
+
+// readXRefStream reads a cross-reference stream (PDF 1.5), whose
+// dictionary is also the trailer
+func (d *pdfDocument) readXRefStream(offset int) (pdfDict, error) {
+	_, obj, err := d.readObjectAt(offset)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := obj.(*pdfStream)
+	if !ok || stream.Dict["Type"] != pdfName("XRef") {
+		return nil, fmt.Errorf("%w: no cross-reference stream at %d", errPDFSyntax, offset)
+	}
+
+	data, err := d.streamData(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	widths, _ := stream.Dict["W"].(pdfArray)
+	if len(widths) != 3 {
+		return nil, fmt.Errorf("%w: bad /W in cross-reference stream", errPDFSyntax)
+	}
+	var w [3]int
+	for i := range w {
+		w[i], _ = widths[i].(int)
+		if w[i] < 0 || w[i] > 8 {
+			return nil, fmt.Errorf("%w: bad /W in cross-reference stream", errPDFSyntax)
+		}
+	}
+	entryLen := w[0] + w[1] + w[2]
+	if entryLen == 0 {
+		return nil, fmt.Errorf("%w: bad /W in cross-reference stream", errPDFSyntax)
+	}
+
+	index, _ := stream.Dict["Index"].(pdfArray)
+	if len(index) == 0 {
+		size, _ := stream.Dict["Size"].(int)
+		index = pdfArray{0, size}
+	}
+
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		first, _ := index[i].(int)
+		count, _ := index[i+1].(int)
+		for n := 0; n < count && pos+entryLen <= len(data); n++ {
+			field := func(start, width int) int {
+				value := 0
+				for _, b := range data[start : start+width] {
+					value = value<<8 | int(b)
+				}
+				return value
+			}
+			kind := 1
+			if w[0] > 0 {
+				kind = field(pos, w[0])
+			}
+			f2 := field(pos+w[0], w[1])
+			f3 := field(pos+w[0]+w[1], w[2])
+			pos += entryLen
+
+			num := first + n
+			switch kind {
+			case 1:
+				d.setOffset(num, f2)
+			case 2:
+				if _, ok := d.offsets[num]; !ok {
+					if _, ok := d.compressed[num]; !ok {
+						d.compressed[num] = [2]int{f2, f3}
+					}
+				}
+			}
+		}
+	}
+
+	return stream.Dict, nil
+}
+
+// repair rebuilds the cross-reference table by scanning the file for
+// "n g obj", and finds the trailer or the catalog
+func (d *pdfDocument) repair() error {
+	d.repaired = true
+	d.offsets = make(map[int]int)
+	d.compressed = make(map[int][2]int)
+	d.cache = make(map[int]any)
+
+	for _, loc := range objectPattern.FindAllSubmatchIndex(d.data, -1) {
+		num, _ := strconv.Atoi(string(d.data[loc[2]:loc[3]]))
+		d.offsets[num] = loc[2]
+	}
+	if len(d.offsets) == 0 {
+		return errors.New("no objects found")
+	}
+
+	// Object streams hold objects of their own
+	for num := range d.offsets {
+		stream, ok := d.object(num).(*pdfStream)
+		if !ok || stream.Dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		if headers, _, err := d.objectStreamHeader(stream); err == nil {
+			for i, header := range headers {
+				if _, ok := d.offsets[header[0]]; !ok {
+					d.compressed[header[0]] = [2]int{num, i}
+				}
+			}
+		}
+	}
+
+	d.trailer = nil
+	for i := bytes.LastIndex(d.data, []byte("trailer")); i >= 0; i = bytes.LastIndex(d.data[:i], []byte("trailer")) {
+		p := &pdfParser{data: d.data, pos: i + len("trailer"), doc: d}
+		if obj, err := p.readObject(); err == nil {
+			if trailer, ok := obj.(pdfDict); ok && trailer["Root"] != nil {
+				d.trailer = trailer
+				break
+			}
+		}
+	}
+	if d.trailer == nil {
+		for num := range d.offsets {
+			if stream, ok := d.object(num).(*pdfStream); ok && stream.Dict["Type"] == pdfName("XRef") && stream.Dict["Root"] != nil {
+				d.trailer = stream.Dict
+				break
+			}
+		}
+	}
+	if d.trailer == nil {
+		d.trailer = make(pdfDict)
+		for num := range d.offsets {
+			if dict, ok := d.object(num).(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+				d.trailer["Root"] = pdfRef{Num: num}
+				break
+			}
+		}
+		if d.trailer["Root"] == nil {
+			return errors.New("no document catalog found")
+		}
+	}
+	return nil
+}
+
+// readObjectAt parses the indirect object "n g obj ... endobj" at offset
+func (d *pdfDocument) readObjectAt(offset int) (int, any, error) {
+	if offset < 0 || offset >= len(d.data) {
+		return 0, nil, fmt.Errorf("%w: object offset %d is outside the file", errPDFSyntax, offset)
+	}
+	p := &pdfParser{data: d.data, pos: offset, doc: d}
+	p.skipSpace()
+	num, err1 := strconv.Atoi(p.readRegular())
+	p.skipSpace()
+	_, err2 := strconv.Atoi(p.readRegular())
+	p.skipSpace()
+	if err1 != nil || err2 != nil || p.readRegular() != "obj" {
+		return 0, nil, fmt.Errorf("%w: no object at %d", errPDFSyntax, offset)
+	}
+	obj, err := p.readObject()
+	if err != nil {
+		return num, nil, err
+	}
+	return num, obj, nil
+}
+
+// object returns an indirect object, or nil when it is missing or damaged,
+// as the PDF specification treats a reference to a missing object as null
+func (d *pdfDocument) object(num int) any {
+	if obj, ok := d.cache[num]; ok {
+		return obj
+	}
+	if d.loading[num] {
+		return nil
+	}
+	d.loading[num] = true
+	defer delete(d.loading, num)
+
+	var obj any
+	if offset, ok := d.offsets[num]; ok {
+		if found, parsed, err := d.readObjectAt(offset); err == nil && found == num {
+			obj = parsed
+		}
+	} else if location, ok := d.compressed[num]; ok {
+		obj = d.compressedObject(location[0], location[1])
+	}
+
+	d.cache[num] = obj
+	return obj
+}
+
+// objectStreamHeader returns the object numbers and offsets of an object
+// stream and its decoded data
+func (d *pdfDocument) objectStreamHeader(stream *pdfStream) ([][2]int, []byte, error) {
+	data, err := d.streamData(stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	n, _ := d.resolveInt(stream.Dict["N"])
+	first, _ := d.resolveInt(stream.Dict["First"])
+	if n < 0 || first < 0 || first > len(data) {
+		return nil, nil, fmt.Errorf("%w: bad object stream", errPDFSyntax)
+	}
+
+	p := &pdfParser{data: data[:first]}
+	// Each header takes at least four bytes, so a larger /N is a lie
+	headers := make([][2]int, 0, min(n, len(data)))
+	for i := 0; i < n; i++ {
+		p.skipSpace()
+		num, err1 := strconv.Atoi(p.readRegular())
+		p.skipSpace()
+		offset, err2 := strconv.Atoi(p.readRegular())
+		if err1 != nil || err2 != nil {
+			return nil, nil, fmt.Errorf("%w: bad object stream header", errPDFSyntax)
+		}
+		headers = append(headers, [2]int{num, first + offset})
+	}
+	return headers, data, nil
+}
+
+// compressedObject reads object index of an object stream
+func (d *pdfDocument) compressedObject(streamNum, index int) any {
+	stream, ok := d.object(streamNum).(*pdfStream)
+	if !ok {
+		return nil
+	}
+	headers, data, err := d.objectStreamHeader(stream)
+	if err != nil || index < 0 || index >= len(headers) || headers[index][1] > len(data) {
+		return nil
+	}
+	p := &pdfParser{data: data, pos: headers[index][1], doc: d}
+	obj, err := p.readObject()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// resolve follows references to the object they name
+func (d *pdfDocument) resolve(obj any) any {
+	for i := 0; i < maxPDFDepth; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		if d == nil {
+			return nil
+		}
+		obj = d.object(ref.Num)
+	}
+	return nil
+}
+
+func (d *pdfDocument) resolveDict(obj any) pdfDict {
+	switch v := d.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.Dict
+	}
+	return nil
+}
+
+func (d *pdfDocument) resolveInt(obj any) (int, bool) {
+	switch v := d.resolve(obj).(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func (d *pdfDocument) resolveNumber(obj any) (float64, bool) {
+	switch v := d.resolve(obj).(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// streamData decodes a stream through its filters. Image filters such as
+// DCTDecode are not decoded, since only text and metadata are read.
+func (d *pdfDocument) streamData(stream *pdfStream) ([]byte, error) {
+	data := stream.Raw
+
+	var filters, params []any
+	switch f := d.resolve(stream.Dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+		params = []any{d.resolve(stream.Dict["DecodeParms"])}
+	case pdfArray:
+		filters = f
+		if p, ok := d.resolve(stream.Dict["DecodeParms"]).(pdfArray); ok {
+			params = p
+		}
+	}
+
+	for i, filter := range filters {
+		var param pdfDict
+		if i < len(params) {
+			param = d.resolveDict(params[i])
+		}
+
+		var err error
+		switch d.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = flateDecode(data)
+			if err == nil {
+				data, err = d.applyPredictor(data, param)
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			p := &pdfParser{data: append(append([]byte(nil), data...), '>')}
+			var s pdfString
+			s, err = p.readHexString()
+			data = []byte(s)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data, err = ascii85Decode(data)
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// flateDecode inflates zlib data, keeping what could be read of a
+// truncated stream. A stream that inflates past maxPDFStreamSize is
+// treated as damaged.
+func flateDecode(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error inflating stream: %v", err)
+	}
+	defer reader.Close()
+
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxPDFStreamSize+1))
+	if len(inflated) > maxPDFStreamSize {
+		return nil, fmt.Errorf("error inflating stream: more than %d bytes", maxPDFStreamSize)
+	}
+	if err != nil && len(inflated) == 0 {
+		return nil, fmt.Errorf("error inflating stream: %v", err)
+	}
+	return inflated, nil
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	decoded := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(decoded, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding ASCII85 stream: %v", err)
+	}
+	return decoded[:n], nil
+}
+
+// applyPredictor undoes the PNG row predictors used with FlateDecode,
+// most often in cross-reference streams
+func (d *pdfDocument) applyPredictor(data []byte, params pdfDict) ([]byte, error) {
+	predictor, _ := d.resolveInt(params["Predictor"])
+	if predictor < 10 {
+		if predictor == 2 {
+			return nil, errors.New("unsupported TIFF predictor")
+		}
+		return data, nil
+	}
+
+	colors, bits, columns := 1, 8, 1
+	if v, ok := d.resolveInt(params["Colors"]); ok && v > 0 {
+		colors = v
+	}
+	if v, ok := d.resolveInt(params["BitsPerComponent"]); ok && v > 0 {
+		bits = v
+	}
+	if v, ok := d.resolveInt(params["Columns"]); ok && v > 0 {
+		columns = v
+	}
+	if colors > 32 || bits > 32 || columns > (math.MaxInt-7)/(colors*bits) {
+		return nil, errors.New("bad predictor parameters")
+	}
+	bpp := max(1, (colors*bits+7)/8)
+	rowLen := (columns*colors*bits + 7) / 8
+	if rowLen <= 0 || rowLen > len(data) {
+		return nil, fmt.Errorf("predictor row of %d bytes does not fit %d bytes of data", rowLen, len(data))
+	}
+
+	var out []byte
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+1+rowLen <= len(data); pos += 1 + rowLen {
+		kind := data[pos]
+		row := append([]byte(nil), data[pos+1:pos+1+rowLen]...)
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up := prev[i]
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
@@ -0,0 +1,351 @@
+package musiclib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDFInfo is what the PDF inspector reads from a file: the document's
+// metadata, its page count and the size of its first page in points
+type PDFInfo struct {
+	Version    string
+	Title      string
+	Author     string
+	Subject    string
+	Keywords   string
+	Creator    string
+	Producer   string
+	PageCount  int
+	PageWidth  float64
+	PageHeight float64
+	Encrypted  bool
+	Repaired   bool
+}
+
+// ErrPDFEncrypted is returned for a PDF whose strings are encrypted, so
+// its metadata cannot be read without the password
+var ErrPDFEncrypted = errors.New("PDF is encrypted")
+
+// InspectPDF reads the metadata and page structure of a PDF file. An
+// encrypted file returns what could be read, such as its page count, with
+// ErrPDFEncrypted; a damaged file returns the error that stopped it.
+func InspectPDF(filePath string) (PDFInfo, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return PDFInfo{}, fmt.Errorf("error reading PDF '%s': %v", filePath, err)
+	}
+	return InspectPDFData(data)
+}
+
+// InspectPDFData reads the metadata and page structure of PDF data
+func InspectPDFData(data []byte) (PDFInfo, error) {
+	doc, err := openPDF(data)
+	if err != nil {
+		return PDFInfo{}, err
+	}
+
+	info := PDFInfo{Version: doc.version(), Repaired: doc.repaired}
+	catalog := doc.resolveDict(doc.trailer["Root"])
+	if catalog == nil {
+		return info, errors.New("PDF has no document catalog")
+	}
+	if version, ok := doc.resolve(catalog["Version"]).(pdfName); ok && string(version) > info.Version {
+		info.Version = string(version)
+	}
+
+	info.PageCount, info.PageWidth, info.PageHeight = doc.pageTree(catalog)
+
+	encrypt := doc.resolveDict(doc.trailer["Encrypt"])
+	if encrypt != nil {
+		info.Encrypted = true
+		// Metadata streams may be left in the clear
+		if encryptMetadata, ok := doc.resolve(encrypt["EncryptMetadata"]).(bool); ok && !encryptMetadata {
+			doc.readXMP(catalog, &info)
+		}
+		return info, ErrPDFEncrypted
+	}
+
+	doc.readXMP(catalog, &info)
+	if dict := doc.resolveDict(doc.trailer["Info"]); dict != nil {
+		for key, field := range map[pdfName]*string{
+			"Title":    &info.Title,
+			"Author":   &info.Author,
+			"Subject":  &info.Subject,
+			"Keywords": &info.Keywords,
+			"Creator":  &info.Creator,
+			"Producer": &info.Producer,
+		} {
+			if *field != "" {
+				continue
+			}
+			if s, ok := doc.resolve(dict[key]).(pdfString); ok {
+				*field = strings.TrimSpace(pdfTextString(s))
+			}
+		}
+	}
+
+	if info.PageCount == 0 {
+		return info, errors.New("PDF has no pages")
+	}
+	return info, nil
+}
+
+// pageTree returns the page count and the size of the first page, whose
+// media box may be inherited from the page tree above it
+func (d *pdfDocument) pageTree(catalog pdfDict) (int, float64, float64) {
+	pages := d.resolveDict(catalog["Pages"])
+	if pages == nil {
+		return 0, 0, 0
+	}
+	count, _ := d.resolveInt(pages["Count"])
+
+	var box pdfArray
+	var rotate int
+	node := pages
+	for depth := 0; node != nil && depth < maxPDFDepth; depth++ {
+		if b, ok := d.resolve(node["CropBox"]).(pdfArray); ok && len(b) == 4 {
+			box = b
+		} else if b, ok := d.resolve(node["MediaBox"]).(pdfArray); ok && len(b) == 4 && box == nil {
+			box = b
+		}
+		if r, ok := d.resolveInt(node["Rotate"]); ok {
+			rotate = r
+		}
+		kids, ok := d.resolve(node["Kids"]).(pdfArray)
+		if !ok || len(kids) == 0 {
+			break
+		}
+		node = d.resolveDict(kids[0])
+	}
+
+	if box == nil {
+		return count, 0, 0
+	}
+	var corners [4]float64
+	for i := range corners {
+		corners[i], _ = d.resolveNumber(box[i])
+	}
+	width := math.Abs(corners[2] - corners[0])
+	height := math.Abs(corners[3] - corners[1])
+	if rotate%180 != 0 {
+		width, height = height, width
+	}
+	return count, width, height
+}
+
+// readXMP fills blank fields of info from the document's XMP metadata
+func (d *pdfDocument) readXMP(catalog pdfDict, info *PDFInfo) {
+	stream, ok := d.resolve(catalog["Metadata"]).(*pdfStream)
+	if !ok {
+		return
+	}
+	data, err := d.streamData(stream)
+	if err != nil {
+		return
+	}
+
+	xmp := parseXMP(data)
+	for _, field := range []struct {
+		target *string
+		value  string
+	}{
+		{&info.Title, xmp.title},
+		{&info.Author, xmp.creator},
+		{&info.Subject, xmp.description},
+		{&info.Keywords, xmp.keywords},
+		{&info.Creator, xmp.creatorTool},
+		{&info.Producer, xmp.producer},
+	} {
+		if *field.target == "" {
+			*field.target = strings.TrimSpace(field.value)
+		}
+	}
+}
+
+const (
+	nsDublinCore = "http://purl.org/dc/elements/1.1/"
+	nsAdobePDF   = "http://ns.adobe.com/pdf/1.3/"
+	nsXMP        = "http://ns.adobe.com/xap/1.0/"
+)
+
+type xmpFields struct {
+	title, creator, description, keywords, creatorTool, producer string
+}
+
+// parseXMP reads the Dublin Core and PDF properties of an XMP packet.
+// Properties may be elements holding an rdf:Alt, rdf:Seq or rdf:Bag of
+// rdf:li items, or attributes of rdf:Description.
+func parseXMP(data []byte) xmpFields {
+	var fields xmpFields
+	targets := map[xml.Name]*string{
+		{Space: nsDublinCore, Local: "title"}:       &fields.title,
+		{Space: nsDublinCore, Local: "creator"}:     &fields.creator,
+		{Space: nsDublinCore, Local: "description"}: &fields.description,
+		{Space: nsAdobePDF, Local: "Keywords"}:      &fields.keywords,
+		{Space: nsDublinCore, Local: "subject"}:     &fields.keywords,
+		{Space: nsXMP, Local: "CreatorTool"}:        &fields.creatorTool,
+		{Space: nsAdobePDF, Local: "Producer"}:      &fields.producer,
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	var current *string
+	var items []string
+	var text strings.Builder
+	depth, currentDepth := 0, 0
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return fields
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if current == nil {
+				if target, ok := targets[t.Name]; ok && *target == "" {
+					current, currentDepth = target, depth
+					items = nil
+					text.Reset()
+				}
+				for _, attr := range t.Attr {
+					if target, ok := targets[attr.Name]; ok && *target == "" {
+						*target = attr.Value
+					}
+				}
+			} else if t.Name.Local == "li" {
+				text.Reset()
+			}
+		case xml.CharData:
+			if current != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if current != nil {
+				if t.Name.Local == "li" {
+					if item := strings.TrimSpace(text.String()); item != "" {
+						items = append(items, item)
+					}
+					text.Reset()
+				} else if depth == currentDepth {
+					if len(items) == 0 {
+						items = append(items, strings.TrimSpace(text.String()))
+					}
+					*current = strings.Join(items, ", ")
+					current = nil
+				}
+			}
+			depth--
+		}
+	}
+}
+
+// pdfDocEncoding maps the bytes 0x80-0x9F of PDFDocEncoding, which differ
+// from Latin-1; the bytes above 0x9F are Latin-1
+var pdfDocEncoding = [32]rune{
+	'•', '†', '‡', '…', '—', '–', 'ƒ', '⁄', '‹', '›', '−', '‰', '„', '“', '”', '‘',
+	'’', '‚', '™', 'ﬁ', 'ﬂ', 'Ł', 'Œ', 'Š', 'Ÿ', 'Ž', 'ı', 'ł', 'œ', 'š', 'ž', '�',
+}
+
+// pdfTextString decodes a PDF text string, which is UTF-16BE or UTF-8
+// with a byte order mark, or else PDFDocEncoding
+func pdfTextString(s pdfString) string {
+	raw := []byte(s)
+	switch {
+	case len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF:
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case len(raw) >= 3 && raw[0] == 0xEF && raw[1] == 0xBB && raw[2] == 0xBF:
+		return strings.ToValidUTF8(string(raw[3:]), "�")
+	}
+
+	var b strings.Builder
+	for _, c := range raw {
+		if c >= 0x80 && c <= 0x9F {
+			b.WriteRune(pdfDocEncoding[c-0x80])
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// pageSizes are the paper sizes music is printed on, in points, portrait
+var pageSizes = []struct {
+	name          string
+	width, height float64
+}{
+	{"Octavo", 486, 756},
+	{"Octavo", 504, 756},
+	{"Letter", 612, 792},
+	{"A4", 595, 842},
+	{"Concert", 648, 864},
+	{"Legal", 612, 1008},
+	{"A5", 420, 595},
+	{"A3", 842, 1191},
+	{"Tabloid", 792, 1224},
+}
+
+// pageSizeTolerance allows for scans and trimmed pages
+const pageSizeTolerance = 14
+
+// PageSize names the paper size of a page, such as "Octavo" or "Letter",
+// adding "landscape" for pages wider than they are tall. Unknown sizes are
+// given in inches.
+func (i PDFInfo) PageSize() string {
+	if i.PageWidth <= 0 || i.PageHeight <= 0 {
+		return ""
+	}
+	width, height := i.PageWidth, i.PageHeight
+	landscape := width > height
+	if landscape {
+		width, height = height, width
+	}
+
+	name := fmt.Sprintf("%.1f x %.1f in", width/72, height/72)
+	for _, size := range pageSizes {
+		if math.Abs(width-size.width) <= pageSizeTolerance && math.Abs(height-size.height) <= pageSizeTolerance {
+			name = size.name
+			break
+		}
+	}
+	if landscape {
+		name += " landscape"
+	}
+	return name
+}
+
+var (
+	wordProcessorPrefix = regexp.MustCompile(`(?i)^(?:microsoft word|microsoft powerpoint|word|untitled)\s*-\s*`)
+	fileExtensionSuffix = regexp.MustCompile(`(?i)\.(?:docx?|pdf|mus|musx|sib|mscz|indd|tiff?|jpe?g|png)$`)
+	placeholderTitle    = regexp.MustCompile(`(?i)^(?:untitled|unknown|title|(?:audio\s*)?track\s*\d*|document\s*\d*|score|scan\s*\d*|img[_ -]?\d+|\d+|\W*)$`)
+)
+
+// UsefulTitle returns the document title with the clutter added by word
+// processors and scanners removed, or "" when the title is a placeholder
+// such as "Untitled" or "Microsoft Word - Document1"
+func (i PDFInfo) UsefulTitle() string {
+	title := wordProcessorPrefix.ReplaceAllString(strings.TrimSpace(i.Title), "")
+	title = strings.TrimSpace(fileExtensionSuffix.ReplaceAllString(title, ""))
+	if IsPlaceholderTitle(title) {
+		return ""
+	}
+	return title
+}
+
+// IsPlaceholderTitle reports whether a title is one a scanner or word
+// processor gives a file, such as "Untitled", "Scan 001", "Track 01" or
+// "Document1"
+func IsPlaceholderTitle(title string) bool {
+	return placeholderTitle.MatchString(strings.TrimSpace(title))
+}
@@ -0,0 +1,94 @@
+package musiclib
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readTestdata returns the contents of a file under testdata
+func readTestdata(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestInspectPDFData(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		want   PDFInfo
+		wantOK bool
+	}{
+		{
+			name:   "info dictionary",
+			data:   readTestdata(t, "word_export.pdf"),
+			want:   PDFInfo{Version: "1.4", Title: "Microsoft Word - Ave Maria.docx", Author: "Franz Biebl", PageCount: 4, PageWidth: 486, PageHeight: 756},
+			wantOK: true,
+		},
+		{
+			name:   "inherited media box",
+			data:   readTestdata(t, "octavo.pdf"),
+			want:   PDFInfo{Version: "1.4", PageCount: 1, PageWidth: 486, PageHeight: 756},
+			wantOK: true,
+		},
+		{
+			name:   "object and cross-reference streams",
+			data:   readTestdata(t, "xref_stream.pdf"),
+			want:   PDFInfo{Version: "1.5", Title: "Locus Iste", Author: "Anton Bruckner", Producer: "Dorico", PageCount: 2, PageWidth: 612, PageHeight: 792},
+			wantOK: true,
+		},
+		{
+			name:   "offset past the end of the file",
+			data:   readTestdata(t, "bad_offset.pdf"),
+			want:   PDFInfo{Version: "1.4", PageCount: 1, PageWidth: 486, PageHeight: 756, Repaired: true},
+			wantOK: true,
+		},
+		{
+			name:   "truncated",
+			data:   readTestdata(t, "word_export.pdf")[:250],
+			want:   PDFInfo{Version: "1.4", PageCount: 4, PageWidth: 486, PageHeight: 756, Repaired: true},
+			wantOK: true,
+		},
+		{
+			name:   "huge predictor columns",
+			data:   bytes.Replace(readTestdata(t, "xref_stream.pdf"), []byte("/Columns 4"), []byte("/Columns 4611686018427387904"), 1),
+			want:   PDFInfo{Version: "1.5", Title: "Locus Iste", Author: "Anton Bruckner", Producer: "Dorico", PageCount: 2, PageWidth: 612, PageHeight: 792, Repaired: true},
+			wantOK: true,
+		},
+		{
+			name: "not a PDF",
+			data: []byte("GIF89a"),
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InspectPDFData(tt.data)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("InspectPDFData() = %+v, %v, want ok %v", got, err, tt.wantOK)
+			}
+			if tt.wantOK && got != tt.want {
+				t.Errorf("InspectPDFData() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzInspectPDFData(f *testing.F) {
+	for _, name := range []string{"word_export.pdf", "octavo.pdf", "xref_stream.pdf", "bad_offset.pdf"} {
+		f.Add(readTestdata(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := InspectPDFData(data)
+		if err == nil && info.PageCount < 0 {
+			t.Errorf("negative page count %d", info.PageCount)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package musiclib
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+)
+
+// deflate compresses data as a FlateDecode stream
+func deflate(t testing.TB, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFlateDecode(t *testing.T) {
+	content := []byte("BT /F1 24 Tf 72 700 Td (Ave Maria) Tj ET")
+	tests := []struct {
+		name    string
+		data    []byte
+		wantLen int
+		wantOK  bool
+	}{
+		{name: "content stream", data: deflate(t, content), wantLen: len(content), wantOK: true},
+		{name: "truncated", data: deflate(t, content)[:20], wantLen: 13, wantOK: true},
+		{name: "not zlib", data: content},
+		{name: "at the size limit", data: deflate(t, make([]byte, maxPDFStreamSize)), wantLen: maxPDFStreamSize, wantOK: true},
+		{name: "past the size limit", data: deflate(t, make([]byte, maxPDFStreamSize+1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := flateDecode(tt.data)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("flateDecode() error = %v, wantOK %v", err, tt.wantOK)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("flateDecode() = %d bytes, want %d", len(got), tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,692 @@
+package musiclib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TextLine is a line of text on a page. Size is the size of its largest
+// font and Top its distance from the top of the page, both in points.
+type TextLine struct {
+	Text string
+	Size float64
+	Top  float64
+}
+
+// maxFormDepth bounds form XObjects drawn inside one another
+const maxFormDepth = 8
+
+// FirstPageText returns the lines of text on the first page of a PDF, top
+// to bottom. A scanned page has no text and gives no lines.
+func FirstPageText(filePath string) ([]TextLine, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF '%s': %v", filePath, err)
+	}
+	return FirstPageTextData(data)
+}
+
+// FirstPageTextData returns the lines of text on the first page of PDF data
+func FirstPageTextData(data []byte) ([]TextLine, error) {
+	doc, err := openPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.resolveDict(doc.trailer["Encrypt"]) != nil {
+		return nil, ErrPDFEncrypted
+	}
+	catalog := doc.resolveDict(doc.trailer["Root"])
+	if catalog == nil {
+		return nil, errors.New("PDF has no document catalog")
+	}
+	page, resources, top := doc.firstPage(catalog)
+	if page == nil {
+		return nil, errors.New("PDF has no pages")
+	}
+
+	extractor := &textExtractor{doc: doc, fonts: make(map[pdfRef]*pdfFont)}
+	extractor.run(doc.contents(page["Contents"]), resources, identityMatrix, 0)
+	return extractor.lines(top), nil
+}
+
+// firstPage returns the first page of the page tree with the resources it
+// inherits and the top of its media box
+func (d *pdfDocument) firstPage(catalog pdfDict) (pdfDict, pdfDict, float64) {
+	node := d.resolveDict(catalog["Pages"])
+	var resources pdfDict
+	top := 792.0
+	for depth := 0; node != nil && depth < maxPDFDepth; depth++ {
+		if r := d.resolveDict(node["Resources"]); r != nil {
+			resources = r
+		}
+		if box, ok := d.resolve(node["MediaBox"]).(pdfArray); ok && len(box) == 4 {
+			if y, ok := d.resolveNumber(box[3]); ok {
+				top = y
+			}
+		}
+		kids, ok := d.resolve(node["Kids"]).(pdfArray)
+		if !ok {
+			return node, resources, top
+		}
+		if len(kids) == 0 {
+			return nil, nil, 0
+		}
+		node = d.resolveDict(kids[0])
+	}
+	return nil, nil, 0
+}
+
+// contents returns the decoded content of a page, whose Contents is a
+// stream or an array of streams
+func (d *pdfDocument) contents(obj any) []byte {
+	var streams []*pdfStream
+	switch contents := d.resolve(obj).(type) {
+	case *pdfStream:
+		streams = append(streams, contents)
+	case pdfArray:
+		for _, item := range contents {
+			if stream, ok := d.resolve(item).(*pdfStream); ok {
+				streams = append(streams, stream)
+			}
+		}
+	}
+
+	var content []byte
+	for _, stream := range streams {
+		if data, err := d.streamData(stream); err == nil {
+			content = append(append(content, data...), '\n')
+		}
+	}
+	return content
+}
+
+// matrix is a PDF transformation matrix [a b c d e f]
+type matrix [6]float64
+
+var identityMatrix = matrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n, which applies m and then n
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translation(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+// textRun is a string shown on the page: where it starts and ends on the
+// baseline, and its size, in points
+type textRun struct {
+	x, y, end, size float64
+	text            string
+}
+
+// textExtractor runs the text operators of content streams and collects
+// the strings they show
+type textExtractor struct {
+	doc   *pdfDocument
+	fonts map[pdfRef]*pdfFont
+	runs  []textRun
+}
+
+// textState is the text state of a content stream
+type textState struct {
+	font                                                 *pdfFont
+	fontSize, charSpace, wordSpace, scale, leading, rise float64
+	tm, tlm                                              matrix
+}
+
+// run interprets a content stream drawn with the transformation ctm
+func (e *textExtractor) run(content []byte, resources pdfDict, ctm matrix, depth int) {
+	parser := &pdfParser{data: content, doc: e.doc}
+	state := textState{scale: 1, tm: identityMatrix, tlm: identityMatrix}
+	var stack []matrix
+	var operands []any
+
+	number := func(i int) float64 {
+		if i >= len(operands) {
+			return 0
+		}
+		return toFloat(operands[i])
+	}
+	operandMatrix := func() matrix {
+		return matrix{number(0), number(1), number(2), number(3), number(4), number(5)}
+	}
+	nextLine := func(tx, ty float64) {
+		state.tlm = translation(tx, ty).multiply(state.tlm)
+		state.tm = state.tlm
+	}
+
+	for {
+		start := parser.pos
+		obj, err := parser.readObject()
+		if err == io.ErrUnexpectedEOF {
+			return
+		}
+		if err != nil {
+			if parser.pos == start {
+				parser.pos++
+			}
+			operands = operands[:0]
+			continue
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, ctm)
+		case "Q":
+			if len(stack) > 0 {
+				ctm, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(operands) == 6 {
+				ctm = operandMatrix().multiply(ctm)
+			}
+		case "BT":
+			state.tm, state.tlm = identityMatrix, identityMatrix
+		case "Tf":
+			if len(operands) == 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					state.font = e.font(resources, name)
+				}
+				state.fontSize = number(1)
+			}
+		case "Tc":
+			state.charSpace = number(0)
+		case "Tw":
+			state.wordSpace = number(0)
+		case "Tz":
+			state.scale = number(0) / 100
+		case "TL":
+			state.leading = number(0)
+		case "Ts":
+			state.rise = number(0)
+		case "Td":
+			nextLine(number(0), number(1))
+		case "TD":
+			state.leading = -number(1)
+			nextLine(number(0), number(1))
+		case "Tm":
+			if len(operands) == 6 {
+				state.tlm = operandMatrix()
+				state.tm = state.tlm
+			}
+		case "T*":
+			nextLine(0, -state.leading)
+		case "Tj", "'", "\"":
+			if op == "\"" && len(operands) == 3 {
+				state.wordSpace, state.charSpace = number(0), number(1)
+			}
+			if op != "Tj" {
+				nextLine(0, -state.leading)
+			}
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					e.show(&state, ctm, s, false)
+				}
+			}
+		case "TJ":
+			items, _ := operandsArray(operands)
+			space := false
+			for _, item := range items {
+				switch item := item.(type) {
+				case pdfString:
+					e.show(&state, ctm, item, space)
+					space = false
+				case int, float64:
+					adjust := toFloat(item)
+					state.tm = translation(-adjust/1000*state.fontSize*state.scale, 0).multiply(state.tm)
+					// Kerning is small; a large gap stands for a space
+					space = space || adjust < -200
+				}
+			}
+		case "Do":
+			if len(operands) == 1 && depth < maxFormDepth {
+				if name, ok := operands[0].(pdfName); ok {
+					e.drawForm(resources, name, ctm, depth)
+				}
+			}
+		case "ID":
+			// Skip the data of an inline image, which ends at "EI"
+			if end := bytes.Index(parser.data[parser.pos:], []byte("EI")); end >= 0 {
+				parser.pos += end + 2
+			} else {
+				return
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func operandsArray(operands []any) (pdfArray, bool) {
+	if len(operands) == 0 {
+		return nil, false
+	}
+	array, ok := operands[len(operands)-1].(pdfArray)
+	return array, ok
+}
+
+func toFloat(obj any) float64 {
+	switch n := obj.(type) {
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// show records a string shown with the current text state and moves the
+// text matrix past it
+func (e *textExtractor) show(state *textState, ctm matrix, s pdfString, space bool) {
+	if state.font == nil {
+		state.font = &pdfFont{defaultWidth: 0.5}
+	}
+	rendering := matrix{state.fontSize * state.scale, 0, 0, state.fontSize, 0, state.rise}.multiply(state.tm).multiply(ctm)
+	text, advances := state.font.decode(s)
+
+	var advance float64
+	for i, width := range advances {
+		advance += width*state.fontSize + state.charSpace
+		if text[i] == " " {
+			advance += state.wordSpace
+		}
+	}
+	state.tm = translation(advance*state.scale, 0).multiply(state.tm)
+	end := state.tm.multiply(ctm)
+
+	joined := strings.Join(text, "")
+	if space {
+		joined = " " + joined
+	}
+	size := math.Hypot(rendering[2], rendering[3])
+	e.runs = append(e.runs, textRun{x: rendering[4], y: rendering[5], end: end[4], size: size, text: joined})
+}
+
+// drawForm interprets a form XObject named in the resources
+func (e *textExtractor) drawForm(resources pdfDict, name pdfName, ctm matrix, depth int) {
+	xobjects := e.doc.resolveDict(resources["XObject"])
+	stream, ok := e.doc.resolve(xobjects[name]).(*pdfStream)
+	if !ok {
+		return
+	}
+	if subtype, _ := e.doc.resolve(stream.Dict["Subtype"]).(pdfName); subtype != "Form" {
+		return
+	}
+	data, err := e.doc.streamData(stream)
+	if err != nil {
+		return
+	}
+
+	form := identityMatrix
+	if m, ok := e.doc.resolve(stream.Dict["Matrix"]).(pdfArray); ok && len(m) == 6 {
+		for i := range form {
+			form[i], _ = e.doc.resolveNumber(m[i])
+		}
+	}
+	formResources := e.doc.resolveDict(stream.Dict["Resources"])
+	if formResources == nil {
+		formResources = resources
+	}
+	e.run(data, formResources, form.multiply(ctm), depth+1)
+}
+
+// lines groups the runs into lines, top to bottom and left to right
+func (e *textExtractor) lines(top float64) []TextLine {
+	runs := e.runs
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].y > runs[j].y })
+
+	var groups [][]textRun
+	for _, run := range runs {
+		if strings.TrimSpace(run.text) == "" {
+			continue
+		}
+		if n := len(groups); n > 0 {
+			first := groups[n-1][0]
+			if math.Abs(first.y-run.y) <= 0.3*max(min(first.size, run.size), 1) {
+				groups[n-1] = append(groups[n-1], run)
+				continue
+			}
+		}
+		groups = append(groups, []textRun{run})
+	}
+
+	var lines []TextLine
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].x < group[j].x })
+		var text strings.Builder
+		line := TextLine{Top: top - group[0].y}
+		for i, run := range group {
+			if i > 0 && run.x-group[i-1].end > 0.15*run.size && !strings.HasPrefix(run.text, " ") {
+				text.WriteByte(' ')
+			}
+			text.WriteString(run.text)
+			line.Size = max(line.Size, run.size)
+		}
+		line.Text = strings.Join(strings.Fields(text.String()), " ")
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfFont decodes the strings shown with a font into text, and gives the
+// width of each character in text space
+type pdfFont struct {
+	twoByte      bool
+	toUnicode    map[int]string
+	encoding     *[256]rune
+	widths       map[int]float64
+	defaultWidth float64
+}
+
+// font loads a font named in the resources, once per font object
+func (e *textExtractor) font(resources pdfDict, name pdfName) *pdfFont {
+	fonts := e.doc.resolveDict(resources["Font"])
+	ref, isRef := fonts[name].(pdfRef)
+	if isRef {
+		if font, ok := e.fonts[ref]; ok {
+			return font
+		}
+	}
+
+	font := e.doc.loadFont(e.doc.resolveDict(fonts[name]))
+	if isRef {
+		e.fonts[ref] = font
+	}
+	return font
+}
+
+// loadFont reads the encoding, ToUnicode map and widths of a font. Type0
+// fonts are taken to use two-byte codes, as the Identity-H encoding does.
+func (d *pdfDocument) loadFont(dict pdfDict) *pdfFont {
+	font := &pdfFont{defaultWidth: 0.5, widths: make(map[int]float64)}
+	if dict == nil {
+		font.encoding = &winAnsiEncoding
+		return font
+	}
+
+	if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.streamData(stream); err == nil {
+			font.toUnicode = parseToUnicode(data)
+		}
+	}
+
+	subtype, _ := d.resolve(dict["Subtype"]).(pdfName)
+	if subtype == "Type0" {
+		font.twoByte = true
+		font.defaultWidth = 1
+		descendants, _ := d.resolve(dict["DescendantFonts"]).(pdfArray)
+		if len(descendants) > 0 {
+			if cidFont := d.resolveDict(descendants[0]); cidFont != nil {
+				if width, ok := d.resolveNumber(cidFont["DW"]); ok {
+					font.defaultWidth = width / 1000
+				}
+				d.readCIDWidths(cidFont["W"], font.widths)
+			}
+		}
+		return font
+	}
+
+	font.encoding = d.simpleEncoding(dict["Encoding"])
+	firstChar, _ := d.resolveInt(dict["FirstChar"])
+	if widths, ok := d.resolve(dict["Widths"]).(pdfArray); ok {
+		for i, width := range widths {
+			if w, ok := d.resolveNumber(width); ok {
+				font.widths[firstChar+i] = w / 1000
+			}
+		}
+	}
+	return font
+}
+
+// readCIDWidths reads the W array of a CID font, whose entries are either
+// "c [w1 w2 ...]" or "cfirst clast w"
+func (d *pdfDocument) readCIDWidths(obj any, widths map[int]float64) {
+	array, _ := d.resolve(obj).(pdfArray)
+	for i := 0; i+1 < len(array); {
+		first, ok := d.resolveInt(array[i])
+		if !ok {
+			return
+		}
+		if list, ok := d.resolve(array[i+1]).(pdfArray); ok {
+			for j, width := range list {
+				if w, ok := d.resolveNumber(width); ok {
+					widths[first+j] = w / 1000
+				}
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(array) {
+			return
+		}
+		last, ok1 := d.resolveInt(array[i+1])
+		width, ok2 := d.resolveNumber(array[i+2])
+		if !ok1 || !ok2 || last-first > 0xFFFF {
+			return
+		}
+		for c := first; c <= last; c++ {
+			widths[c] = width / 1000
+		}
+		i += 3
+	}
+}
+
+// simpleEncoding returns the encoding of a simple font: WinAnsiEncoding,
+// which agrees with the standard encodings on letters and digits, changed
+// by the glyph names of a Differences array
+func (d *pdfDocument) simpleEncoding(obj any) *[256]rune {
+	dict := d.resolveDict(obj)
+	if dict == nil {
+		return &winAnsiEncoding
+	}
+	differences, ok := d.resolve(dict["Differences"]).(pdfArray)
+	if !ok {
+		return &winAnsiEncoding
+	}
+
+	encoding := winAnsiEncoding
+	code := 0
+	for _, item := range differences {
+		switch item := d.resolve(item).(type) {
+		case int:
+			code = item
+		case pdfName:
+			if code >= 0 && code < 256 {
+				if r, ok := glyphRune(string(item)); ok {
+					encoding[code] = r
+				}
+			}
+			code++
+		}
+	}
+	return &encoding
+}
+
+// decode returns the text of each character code in s with its width
+func (f *pdfFont) decode(s pdfString) ([]string, []float64) {
+	var text []string
+	var widths []float64
+	step := 1
+	if f.twoByte {
+		step = 2
+	}
+	for i := 0; i+step <= len(s); i += step {
+		code := int(s[i])
+		if f.twoByte {
+			code = code<<8 | int(s[i+1])
+		}
+
+		char, ok := f.toUnicode[code]
+		if !ok && !f.twoByte && f.encoding != nil {
+			if r := f.encoding[code]; r != 0 {
+				char = string(r)
+			}
+		}
+		width, ok := f.widths[code]
+		if !ok {
+			width = f.defaultWidth
+		}
+		text = append(text, char)
+		widths = append(widths, width)
+	}
+	return text, widths
+}
+
+// parseToUnicode reads the bfchar and bfrange mappings of a ToUnicode CMap
+func parseToUnicode(data []byte) map[int]string {
+	mapping := make(map[int]string)
+	parser := &pdfParser{data: data}
+	code := func(obj any) (int, bool) {
+		s, ok := obj.(pdfString)
+		if !ok || len(s) == 0 || len(s) > 4 {
+			return 0, false
+		}
+		n := 0
+		for i := 0; i < len(s); i++ {
+			n = n<<8 | int(s[i])
+		}
+		return n, true
+	}
+	unicode := func(obj any) string {
+		s, _ := obj.(pdfString)
+		return decodeUTF16([]byte(s), true)
+	}
+
+	var operands []any
+	for {
+		start := parser.pos
+		obj, err := parser.readObject()
+		if err == io.ErrUnexpectedEOF {
+			return mapping
+		}
+		if err != nil {
+			if parser.pos == start {
+				parser.pos++
+			}
+			continue
+		}
+		keyword, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch keyword {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				if src, ok := code(operands[i]); ok {
+					mapping[src] = unicode(operands[i+1])
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, ok1 := code(operands[i])
+				high, ok2 := code(operands[i+1])
+				if !ok1 || !ok2 || high < low || high-low > 0xFFFF {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					runes := []rune(unicode(dst))
+					if len(runes) == 0 {
+						continue
+					}
+					// The last character counts up with the code
+					last := runes[len(runes)-1]
+					for c := low; c <= high; c++ {
+						runes[len(runes)-1] = last + rune(c-low)
+						mapping[c] = string(runes)
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if low+j <= high {
+							mapping[low+j] = unicode(item)
+						}
+					}
+				}
+			}
+		}
+		if strings.HasPrefix(string(keyword), "end") || strings.HasPrefix(string(keyword), "begin") {
+			operands = operands[:0]
+		}
+	}
+}
+
+// winAnsiEncoding is Windows code page 1252, the encoding of most simple
+// fonts
+var winAnsiEncoding = func() [256]rune {
+	var encoding [256]rune
+	for c := 0x20; c < 0x7F; c++ {
+		encoding[c] = rune(c)
+	}
+	for c := 0xA0; c <= 0xFF; c++ {
+		encoding[c] = rune(c)
+	}
+	for i, r := range []rune("€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ") {
+		encoding[0x80+i] = r
+	}
+	return encoding
+}()
+
+// glyphNames are the Adobe glyph names of punctuation and accented letters
+// likely on the title page of a score
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$',
+	"percent": '%', "ampersand": '&', "quotesingle": '\'', "quoteright": '’',
+	"parenleft": '(', "parenright": ')', "asterisk": '*', "plus": '+', "comma": ',',
+	"hyphen": '-', "period": '.', "slash": '/', "colon": ':', "semicolon": ';',
+	"less": '<', "equal": '=', "greater": '>', "question": '?', "at": '@',
+	"bracketleft": '[', "backslash": '\\', "bracketright": ']', "underscore": '_',
+	"quoteleft": '‘', "braceleft": '{', "bar": '|', "braceright": '}',
+	"endash": '–', "emdash": '—', "quotedblleft": '“', "quotedblright": '”',
+	"bullet": '•', "ellipsis": '…', "copyright": '©', "registered": '®',
+	"trademark": '™', "degree": '°', "germandbls": 'ß',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"aacute": 'á', "agrave": 'à', "acircumflex": 'â', "adieresis": 'ä',
+	"eacute": 'é', "egrave": 'è', "ecircumflex": 'ê', "edieresis": 'ë',
+	"iacute": 'í', "igrave": 'ì', "icircumflex": 'î', "idieresis": 'ï',
+	"oacute": 'ó', "ograve": 'ò', "ocircumflex": 'ô', "odieresis": 'ö',
+	"uacute": 'ú', "ugrave": 'ù', "ucircumflex": 'û', "udieresis": 'ü',
+	"ntilde": 'ñ', "ccedilla": 'ç', "Eacute": 'É', "Aacute": 'Á',
+	"flat": '♭', "sharp": '♯', "natural": '♮',
+}
+
+// glyphRune returns the character of a glyph name such as "A", "eacute"
+// or "uni00E9"
+func glyphRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		if hexCode, ok := strings.CutPrefix(name, prefix); ok && len(hexCode) >= 4 && len(hexCode) <= 6 {
+			if n, err := strconv.ParseUint(hexCode[:4], 16, 32); err == nil {
+				return rune(n), true
+			}
+		}
+	}
+	return 0, false
+}
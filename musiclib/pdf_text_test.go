@@ -0,0 +1,50 @@
+package musiclib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFirstPageTextData(t *testing.T) {
+	octavo := []TextLine{
+		{Text: "SHENANDOAH", Size: 24, Top: 76},
+		{Text: "SATB and Piano", Size: 11, Top: 116},
+		{Text: "Music by FRANZ BIEBL Arranged by Mac Huff", Size: 11, Top: 130},
+		{Text: "Duration: ca. 3:30", Size: 9, Top: 156},
+		{Text: "© 2019 Hal Leonard. All Rights Reserved.", Size: 8, Top: 696},
+	}
+	tests := []struct {
+		name   string
+		data   []byte
+		want   []TextLine
+		wantOK bool
+	}{
+		{"compressed content stream", readTestdata(t, "octavo.pdf"), octavo, true},
+		{"repaired cross-reference table", readTestdata(t, "bad_offset.pdf"), octavo, true},
+		{"page without contents", readTestdata(t, "word_export.pdf"), nil, true},
+		{"not a PDF", []byte("%!PS-Adobe-3.0"), nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FirstPageTextData(tt.data)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("FirstPageTextData() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FirstPageTextData() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzFirstPageTextData(f *testing.F) {
+	for _, name := range []string{"octavo.pdf", "word_export.pdf", "xref_stream.pdf"} {
+		f.Add(readTestdata(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		lines, err := FirstPageTextData(data)
+		if err != nil && lines != nil {
+			t.Errorf("FirstPageTextData() returned lines with error %v", err)
+		}
+	})
+}
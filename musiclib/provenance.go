@@ -0,0 +1,115 @@
+package musiclib
+
+import (
+	"fmt"
+)
+
+// Sources a field value can come from
+const (
+	SourceFilename     = "filename"
+	SourceFolderRule   = "folder rule"
+	SourceFilesystem   = "filesystem"
+	SourceFileContents = "file contents"
+	SourceLexicon      = "composer lexicon"
+	SourcePDFMetadata  = "PDF metadata"
+	SourcePageText     = "first page text"
+	SourceAudioTags    = "audio tags"
+	SourceScore        = "score"
+	SourceOverride     = "manual override"
+	SourceDerived      = "derived"
+	SourceDefault      = "default"
+)
+
+// ProvenanceColumns is the schema of the field_provenance table, which
+// keeps where the final value of each field of each file came from
+const ProvenanceColumns = "path TEXT, field TEXT, value TEXT, source TEXT, detail TEXT, confidence DOUBLE"
+
+// Provenance records one step of a field's derivation: the value it was
+// given, the source it came from, which rule or token produced it and how
+// much the value can be trusted
+type Provenance struct {
+	Field      string  `json:"field"`
+	Value      string  `json:"value"`
+	Source     string  `json:"source"`
+	Detail     string  `json:"detail"`
+	Confidence float64 `json:"confidence"`
+}
+
+// String describes the step for explain output
+func (p Provenance) String() string {
+	return fmt.Sprintf("%s = %q from %s: %s (confidence %.2f)", p.Field, p.Value, p.Source, p.Detail, p.Confidence)
+}
+
+// ProvenanceLog lists the derivation steps of a file's fields in the order
+// they were taken. A later step for a field replaces its earlier value.
+type ProvenanceLog []Provenance
+
+// Record adds a derivation step
+func (l *ProvenanceLog) Record(field, value, source, detail string, confidence float64) {
+	*l = append(*l, Provenance{Field: field, Value: value, Source: source, Detail: detail, Confidence: confidence})
+}
+
+// Final returns the last step of each field, in the order the fields were
+// first recorded
+func (l ProvenanceLog) Final() []Provenance {
+	index := make(map[string]int)
+	var final []Provenance
+	for _, step := range l {
+		if i, ok := index[step.Field]; ok {
+			final[i] = step
+			continue
+		}
+		index[step.Field] = len(final)
+		final = append(final, step)
+	}
+	return final
+}
+
+// Field returns the last step recorded for a field
+func (l ProvenanceLog) Field(field string) (Provenance, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].Field == field {
+			return l[i], true
+		}
+	}
+	return Provenance{}, false
+}
+
+// ResetProvenanceTable recreates the field_provenance table, which is
+// rebuilt with the catalog on every scan
+func ResetProvenanceTable(db LibraryStore) error {
+	if _, err := db.Exec("DROP TABLE IF EXISTS field_provenance"); err != nil {
+		return fmt.Errorf("error dropping field_provenance table: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE field_provenance (" + ProvenanceColumns + ")"); err != nil {
+		return fmt.Errorf("error creating field_provenance table: %v", err)
+	}
+	return nil
+}
+
+// SaveProvenance writes the final provenance of each field of the files,
+// keyed by their path below the library root
+func SaveProvenance(db LibraryStore, logs map[string]ProvenanceLog) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error saving field_provenance: %v", err)
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare("INSERT INTO field_provenance VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("error saving field_provenance: %v", err)
+	}
+	defer statement.Close()
+
+	for path, log := range logs {
+		for _, step := range log.Final() {
+			_, err := statement.Exec(path, step.Field, step.Value, step.Source, step.Detail, step.Confidence)
+			if err != nil {
+				return fmt.Errorf("error adding provenance of '%s' to field_provenance: %v", path, err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,110 @@
+package musiclib
+
+import (
+	"reflect"
+	"testing"
+)
+
+// wonderfulLog is how the composer "Wonderful" came about before it was
+// corrected by a librarian
+func wonderfulLog() ProvenanceLog {
+	var log ProvenanceLog
+	log.Record("song title", "The Most", SourceFilename, "token 1 of title_voicing_composer", 0.5)
+	log.Record("composer or arranger", "Wonderful", SourceFilename, "token 3 of title_voicing_composer", 0.5)
+	log.Record("song title", "The Most Wonderful Day of the Year", SourceDerived, "title normalizer", 0.9)
+	log.Record("composer or arranger", "Huff", SourceOverride, "matched by path", 1)
+	return log
+}
+
+func TestProvenanceLogFinal(t *testing.T) {
+	tests := []struct {
+		name string
+		log  ProvenanceLog
+		want []Provenance
+	}{
+		{
+			name: "later steps replace earlier ones in first-recorded order",
+			log:  wonderfulLog(),
+			want: []Provenance{
+				{Field: "song title", Value: "The Most Wonderful Day of the Year", Source: SourceDerived, Detail: "title normalizer", Confidence: 0.9},
+				{Field: "composer or arranger", Value: "Huff", Source: SourceOverride, Detail: "matched by path", Confidence: 1},
+			},
+		},
+		{name: "empty", log: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.log.Final(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Final() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvenanceLogField(t *testing.T) {
+	log := wonderfulLog()
+	tests := []struct {
+		field  string
+		want   string
+		wantOK bool
+	}{
+		{"composer or arranger", `composer or arranger = "Huff" from manual override: matched by path (confidence 1.00)`, true},
+		{"song title", `song title = "The Most Wonderful Day of the Year" from derived: title normalizer (confidence 0.90)`, true},
+		{"voicing", "", false},
+	}
+	for _, tt := range tests {
+		step, ok := log.Field(tt.field)
+		if ok != tt.wantOK || (ok && step.String() != tt.want) {
+			t.Errorf("Field(%q) = %q, %v, want %q, %v", tt.field, step, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSaveProvenance(t *testing.T) {
+	var voicing ProvenanceLog
+	voicing.Record("voicing", "SSA", SourceFilename, `"SSA" in token 2`, 1)
+	logs := map[string]ProvenanceLog{
+		"2016 Christmas/TheMostWonderfulDayOfTheYear_SATB_Wonderful.pdf": wonderfulLog(),
+		"2014 Christmas/TaylorTheLatteBoy_SSA_Huff.pdf":                  voicing,
+	}
+	want := [][]interface{}{
+		{"2014 Christmas/TaylorTheLatteBoy_SSA_Huff.pdf", "voicing", "SSA", SourceFilename, `"SSA" in token 2`, 1.0},
+		{"2016 Christmas/TheMostWonderfulDayOfTheYear_SATB_Wonderful.pdf", "composer or arranger", "Huff", SourceOverride, "matched by path", 1.0},
+		{"2016 Christmas/TheMostWonderfulDayOfTheYear_SATB_Wonderful.pdf", "song title", "The Most Wonderful Day of the Year", SourceDerived, "title normalizer", 0.9},
+	}
+
+	for backend, store := range openTestStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			// Saved twice, as two scans would, without keeping the first
+			for scan := 0; scan < 2; scan++ {
+				if err := ResetProvenanceTable(store); err != nil {
+					t.Fatal(err)
+				}
+				if err := SaveProvenance(store, logs); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rows, err := store.Query("SELECT path, field, value, source, detail, confidence FROM field_provenance ORDER BY path, field")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got [][]interface{}
+			for rows.Next() {
+				var path, field, value, source, detail string
+				var confidence float64
+				if err := rows.Scan(&path, &field, &value, &source, &detail, &confidence); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, []interface{}{path, field, value, source, detail, confidence})
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("field_provenance = %v, want %v", got, want)
+			}
+		})
+	}
+}
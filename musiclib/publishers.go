@@ -0,0 +1,145 @@
+package musiclib
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PublisherRule is one entry of the publisher table in config.yml. Pattern
+// is a regular expression matched against the filename without its
+// extension; its "catalog" group is the catalog number, and the text of
+// every named group is removed from the title. Kind is "publisher" for a
+// music publisher, "source" for a site the file came from, or empty for a
+// catalog number format without a known publisher.
+type PublisherRule struct {
+	Name    string `yaml:"name"`
+	Kind    string `yaml:"kind"`
+	Pattern string `yaml:"pattern"`
+}
+
+// DefaultPublisherRules returns the publishers and sources found in the
+// library before a table was configured
+func DefaultPublisherRules() []PublisherRule {
+	return []PublisherRule{
+		{Name: "Free-scores.com", Kind: "source", Pattern: `(?i)^(?P<marker>\[free-scores\.com\])(?:.*[-_ ](?P<catalog>\d+)$)?`},
+		{Name: "IMSLP", Kind: "source", Pattern: `(?P<marker>IMSLP)(?P<catalog>\d+)`},
+		{Name: "WIMA", Kind: "source", Pattern: `(?P<marker>WIMA)\.(?P<catalog>\d+)`},
+		{Name: "Scribd", Kind: "source", Pattern: `^(?P<catalog>\d{8,9})-`},
+		{Name: "Perusal", Kind: "source", Pattern: `(?i)(?P<marker>(?:[-+_ ]+octavo)?[-+_ ]+perusal)$`},
+		{Name: "Musicnotes", Kind: "publisher", Pattern: `(?:^|[^A-Za-z0-9])(?P<catalog>MN\d{7})(?P<suffix>_D\d+)?`},
+		{Name: "Hal Leonard", Kind: "publisher", Pattern: `(?:^|[^A-Za-z0-9])(?P<marker>HL[-_ ]?)?(?P<catalog>0\d{7})(?:[^0-9]|$)`},
+		{Name: "Alfred Music", Kind: "publisher", Pattern: `(?:^|[^0-9])(?P<catalog>00-\d{5})(?:[^0-9]|$)`},
+		{Name: "Hinshaw Music", Kind: "publisher", Pattern: `(?:^|[^A-Za-z])(?P<catalog>HMC[-_ ]?\d{3,4})(?:[^0-9]|$)`},
+		{Name: "Santa Barbara Music Publishing", Kind: "publisher", Pattern: `(?:^|[^A-Za-z])(?P<catalog>SBMP[-_ ]?\d{2,4})(?:[^0-9]|$)`},
+		{Name: "Walton Music", Kind: "publisher", Pattern: `(?:^|[^A-Za-z])(?P<catalog>W[WLJ][-_ ]?\d{4})(?:[^0-9]|$)`},
+		{Name: "", Kind: "", Pattern: `(?:^|[^A-Za-z])(?P<voicing>[SATB]{2,8})[-_ ](?P<catalog>\d{1,3}-\d{1,3})$`},
+	}
+}
+
+// PublisherMatch is what the publisher table found in one filename
+type PublisherMatch struct {
+	Publisher     string
+	Source        string
+	CatalogNumber string
+}
+
+// PublisherRecognizer finds publishers, sources and catalog numbers in
+// filenames using the publisher table
+type PublisherRecognizer struct {
+	rules []compiledPublisherRule
+}
+
+type compiledPublisherRule struct {
+	PublisherRule
+	re *regexp.Regexp
+}
+
+// NewPublisherRecognizer compiles the publisher table
+func NewPublisherRecognizer(rules []PublisherRule) (*PublisherRecognizer, error) {
+	recognizer := &PublisherRecognizer{}
+	for _, rule := range rules {
+		switch rule.Kind {
+		case "publisher", "source", "":
+		default:
+			return nil, fmt.Errorf("error in publisher rule '%s': unknown kind '%s'", rule.Name, rule.Kind)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling publisher rule '%s': %v", rule.Name, err)
+		}
+		recognizer.rules = append(recognizer.rules, compiledPublisherRule{PublisherRule: rule, re: re})
+	}
+	return recognizer, nil
+}
+
+// Recognize returns the publishers, sources and catalog numbers of every
+// rule that matches text. Several matches are joined with ", ", as in the
+// IMSLP copy of a WIMA edition.
+func (r *PublisherRecognizer) Recognize(text string) PublisherMatch {
+	var publishers, sources, catalogNumbers []string
+
+	for _, rule := range r.rules {
+		groups := rule.re.FindStringSubmatch(text)
+		if groups == nil {
+			continue
+		}
+		switch rule.Kind {
+		case "publisher":
+			publishers = append(publishers, rule.Name)
+		case "source":
+			sources = append(sources, rule.Name)
+		}
+		if i := rule.re.SubexpIndex("catalog"); i > 0 && groups[i] != "" {
+			catalogNumbers = append(catalogNumbers, groups[i])
+		}
+	}
+
+	return PublisherMatch{
+		Publisher:     strings.Join(publishers, ", "),
+		Source:        strings.Join(sources, ", "),
+		CatalogNumber: strings.Join(catalogNumbers, ", "),
+	}
+}
+
+// StripFromTitle removes the text of the rules' named groups from a raw
+// title, so "[Free-scores.com]_traditional-amazing-grace-2984" becomes
+// "traditional-amazing-grace". The title is returned unchanged when nothing
+// would be left of it.
+func (r *PublisherRecognizer) StripFromTitle(title string) string {
+	type span struct{ start, end int }
+	var spans []span
+
+	for _, rule := range r.rules {
+		loc := rule.re.FindStringSubmatchIndex(title)
+		if loc == nil {
+			continue
+		}
+		for i, name := range rule.re.SubexpNames() {
+			if name != "" && loc[2*i] >= 0 && loc[2*i+1] > loc[2*i] {
+				spans = append(spans, span{loc[2*i], loc[2*i+1]})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return title
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start > last {
+			b.WriteString(title[last:s.start])
+		}
+		last = max(last, s.end)
+	}
+	b.WriteString(title[last:])
+
+	stripped := strings.Trim(b.String(), " _.-+")
+	if stripped == "" {
+		return title
+	}
+	return stripped
+}
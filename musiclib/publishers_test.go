@@ -0,0 +1,61 @@
+package musiclib
+
+import "testing"
+
+func TestPublisherRecognizer(t *testing.T) {
+	recognizer, err := NewPublisherRecognizer(DefaultPublisherRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		filename  string
+		want      PublisherMatch
+		wantTitle string
+	}{
+		{"[Free-scores.com]_traditional-amazing-grace-2984", PublisherMatch{Source: "Free-scores.com", CatalogNumber: "2984"}, "traditional-amazing-grace"},
+		{"IMSLP132203-WIMA.2032-Palestrina_Adoramus_te", PublisherMatch{Source: "IMSLP, WIMA", CatalogNumber: "132203, 2032"}, "Palestrina_Adoramus_te"},
+		{"165042971-Runaround-Sue", PublisherMatch{Source: "Scribd", CatalogNumber: "165042971"}, "Runaround-Sue"},
+		{"Let+us+Drink+and+be+Merry+-+Octavo+-+Perusal", PublisherMatch{Source: "Perusal"}, "Let+us+Drink+and+be+Merry"},
+		{"Only Us - Eb Major - MN0174554_D3", PublisherMatch{Publisher: "Musicnotes", CatalogNumber: "MN0174554"}, "Only Us - Eb Major"},
+		{"Seasons_of_Love_HL_08745163", PublisherMatch{Publisher: "Hal Leonard", CatalogNumber: "08745163"}, "Seasons_of_Love"},
+		{"Shenandoah_00-12345", PublisherMatch{Publisher: "Alfred Music", CatalogNumber: "00-12345"}, "Shenandoah"},
+		{"Alleluia_HMC-1234", PublisherMatch{Publisher: "Hinshaw Music", CatalogNumber: "HMC-1234"}, "Alleluia"},
+		{"Hope SBMP 512", PublisherMatch{Publisher: "Santa Barbara Music Publishing", CatalogNumber: "SBMP 512"}, "Hope"},
+		{"Glory WW1234", PublisherMatch{Publisher: "Walton Music", CatalogNumber: "WW1234"}, "Glory"},
+		// An octavo number after the voicing, with no known publisher
+		{"Bandstand-SATB-2-9", PublisherMatch{CatalogNumber: "2-9"}, "Bandstand"},
+		{"AveMaria_Biebl", PublisherMatch{}, "AveMaria_Biebl"},
+		// Nothing would be left of the title
+		{"[Free-scores.com]", PublisherMatch{Source: "Free-scores.com"}, "[Free-scores.com]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			if got := recognizer.Recognize(tt.filename); got != tt.want {
+				t.Errorf("Recognize() = %+v, want %+v", got, tt.want)
+			}
+			if got := recognizer.StripFromTitle(tt.filename); got != tt.wantTitle {
+				t.Errorf("StripFromTitle() = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestNewPublisherRecognizer(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   PublisherRule
+		wantOK bool
+	}{
+		{"publisher", PublisherRule{Name: "GIA Publications", Kind: "publisher", Pattern: `(?P<catalog>G-\d{4})`}, true},
+		{"catalog number format", PublisherRule{Pattern: `(?P<catalog>\d{3}-\d{3})$`}, true},
+		{"unknown kind", PublisherRule{Name: "GIA Publications", Kind: "label", Pattern: `G-\d{4}`}, false},
+		{"bad pattern", PublisherRule{Name: "GIA Publications", Kind: "publisher", Pattern: `(G-\d{4}`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPublisherRecognizer([]PublisherRule{tt.rule}); (err == nil) != tt.wantOK {
+				t.Errorf("NewPublisherRecognizer() error = %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
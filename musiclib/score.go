@@ -0,0 +1,230 @@
+package musiclib
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScorePartsColumns is the schema of the score_parts table, which holds the
+// range of each part of each score file keyed by its path below the library
+// root. Voice is S, A, T or B for a vocal part and blank for the others.
+const ScorePartsColumns = "path TEXT, part TEXT, voice TEXT, lowest TEXT, highest TEXT, lowest_midi INTEGER, highest_midi INTEGER"
+
+// confidenceScoreParts is the confidence of a voicing implied by the names
+// of a score's parts
+const confidenceScoreParts = 0.9
+
+// Pitch is a MIDI note number, middle C (C4) being 60
+type Pitch int
+
+var (
+	pitchNames = []string{"C", "C#", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B"}
+	pitchSteps = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+	pitchWord  = regexp.MustCompile(`^([A-Ga-g])(#|b|♯|♭)?(-?\d)$`)
+)
+
+// String writes a pitch in scientific pitch notation, such as "G5" or "F#4"
+func (p Pitch) String() string {
+	n := int(p)
+	return pitchNames[(n%12+12)%12] + strconv.Itoa(n/12-1)
+}
+
+// ParsePitch reads a pitch in scientific pitch notation, such as "G5",
+// "Bb3" or "F#4"
+func ParsePitch(s string) (Pitch, bool) {
+	groups := pitchWord.FindStringSubmatch(strings.TrimSpace(s))
+	if groups == nil {
+		return 0, false
+	}
+	octave, _ := strconv.Atoi(groups[3])
+	step := pitchSteps[strings.ToUpper(groups[1])[0]]
+	switch groups[2] {
+	case "#", "♯":
+		step++
+	case "b", "♭":
+		step--
+	}
+	return Pitch((octave+1)*12 + step), true
+}
+
+// ScorePart is one part of a score and the range of its notes
+type ScorePart struct {
+	Name    string
+	Lowest  Pitch
+	Highest Pitch
+	Notes   int
+}
+
+// addNote widens the range of a part to take in a note
+func (p *ScorePart) addNote(pitch Pitch) {
+	if p.Notes == 0 || pitch < p.Lowest {
+		p.Lowest = pitch
+	}
+	if p.Notes == 0 || pitch > p.Highest {
+		p.Highest = pitch
+	}
+	p.Notes++
+}
+
+// Range writes the range of a part as "D4-G5", or "" for a part without
+// notes
+func (p ScorePart) Range() string {
+	if p.Notes == 0 {
+		return ""
+	}
+	return p.Lowest.String() + "-" + p.Highest.String()
+}
+
+// ScoreInfo is what a score reader reads from a file. Time is the first
+// time signature, as in "6/8", and Measures the length of the longest part.
+// Tempos are the tempos in beats per minute, in the order they change, and
+// Duration the playing time, for formats that give them. Part is the part
+// a rehearsal file is for, where its tracks single one out, and Pages the
+// page count, for formats that store one.
+type ScoreInfo struct {
+	Format    string
+	Title     string
+	Composer  string
+	Arranger  string
+	Lyricist  string
+	Copyright string
+	Key       Key
+	Time      string
+	Tempos    []float64
+	Duration  time.Duration
+	Measures  int
+	Pages     int
+	Parts     []ScorePart
+	Part      string
+}
+
+// FormatTempo writes the tempo as "96 bpm", or as the slowest and fastest
+// tempos, as in "72-96 bpm", when it changes
+func (s ScoreInfo) FormatTempo() string {
+	if len(s.Tempos) == 0 {
+		return ""
+	}
+	slowest, fastest := slices.Min(s.Tempos), slices.Max(s.Tempos)
+	if math.Round(slowest) == math.Round(fastest) {
+		return fmt.Sprintf("%.0f bpm", slowest)
+	}
+	return fmt.Sprintf("%.0f-%.0f bpm", slowest, fastest)
+}
+
+var (
+	voicePartName = regexp.MustCompile(`(?i)^(?:(sopranos?|sopr?|s|trebles?|descant)|(altos?|contralto|mezzo(?:[ -]soprano)?|a)|(tenors?|ten|t)|(bass|basses|baritones?|bari?|b))\.?(?:\s*(?:\d|i{1,3}|iv)\.?)?$`)
+	partNameSplit = regexp.MustCompile(`(?i)\s*(?:/|&|\+|,|\band\b)\s*`)
+)
+
+// PartVoices returns the voices a part is for, such as "S" for "Soprano 1"
+// or "TB" for "Tenor/Bass". Parts for instruments have none.
+func PartVoices(name string) string {
+	var voices strings.Builder
+	for _, piece := range partNameSplit.Split(strings.TrimSpace(name), -1) {
+		groups := voicePartName.FindStringSubmatch(piece)
+		if groups == nil {
+			// "T. B." and "S.A." name two voices of one staff
+			if letters := strings.ReplaceAll(strings.ReplaceAll(piece, ".", ""), " ", ""); len(letters) > 1 && isLetterVoicing(letters) {
+				voices.WriteString(letters)
+			}
+			continue
+		}
+		for i, voice := range []string{"S", "A", "T", "B"} {
+			if groups[i+1] != "" {
+				voices.WriteString(voice)
+			}
+		}
+	}
+	return voices.String()
+}
+
+// Voicing returns the voicing implied by the names of a score's vocal
+// parts, such as SATB for parts named Soprano, Alto, Tenor and Bass, or
+// SSA for Soprano 1, Soprano 2 and Alto. A single vocal part, as in a
+// rehearsal file for one voice, implies none.
+func (s ScoreInfo) Voicing() VoicingResult {
+	counts := make(map[byte]int)
+	var names []string
+	for _, part := range s.Parts {
+		voices := PartVoices(part.Name)
+		for i := 0; i < len(voices); i++ {
+			counts[voices[i]]++
+		}
+		if voices != "" {
+			names = append(names, part.Name)
+		}
+	}
+
+	var letters strings.Builder
+	for _, voice := range []byte("SATB") {
+		letters.WriteString(strings.Repeat(string(voice), counts[voice]))
+	}
+	voicing := Voicing(letters.String())
+	if !voicing.Valid() {
+		return VoicingResult{Voicing: VoicingUnknown}
+	}
+	return VoicingResult{Voicing: voicing, Confidence: confidenceScoreParts, Matched: strings.Join(names, ", ")}
+}
+
+// FormatRanges writes the range of each part with notes, as in
+// "Soprano D4-G5; Alto A3-D5"
+func (s ScoreInfo) FormatRanges() string {
+	var ranges []string
+	for _, part := range s.Parts {
+		if part.Notes > 0 {
+			ranges = append(ranges, part.Name+" "+part.Range())
+		}
+	}
+	return strings.Join(ranges, "; ")
+}
+
+// ResetScorePartsTable recreates the score_parts table, which is rebuilt
+// with the catalog on every scan
+func ResetScorePartsTable(db LibraryStore) error {
+	if _, err := db.Exec("DROP TABLE IF EXISTS score_parts"); err != nil {
+		return fmt.Errorf("error dropping score_parts table: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE score_parts (" + ScorePartsColumns + ")"); err != nil {
+		return fmt.Errorf("error creating score_parts table: %v", err)
+	}
+	return nil
+}
+
+// SaveScoreParts writes the range of each part of score files, keyed by
+// their path below the library root
+func SaveScoreParts(db LibraryStore, scores map[string]ScoreInfo) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error saving score_parts: %v", err)
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare("INSERT INTO score_parts VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("error saving score_parts: %v", err)
+	}
+	defer statement.Close()
+
+	for path, score := range scores {
+		for _, part := range score.Parts {
+			if part.Notes == 0 {
+				continue
+			}
+			voice := PartVoices(part.Name)
+			if len(voice) != 1 {
+				voice = ""
+			}
+			_, err := statement.Exec(path, part.Name, voice, part.Lowest.String(), part.Highest.String(), int(part.Lowest), int(part.Highest))
+			if err != nil {
+				return fmt.Errorf("error adding '%s' to score_parts: %v", path, err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,292 @@
+package musiclib
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// scoreKeyExtensions are the score formats a key signature can be read
+// from, in the order sibling files are tried
+var scoreKeyExtensions = []string{".musicxml", ".mxl", ".xml", ".mscz", ".mscx", ".mid", ".midi", ".abc", ".ly"}
+
+// scoreKeyReaders read the key of the score formats that are read whole
+var scoreKeyReaders = map[string]func(string) (ScoreInfo, error){
+	".mscz": ReadMuseScore,
+	".mscx": ReadMuseScore,
+	".abc":  ReadABC,
+	".ly":   ReadLilyPond,
+}
+
+// errNoKeySignature is returned for a score that has no key signature
+var errNoKeySignature = errors.New("no key signature")
+
+// ReadScoreKey reads the first key signature of a MusicXML, compressed
+// MusicXML (.mxl), MuseScore, MIDI, ABC or LilyPond file
+func ReadScoreKey(filename string) (KeyResult, error) {
+	var key Key
+	var err error
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".musicxml", ".xml":
+		key, err = readMusicXMLFileKey(filename)
+	case ".mxl":
+		key, err = readMXLKey(filename)
+	case ".mscz", ".mscx", ".abc", ".ly":
+		var score ScoreInfo
+		if score, err = scoreKeyReaders[strings.ToLower(filepath.Ext(filename))](filename); err == nil && score.Key.Tonic == "" {
+			err = errNoKeySignature
+		}
+		key = score.Key
+	case ".mid", ".midi":
+		key, err = readMIDIFileKey(filename)
+	default:
+		return KeyResult{}, fmt.Errorf("error reading key of '%s': not a score file", filename)
+	}
+	if err != nil {
+		return KeyResult{}, fmt.Errorf("error reading key of '%s': %v", filename, err)
+	}
+
+	return KeyResult{Key: key, Confidence: confidenceKeySignature, Matched: filepath.Base(filename)}, nil
+}
+
+// FindScoreKey reads the key of filePath if it is a score file, and
+// otherwise of a score file next to it with the same name, so
+// "JingleBells.pdf" takes its key from "JingleBells.musicxml"
+func FindScoreKey(filePath string) (KeyResult, bool) {
+	if result, err := ReadScoreKey(filePath); err == nil {
+		return result, true
+	}
+
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	for _, ext := range scoreKeyExtensions {
+		candidate := base + ext
+		if candidate == filePath {
+			continue
+		}
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		if result, err := ReadScoreKey(candidate); err == nil {
+			return result, true
+		}
+	}
+
+	return KeyResult{}, false
+}
+
+func readMusicXMLFileKey(filename string) (Key, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return Key{}, err
+	}
+	defer file.Close()
+
+	return readMusicXMLKey(file)
+}
+
+// readMusicXMLKey returns the first <key> element with a <fifths> count
+func readMusicXMLKey(r io.Reader) (Key, error) {
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return Key{}, errNoKeySignature
+		}
+		if err != nil {
+			return Key{}, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "key" {
+			continue
+		}
+		var element struct {
+			Fifths *int   `xml:"fifths"`
+			Mode   string `xml:"mode"`
+		}
+		if err := decoder.DecodeElement(&element, &start); err != nil {
+			return Key{}, err
+		}
+		if element.Fifths == nil {
+			continue
+		}
+		if key, ok := KeyFromSignature(*element.Fifths, element.Mode); ok {
+			return key, nil
+		}
+	}
+}
+
+// readMXLKey reads the key of the score named in the container of a
+// compressed MusicXML file
+func readMXLKey(filename string) (Key, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return Key{}, err
+	}
+	defer archive.Close()
+
+	file, err := zipRootFile(&archive.Reader, ".xml", ".musicxml")
+	if err != nil {
+		return Key{}, err
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return Key{}, err
+	}
+	defer reader.Close()
+
+	return readMusicXMLKey(reader)
+}
+
+// zipRootFile finds the score in an .mxl or .mscz archive, using
+// META-INF/container.xml when present and otherwise the first file with
+// one of extensions outside META-INF
+func zipRootFile(archive *zip.Reader, extensions ...string) (*zip.File, error) {
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	if container, ok := files["META-INF/container.xml"]; ok {
+		reader, err := container.Open()
+		if err != nil {
+			return nil, err
+		}
+		var parsed struct {
+			RootFiles []struct {
+				FullPath string `xml:"full-path,attr"`
+			} `xml:"rootfiles>rootfile"`
+		}
+		err = xml.NewDecoder(reader).Decode(&parsed)
+		reader.Close()
+		if err == nil {
+			for _, rootFile := range parsed.RootFiles {
+				file, ok := files[rootFile.FullPath]
+				if ok && slices.Contains(extensions, strings.ToLower(filepath.Ext(file.Name))) {
+					return file, nil
+				}
+			}
+		}
+	}
+
+	for _, file := range archive.File {
+		ext := strings.ToLower(filepath.Ext(file.Name))
+		if !strings.HasPrefix(file.Name, "META-INF/") && slices.Contains(extensions, ext) {
+			return file, nil
+		}
+	}
+	return nil, errors.New("no score in archive")
+}
+
+func readMIDIFileKey(filename string) (Key, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Key{}, err
+	}
+	return readMIDIKey(data)
+}
+
+// readMIDIKey returns the first key signature meta event (FF 59) of a
+// Standard MIDI File
+func readMIDIKey(data []byte) (Key, error) {
+	if len(data) < 14 || string(data[:4]) != "MThd" {
+		return Key{}, errors.New("not a standard MIDI file")
+	}
+
+	pos := 8 + int(binary.BigEndian.Uint32(data[4:8]))
+	for pos+8 <= len(data) {
+		chunkType := string(data[pos : pos+4])
+		length := int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		pos += 8
+		end := min(pos+length, len(data))
+		if chunkType == "MTrk" {
+			if key, ok := midiTrackKey(data[pos:end]); ok {
+				return key, nil
+			}
+		}
+		pos = end
+	}
+
+	return Key{}, errNoKeySignature
+}
+
+// midiTrackKey walks the events of one track looking for a key signature
+func midiTrackKey(track []byte) (Key, bool) {
+	pos := 0
+	var status byte
+
+	for pos < len(track) {
+		if _, n := readVarLen(track[pos:]); n > 0 {
+			pos += n
+		} else {
+			return Key{}, false
+		}
+		if pos >= len(track) {
+			break
+		}
+
+		if track[pos]&0x80 != 0 {
+			status = track[pos]
+			pos++
+		}
+
+		switch {
+		case status == 0xFF:
+			if pos >= len(track) {
+				return Key{}, false
+			}
+			metaType := track[pos]
+			length, n := readVarLen(track[pos+1:])
+			if n == 0 {
+				return Key{}, false
+			}
+			dataStart := pos + 1 + n
+			if metaType == 0x59 && length >= 2 && dataStart+2 <= len(track) {
+				mode := "major"
+				if track[dataStart+1] == 1 {
+					mode = "minor"
+				}
+				return KeyFromSignature(int(int8(track[dataStart])), mode)
+			}
+			pos = dataStart + length
+		case status == 0xF0 || status == 0xF7:
+			length, n := readVarLen(track[pos:])
+			if n == 0 {
+				return Key{}, false
+			}
+			pos += n + length
+		case status&0xF0 == 0xC0 || status&0xF0 == 0xD0:
+			pos++
+		case status >= 0x80:
+			pos += 2
+		default:
+			return Key{}, false
+		}
+	}
+
+	return Key{}, false
+}
+
+// readVarLen reads a MIDI variable-length quantity, returning its value and
+// the number of bytes used, or 0 bytes if it is truncated
+func readVarLen(data []byte) (int, int) {
+	value := 0
+	for i := 0; i < len(data) && i < 4; i++ {
+		value = value<<7 | int(data[i]&0x7F)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
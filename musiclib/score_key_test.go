@@ -0,0 +1,62 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadScoreKey(t *testing.T) {
+	tests := []struct {
+		file   string
+		want   Key
+		wantOK bool
+	}{
+		{"satb_piano.musicxml", Key{Tonic: "G", Mode: "Major"}, true},
+		{"compressed.mxl", Key{Tonic: "G", Mode: "Major"}, true},
+		{"satb.mscz", Key{Tonic: "G", Mode: "Major"}, true},
+		{"satb.mscx", Key{Tonic: "G", Mode: "Major"}, true},
+		{"alto_featured.mid", Key{Tonic: "G", Mode: "Major"}, true},
+		{"format0.mid", Key{Tonic: "G", Mode: "Major"}, true},
+		{"water_is_wide.abc", Key{Tonic: "Eb", Mode: "Major"}, true},
+		{"shenandoah.ly", Key{Tonic: "Db", Mode: "Major"}, true},
+		{"octavo.pdf", Key{}, false},
+		{"missing.musicxml", Key{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := ReadScoreKey(filepath.Join("testdata", tt.file))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("ReadScoreKey() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if got.Key != tt.want {
+				t.Errorf("ReadScoreKey() = %v, want %v", got.Key, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindScoreKey(t *testing.T) {
+	dir := t.TempDir()
+	// Only the ABC file is there; the PDF it belongs to need not be read
+	abc := writeTemp(t, "WaterIsWide.abc", readTestdata(t, "water_is_wide.abc"))
+	pdf := strings.TrimSuffix(abc, ".abc") + ".pdf"
+	tests := []struct {
+		name      string
+		path      string
+		want      Key
+		wantMatch string
+	}{
+		{"score file", filepath.Join("testdata", "shenandoah.ly"), Key{Tonic: "Db", Mode: "Major"}, "shenandoah.ly"},
+		{"score next to the PDF", pdf, Key{Tonic: "Eb", Mode: "Major"}, "WaterIsWide.abc"},
+		{"no score", filepath.Join(dir, "Fruitcake.pdf"), Key{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FindScoreKey(tt.path)
+			if got.Key != tt.want || got.Matched != tt.wantMatch || ok != (tt.wantMatch != "") {
+				t.Errorf("FindScoreKey() = %v from %q, %v, want %v from %q", got.Key, got.Matched, ok, tt.want, tt.wantMatch)
+			}
+		})
+	}
+}
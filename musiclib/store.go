@@ -0,0 +1,179 @@
+package musiclib
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Backends a LibraryStore can keep the catalog in
+const (
+	BackendDuckDB = "duckdb"
+	BackendSQLite = "sqlite"
+	BackendMemory = "memory"
+)
+
+// LibraryStore is the SQL database the catalog is kept in. The scanner and
+// the CSV importers use it rather than a particular database, so the
+// catalog can be kept in DuckDB, SQLite or memory as config.yml says. Exec,
+// Query, QueryRow and Begin run the SQL of the table helpers of this
+// package, which every backend accepts, so a store is built on a
+// database/sql driver.
+type LibraryStore interface {
+	Backend() string
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Begin() (*sql.Tx, error)
+	CreateTable(tableName, columns string) error
+	InsertData(tableName string, data []interface{}) error
+	FetchAll(tableName, condition string) ([][]interface{}, error)
+	Columns(tableName string) ([]string, error)
+	ExecuteQuery(query string, params ...interface{}) error
+	Close() error
+}
+
+// OpenStore opens the store of a backend, as chosen by Config.Store. The
+// filename is ignored by the memory backend.
+func OpenStore(backend, filename string) (LibraryStore, error) {
+	switch backend {
+	case BackendDuckDB:
+		return opened(NewDuckDBStore(filename))
+	case BackendSQLite:
+		return opened(NewSQLiteStore(filename))
+	case BackendMemory:
+		return opened(NewMemoryStore())
+	}
+	return nil, fmt.Errorf("error opening database: unknown backend %q", backend)
+}
+
+// opened returns a store that opened as a LibraryStore, and a nil
+// LibraryStore, not one holding a nil pointer, for a store that did not
+func opened[S LibraryStore](store S, err error) (LibraryStore, error) {
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// sqlStore is a LibraryStore on a database/sql driver. The DuckDB, SQLite
+// and memory stores differ only in the driver and data source they open.
+type sqlStore struct {
+	backend    string
+	DbName     string
+	Connection *sql.DB
+}
+
+func openSQLStore(backend, driver, dbName string) (sqlStore, error) {
+	connection, err := sql.Open(driver, dbName)
+	if err != nil {
+		return sqlStore{}, fmt.Errorf("error connecting to database: %v", err)
+	}
+	if err := connection.Ping(); err != nil {
+		connection.Close()
+		return sqlStore{}, fmt.Errorf("error connecting to database '%s': %v", dbName, err)
+	}
+	return sqlStore{backend: backend, DbName: dbName, Connection: connection}, nil
+}
+
+// Backend names the kind of database the store keeps the catalog in
+func (s *sqlStore) Backend() string {
+	return s.backend
+}
+
+// Exec runs a statement that returns no rows
+func (s *sqlStore) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.Connection.Exec(query, args...)
+}
+
+// Query runs a statement that returns rows
+func (s *sqlStore) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.Connection.Query(query, args...)
+}
+
+// QueryRow runs a statement that returns at most one row
+func (s *sqlStore) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.Connection.QueryRow(query, args...)
+}
+
+// Begin starts a transaction
+func (s *sqlStore) Begin() (*sql.Tx, error) {
+	return s.Connection.Begin()
+}
+
+func (s *sqlStore) Close() error {
+	if s.Connection != nil {
+		return s.Connection.Close()
+	}
+	return nil
+}
+
+func (s *sqlStore) CreateTable(tableName, columns string) error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", tableName, columns)
+	_, err := s.Connection.Exec(query)
+	if err != nil {
+		return fmt.Errorf("error creating table: %v", err)
+	}
+	return nil
+}
+
+func (s *sqlStore) InsertData(tableName string, data []interface{}) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(data)), ",")
+	query := fmt.Sprintf("INSERT INTO %s VALUES (%s)", tableName, placeholders)
+	_, err := s.Connection.Exec(query, data...)
+	if err != nil {
+		return fmt.Errorf("error inserting into %s: %v", tableName, err)
+	}
+	return nil
+}
+
+// FetchAll returns every row of a table, the condition (such as "WHERE
+// voicing = 'SATB' ORDER BY sort_key") following its name in the query
+func (s *sqlStore) FetchAll(tableName, condition string) ([][]interface{}, error) {
+	query := fmt.Sprintf("SELECT * FROM %s %s", tableName, condition)
+	rows, err := s.Connection.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching data: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range columns {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, values)
+	}
+
+	return result, rows.Err()
+}
+
+// Columns returns the column names of a table in order
+func (s *sqlStore) Columns(tableName string) ([]string, error) {
+	rows, err := s.Connection.Query(fmt.Sprintf("SELECT * FROM %s LIMIT 0", tableName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading columns of %s: %v", tableName, err)
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+func (s *sqlStore) ExecuteQuery(query string, params ...interface{}) error {
+	_, err := s.Connection.Exec(query, params...)
+	if err != nil {
+		return fmt.Errorf("error executing query: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package musiclib
+
+import _ "github.com/marcboeker/go-duckdb"
+
+// DuckDBStore keeps the catalog in a DuckDB database file
+type DuckDBStore struct {
+	sqlStore
+}
+
+// NewDuckDBStore opens or creates a DuckDB database
+func NewDuckDBStore(dbName string) (*DuckDBStore, error) {
+	if dbName == "" {
+		dbName = "musiclibrary.duckdb"
+	}
+	store, err := openSQLStore(BackendDuckDB, "duckdb", dbName)
+	if err != nil {
+		return nil, err
+	}
+	return &DuckDBStore{store}, nil
+}
+
+// MemoryStore keeps the catalog in an in-memory DuckDB database, which is
+// gone when the store is closed. It suits trial scans and conversions that
+// should leave no database behind.
+type MemoryStore struct {
+	sqlStore
+}
+
+// NewMemoryStore opens an empty in-memory database. Every connection of
+// the pool shares the one database.
+func NewMemoryStore() (*MemoryStore, error) {
+	store, err := openSQLStore(BackendMemory, "duckdb", "")
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryStore{store}, nil
+}
@@ -0,0 +1,21 @@
+package musiclib
+
+import _ "github.com/mattn/go-sqlite3"
+
+// SQLiteStore keeps the catalog in a SQLite database file, as the Python
+// version of the app did
+type SQLiteStore struct {
+	sqlStore
+}
+
+// NewSQLiteStore opens or creates a SQLite database
+func NewSQLiteStore(dbName string) (*SQLiteStore, error) {
+	if dbName == "" {
+		dbName = "musiclibrary.db"
+	}
+	store, err := openSQLStore(BackendSQLite, "sqlite3", dbName)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{store}, nil
+}
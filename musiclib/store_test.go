@@ -0,0 +1,57 @@
+package musiclib
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOpenStore(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		backend  string
+		filename string
+		wantOK   bool
+	}{
+		{"DuckDB", BackendDuckDB, filepath.Join(dir, "library.duckdb"), true},
+		{"SQLite", BackendSQLite, filepath.Join(dir, "library.db"), true},
+		{"memory", BackendMemory, "", true},
+		{"memory ignores the filename", BackendMemory, filepath.Join(dir, "unused.duckdb"), true},
+		{"unknown backend", "postgres", filepath.Join(dir, "library.pg"), false},
+		{"DuckDB in a missing folder", BackendDuckDB, filepath.Join(dir, "missing", "library.duckdb"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := OpenStore(tt.backend, tt.filename)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("OpenStore() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if !tt.wantOK {
+				if store != nil {
+					t.Errorf("OpenStore() = %#v, want nil", store)
+				}
+				return
+			}
+			defer store.Close()
+
+			if got := store.Backend(); got != tt.backend {
+				t.Errorf("Backend() = %q, want %q", got, tt.backend)
+			}
+			if err := store.CreateTable("music_library", "id INTEGER, song_title TEXT"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.InsertData("music_library", []interface{}{1, "Ave Maria"}); err != nil {
+				t.Fatal(err)
+			}
+			columns, err := store.Columns("music_library")
+			if err != nil || !reflect.DeepEqual(columns, []string{"id", "song_title"}) {
+				t.Errorf("Columns() = %q, %v", columns, err)
+			}
+			var title string
+			if err := store.QueryRow("SELECT song_title FROM music_library WHERE id = ?", 1).Scan(&title); err != nil || title != "Ave Maria" {
+				t.Errorf("QueryRow() = %q, %v", title, err)
+			}
+		})
+	}
+}
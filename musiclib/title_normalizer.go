@@ -0,0 +1,426 @@
+package musiclib
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// TitleNormalizerConfig is the TitleNormalizer section of config.yml
+type TitleNormalizerConfig struct {
+	SmallWords   []string      `yaml:"small_words"`
+	Contractions []Contraction `yaml:"contractions"`
+}
+
+// Contraction restores an apostrophe that a filename could not hold. When
+// FirstWord or Before is set the word is only replaced in that context, so
+// "Its" becomes "It's" in "ItsASmallWorld" but stays possessive in
+// "TheWorldAndItsPeople".
+type Contraction struct {
+	Word        string   `yaml:"word"`
+	Replacement string   `yaml:"replacement"`
+	FirstWord   bool     `yaml:"first_word,omitempty"`
+	Before      []string `yaml:"before,omitempty"`
+}
+
+// DefaultTitleNormalizerConfig returns the small words and contractions used
+// before they were configured
+func DefaultTitleNormalizerConfig() TitleNormalizerConfig {
+	contractions := []Contraction{
+		{Word: "Its", Replacement: "It's", FirstWord: true, Before: []string{
+			"a", "an", "the", "not", "been", "beginning", "cold", "christmas", "time",
+			"only", "all", "over", "so", "just", "still", "too", "raining", "snowing", "gonna",
+		}},
+		{Word: "Lets", Replacement: "Let's", FirstWord: true},
+		{Word: "Twas", Replacement: "'Twas"},
+		{Word: "Oer", Replacement: "O'er"},
+		{Word: "Neath", Replacement: "'Neath"},
+	}
+	for _, word := range []string{
+		"Ain't", "Aren't", "Can't", "Couldn't", "Didn't", "Doesn't", "Don't", "Hasn't",
+		"Haven't", "Isn't", "Shouldn't", "Wasn't", "Weren't", "Won't", "Wouldn't",
+		"I'm", "I've", "You're", "You'll", "You've", "They're", "They'll", "We've",
+		"She's", "That's", "There's", "Here's", "What's", "Who's", "Where's",
+	} {
+		contractions = append(contractions, Contraction{Word: strings.ReplaceAll(word, "'", ""), Replacement: word})
+	}
+	for _, word := range []string{
+		"Comin", "Doin", "Dreamin", "Gettin", "Goin", "Jinglin", "Lovin", "Movin",
+		"Nothin", "Ringin", "Rockin", "Rollin", "Singin", "Somethin", "Swingin", "Walkin",
+	} {
+		contractions = append(contractions, Contraction{Word: word, Replacement: word + "'"})
+	}
+
+	return TitleNormalizerConfig{
+		SmallWords: []string{
+			"a", "an", "and", "as", "at", "but", "by", "for", "from", "in", "into",
+			"nor", "of", "on", "or", "o'er", "the", "to", "with",
+		},
+		Contractions: contractions,
+	}
+}
+
+// TitleNormalizer turns the title part of a filename into the title shown in
+// the catalog: "YouCan-apos-tHurryLove" becomes "You Can't Hurry Love"
+type TitleNormalizer struct {
+	smallWords   map[string]bool
+	contractions map[string]contractionRule
+}
+
+type contractionRule struct {
+	replacement string
+	firstWord   bool
+	before      map[string]bool
+}
+
+// NewTitleNormalizer builds a normalizer from its configuration
+func NewTitleNormalizer(config TitleNormalizerConfig) *TitleNormalizer {
+	normalizer := &TitleNormalizer{
+		smallWords:   make(map[string]bool),
+		contractions: make(map[string]contractionRule),
+	}
+
+	for _, word := range config.SmallWords {
+		normalizer.smallWords[foldName(word)] = true
+	}
+	for _, contraction := range config.Contractions {
+		rule := contractionRule{replacement: contraction.Replacement, firstWord: contraction.FirstWord}
+		if len(contraction.Before) > 0 {
+			rule.before = make(map[string]bool)
+			for _, word := range contraction.Before {
+				rule.before[foldName(word)] = true
+			}
+		}
+		normalizer.contractions[foldName(contraction.Word)] = rule
+	}
+
+	return normalizer
+}
+
+// encodedPunctuation matches the words the library's filenames use in place
+// of punctuation, as in "Sunday-apos-sPalms" or "MyJesus-comma-ILoveThee".
+// The closing hyphen is left in the text since two words may share it, as
+// in "-exclaim-paren-".
+var encodedPunctuation = regexp.MustCompile(`-(apos|comma|exclaim|quest|colon|amp|dash|slash|oparen|cparen|paren)\b`)
+
+var decodedPunctuation = map[string]string{
+	"apos":    "'",
+	"comma":   ", ",
+	"exclaim": "! ",
+	"quest":   "? ",
+	"colon":   ": ",
+	"amp":     " & ",
+	"dash":    "-",
+	"slash":   "/",
+	"oparen":  " (",
+	"cparen":  ") ",
+}
+
+var romanNumeral = regexp.MustCompile(`^X{0,3}(?:IX|IV|V?I{0,3})$`)
+
+// Normalize returns the catalog form of a raw title. It can be run again on
+// a title it produced without changing it.
+func (n *TitleNormalizer) Normalize(raw string) string {
+	words := strings.Fields(n.joinWords(raw))
+
+	var split []string
+	for _, word := range words {
+		split = append(split, splitCamel(word)...)
+	}
+
+	n.expandContractions(split)
+	return n.applyCase(split)
+}
+
+// joinWords decodes punctuation words and turns the separators between
+// words into spaces. Hyphens are kept inside words such as "Red-Nosed"
+// unless they separate the words of the title, as in "taylor-the-latte-boy"
+// (see HyphenSeparated).
+func (n *TitleNormalizer) joinWords(raw string) string {
+	encoded := encodedPunctuation.FindAllStringSubmatchIndex(raw, -1)
+	hyphensSeparate := HyphenSeparated(raw)
+
+	separators := strings.NewReplacer("_", " ", "+", " ", ".", " ")
+	joinSegment := func(segment string) string {
+		segment = separators.Replace(segment)
+		if hyphensSeparate {
+			return strings.ReplaceAll(segment, "-", " ")
+		}
+		// A hyphen at the edge of a word is a dash between words
+		var words []string
+		for _, word := range strings.Fields(segment) {
+			if trimmed := strings.Trim(word, "-"); trimmed != word {
+				if trimmed == "" {
+					words = append(words, "-")
+					continue
+				}
+				word = trimmed
+			}
+			words = append(words, word)
+		}
+		return strings.Join(words, " ")
+	}
+
+	var b strings.Builder
+	last := 0
+	openParen := false
+	for _, loc := range encoded {
+		b.WriteString(joinSegment(strings.TrimPrefix(raw[last:loc[0]], "-")))
+		name := raw[loc[2]:loc[3]]
+		switch {
+		case name == "paren" && !openParen:
+			b.WriteString(" (")
+			openParen = true
+		case name == "paren" || name == "cparen":
+			b.WriteString(") ")
+			openParen = false
+		default:
+			b.WriteString(decodedPunctuation[name])
+			openParen = openParen || name == "oparen"
+		}
+		last = loc[1]
+	}
+	b.WriteString(joinSegment(strings.TrimPrefix(raw[last:], "-")))
+
+	return b.String()
+}
+
+// HyphenSeparated reports whether hyphens are the only word separator of a
+// filename or of one of its fields, as in "165042971-Runaround-Sue.pdf" or
+// the "traditional-amazing-grace" left of a title once its publisher prefix
+// is stripped. It is decided for each field, since the fields of a
+// filename may be joined by other separators. Two hyphens are enough
+// when no word is in CamelCase; "HereWeComeA-Caroling-Bass" keeps its
+// hyphenated word. Hyphens of punctuation words such as "-apos-" are not
+// counted.
+func HyphenSeparated(filename string) bool {
+	if strings.ContainsAny(filename, " _+") {
+		return false
+	}
+	hyphens := strings.Count(filename, "-") - 2*len(encodedPunctuation.FindAllStringIndex(filename, -1))
+	if hyphens >= 3 {
+		return true
+	}
+	if hyphens < 2 {
+		return false
+	}
+	for _, word := range strings.Split(filename, "-") {
+		if len(splitCamel(word)) > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// splitCamel splits one space-free word at its CamelCase boundaries. A
+// capital after a dropped g ("Rockin'The") starts a new word, and "Mc" stays
+// with the rest of the name.
+func splitCamel(word string) []string {
+	runes := []rune(word)
+	var pieces []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		apostropheBoundary := i >= 2 && runes[i-1] == '\'' && unicode.IsLower(runes[i-2]) && unicode.IsUpper(runes[i])
+		if isCamelBoundary(runes, i) || apostropheBoundary {
+			pieces = append(pieces, string(runes[start:i]))
+			start = i
+		}
+	}
+	pieces = append(pieces, string(runes[start:]))
+
+	var merged []string
+	for i := 0; i < len(pieces); i++ {
+		if pieces[i] == "Mc" && i+1 < len(pieces) {
+			merged = append(merged, pieces[i]+pieces[i+1])
+			i++
+			continue
+		}
+		merged = append(merged, pieces[i])
+	}
+	return merged
+}
+
+// splitPunctuation separates leading and trailing punctuation from a word,
+// so "(Praise" gives "(" and "Praise"
+func splitPunctuation(word string) (string, string, string) {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	start := strings.IndexFunc(word, isWordRune)
+	if start < 0 {
+		return word, "", ""
+	}
+	end := strings.LastIndexFunc(word, isWordRune)
+	end += len(string([]rune(word[end:])[0]))
+	return word[:start], word[start:end], word[end:]
+}
+
+// expandContractions replaces the words of the contraction dictionary in
+// place. Words that already hold an apostrophe are left alone.
+func (n *TitleNormalizer) expandContractions(words []string) {
+	for i, word := range words {
+		if strings.ContainsAny(word, "'’") {
+			continue
+		}
+		prefix, core, suffix := splitPunctuation(word)
+		rule, ok := n.contractions[foldName(core)]
+		if !ok || foldName(core) != strings.ToLower(core) {
+			continue
+		}
+
+		if rule.firstWord || rule.before != nil {
+			first := rule.firstWord && i == 0
+			before := false
+			if rule.before != nil && i+1 < len(words) {
+				_, next, _ := splitPunctuation(words[i+1])
+				before = rule.before[foldName(next)]
+			}
+			if !first && !before {
+				continue
+			}
+		}
+
+		words[i] = prefix + rule.replacement + suffix
+	}
+}
+
+// applyCase capitalizes the words of a title, keeping small words lowercase
+// except at the start and end of the title or of a parenthesized phrase.
+// Acronyms keep their capitals unless the whole title is in capitals.
+func (n *TitleNormalizer) applyCase(words []string) string {
+	shouting := isAllCaps(strings.Join(words, ""))
+
+	for i, word := range words {
+		prefix, core, suffix := splitPunctuation(word)
+		if core == "" {
+			continue
+		}
+
+		first := i == 0 || strings.ContainsAny(prefix, "(\"") ||
+			strings.HasSuffix(words[i-1], ":") || words[i-1] == "-"
+		last := i == len(words)-1 || strings.ContainsAny(suffix, ")!?:\"") || words[i+1] == "-"
+
+		var parts []string
+		for _, part := range strings.Split(core, "-") {
+			small := !first && !last && n.smallWords[foldName(part)] && !strings.Contains(core, "-")
+			parts = append(parts, casePart(part, small, shouting))
+		}
+		words[i] = prefix + strings.Join(parts, "-") + suffix
+	}
+
+	title := strings.Join(words, " ")
+	return strings.NewReplacer("( ", "(", " )", ")", " ,", ",", " !", "!", " ?", "?").Replace(title)
+}
+
+// casePart cases one word, or one part of a hyphenated word
+func casePart(part string, small, shouting bool) string {
+	runes := []rune(part)
+	letters := 0
+	for _, r := range runes {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters == 0 {
+		return part
+	}
+
+	// Acronyms, roman numerals and catalog numbers such as "WFRN1049" keep
+	// their capitals
+	if isAllCaps(part) && (romanNumeral.MatchString(part) || strings.ContainsAny(part, "0123456789") || (!shouting && letters >= 2)) {
+		return part
+	}
+	if small {
+		return strings.ToLower(part)
+	}
+
+	// A word that starts with a number, such as "49ers", is left lowercase
+	capitalized := unicode.IsDigit(runes[0])
+	for i, r := range runes {
+		switch {
+		case !capitalized && unicode.IsLetter(r):
+			runes[i] = unicode.ToUpper(r)
+			capitalized = true
+		case capitalized && shouting:
+			runes[i] = unicode.ToLower(r)
+		}
+	}
+	return string(runes)
+}
+
+// isAllCaps reports whether s has letters and all of them are capitals
+func isAllCaps(s string) bool {
+	hasLetter := false
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	return hasLetter
+}
+
+// RenormalizeTitles runs every song title already in tableName through the
+// normalizer, so titles stored by an older scan pick up the current rules.
+// The alphabetizing letter, sort key and work id are derived again from the
+// new title. A field a librarian corrected keeps the corrected value, which
+// lookup (if not nil) returns for the file of a row, and the fields that
+// were not corrected are derived from the corrected title. It returns the
+// number of rows that changed.
+func RenormalizeTitles(db LibraryStore, tableName string, normalizer *TitleNormalizer, filing *FilingRules, lookup func(folder, filename string) map[string]OverrideValue) (int, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT id, full_path_to_folder, original_filename, song_title, composer_or_arranger, alphabetizing_letter, sort_key, work_id FROM %s", tableName))
+	if err != nil {
+		return 0, fmt.Errorf("error reading titles from %s: %v", tableName, err)
+	}
+
+	type refiled struct {
+		title, letter, sortKey, workID string
+	}
+	changed := make(map[int64]refiled)
+	for rows.Next() {
+		var id int64
+		var folder, filename, title, composer, letter, sortKey, workID sql.NullString
+		if err := rows.Scan(&id, &folder, &filename, &title, &composer, &letter, &sortKey, &workID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !title.Valid || title.String == "" || title.String == "UNKNOWN" {
+			continue
+		}
+		var overrides map[string]OverrideValue
+		if lookup != nil {
+			overrides = lookup(folder.String, filename.String)
+		}
+		corrected := func(field, value string) string {
+			if override, ok := overrides[field]; ok {
+				return override.Value
+			}
+			return value
+		}
+		normalized := corrected("song title", normalizer.Normalize(title.String))
+		row := refiled{
+			title:   normalized,
+			letter:  corrected("alphabetizing letter", filing.Letter(normalized)),
+			sortKey: corrected("sort key", filing.SortKey(normalized)),
+			workID:  corrected("work id", WorkID(normalized, composer.String)),
+		}
+		if row != (refiled{title.String, letter.String, sortKey.String, workID.String}) {
+			changed[id] = row
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET song_title = ?, alphabetizing_letter = ?, sort_key = ?, work_id = ? WHERE id = ?", tableName)
+	for id, row := range changed {
+		_, err := db.Exec(query, row.title, row.letter, row.sortKey, row.workID, id)
+		if err != nil {
+			return 0, fmt.Errorf("error updating title of row %d: %v", id, err)
+		}
+	}
+
+	return len(changed), nil
+}
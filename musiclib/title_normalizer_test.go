@@ -0,0 +1,124 @@
+package musiclib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTitleNormalizerNormalize(t *testing.T) {
+	normalizer := NewTitleNormalizer(DefaultTitleNormalizerConfig())
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"AFamilyChristmasSpectacular", "A Family Christmas Spectacular"},
+		{"We_Didnt_Start_The_Fire", "We Didn't Start the Fire"},
+		{"YouCan-apos-tHurryLove", "You Can't Hurry Love"},
+		{"MyJesus-comma-ILoveThee", "My Jesus, I Love Thee"},
+		{"ItsASmallWorld", "It's a Small World"},
+		{"TheWorldAndItsPeople", "The World and Its People"},
+		{"LetsGoFlyAKite", "Let's Go Fly a Kite"},
+		{"TwasTheNightBeforeChristmas", "'Twas the Night Before Christmas"},
+		{"RockinAroundTheChristmasTree", "Rockin' Around the Christmas Tree"},
+		{"RudolphTheRed-NosedReindeer", "Rudolph the Red-Nosed Reindeer"},
+		{"KeepTheHome-firesBurning", "Keep the Home-Fires Burning"},
+		{"taylor-the-latte-boy", "Taylor the Latte Boy"},
+		{"the_lord_is_my_shepherd", "The Lord Is My Shepherd"},
+		{"Were_You_There_on_That_Christmas_Night", "Were You There on That Christmas Night"},
+		{"ABCSong", "ABC Song"},
+		{"McCartneyMedley", "McCartney Medley"},
+		{"MusicOfTheNightIII", "Music of the Night III"},
+		{"OHolyNight", "O Holy Night"},
+		{"GOD BLESS AMERICA", "God Bless America"},
+		{"Do You Hear What I Hear.", "Do You Hear What I Hear"},
+		{"Lo How a Rose - The Rose", "Lo How a Rose - The Rose"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got := normalizer.Normalize(tt.raw)
+			if got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+			if again := normalizer.Normalize(got); again != got {
+				t.Errorf("Normalize(%q) = %q, want it unchanged", got, again)
+			}
+		})
+	}
+}
+
+func TestHyphenSeparated(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     bool
+	}{
+		{"165042971-Runaround-Sue", true},
+		{"traditional-amazing-grace", true},
+		{"HereWeComeA-Caroling-Bass", false},
+		{"Sunday-apos-sPalms", false},
+		{"Red-Nosed", false},
+		{"Lo How a Rose - The Rose", false},
+	}
+	for _, tt := range tests {
+		if got := HyphenSeparated(tt.filename); got != tt.want {
+			t.Errorf("HyphenSeparated(%q) = %v, want %v", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestRenormalizeTitles(t *testing.T) {
+	store, err := OpenStore(BackendMemory, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	const columns = "id INTEGER PRIMARY KEY, full_path_to_folder TEXT, original_filename TEXT, song_title TEXT, composer_or_arranger TEXT, alphabetizing_letter TEXT, sort_key TEXT, work_id TEXT"
+	if err := store.CreateTable("music_library", columns); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]interface{}{
+		{1, "/library/2019 Spring", "AveMaria_Biebl.pdf", "AveMaria", "Franz Biebl", "A", "avemaria", "avemaria/franzbiebl"},
+		{2, "/library/2019 Spring", "TheSeal_Lullaby.pdf", "TheSeal Lullaby", "Eric Whitacre", "T", "theseal lullaby", "thesealllullaby"},
+		{3, "/library/2019 Spring", "LocusIste.pdf", "LocusIste", "Anton Bruckner", "L", "locusiste", "locusiste/antonbruckner"},
+	}
+	for _, row := range rows {
+		if err := store.InsertData("music_library", row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The librarian filed Locus Iste under its full title and kept the
+	// letter of The Seal Lullaby
+	overrides := map[string]map[string]OverrideValue{
+		"LocusIste.pdf":       {"song title": {Value: "Locus Iste (Gradual)", MatchedBy: OverrideByPath}},
+		"TheSeal_Lullaby.pdf": {"alphabetizing letter": {Value: "T", MatchedBy: OverrideByHash}},
+	}
+	lookup := func(folder, filename string) map[string]OverrideValue {
+		return overrides[filename]
+	}
+
+	filing := NewFilingRules(DefaultFilingConfig())
+	changed, err := RenormalizeTitles(store, "music_library", NewTitleNormalizer(DefaultTitleNormalizerConfig()), filing, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 3 {
+		t.Errorf("changed = %d, want 3", changed)
+	}
+
+	got, err := store.FetchAll("music_library", "ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Ave Maria", "A", filing.SortKey("Ave Maria"), "avemaria/franzbiebl"},
+		{"The Seal Lullaby", "T", filing.SortKey("The Seal Lullaby"), "theseallullaby/ericwhitacre"},
+		{"Locus Iste (Gradual)", "L", filing.SortKey("Locus Iste (Gradual)"), "locusistegradual/antonbruckner"},
+	}
+	for i, row := range got {
+		titled := []string{row[3].(string), row[5].(string), row[6].(string), row[7].(string)}
+		if !reflect.DeepEqual(titled, want[i]) {
+			t.Errorf("row %d = %q, want %q", i+1, titled, want[i])
+		}
+	}
+}
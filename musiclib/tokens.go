@@ -0,0 +1,180 @@
+package musiclib
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wordSpan is one word of a filename with its byte offsets, so callers can
+// cut the original text without losing its separators
+type wordSpan struct {
+	Text       string
+	Start, End int
+}
+
+// isWordSeparator reports whether r separates words in a filename
+func isWordSeparator(r rune) bool {
+	switch r {
+	case ' ', '_', '.', '-', '+':
+		return true
+	}
+	return false
+}
+
+// wordSpans splits a filename fragment on separators and CamelCase
+// boundaries. Runs of capitals stay together as an acronym, so "PDQBach"
+// gives "PDQ" and "Bach". Numbers are split from the words around them
+// except for a lowercase suffix, so "49ers" stays one word.
+func wordSpans(s string) []wordSpan {
+	var spans []wordSpan
+	runes := []rune(s)
+	offsets := make([]int, len(runes)+1)
+	pos := 0
+	for i, r := range runes {
+		offsets[i] = pos
+		pos += len(string(r))
+	}
+	offsets[len(runes)] = pos
+
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			spans = append(spans, wordSpan{Text: s[offsets[start]:offsets[end]], Start: offsets[start], End: offsets[end]})
+		}
+		start = -1
+	}
+
+	for i, r := range runes {
+		if isWordSeparator(r) {
+			flush(i)
+			continue
+		}
+		if start >= 0 && isCamelBoundary(runes, i) {
+			flush(i)
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	flush(len(runes))
+
+	return spans
+}
+
+// isCamelBoundary reports whether a new word starts at runes[i]
+func isCamelBoundary(runes []rune, i int) bool {
+	if i == 0 {
+		return false
+	}
+	prev, cur := runes[i-1], runes[i]
+
+	switch {
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return true
+	case unicode.IsLower(prev) && unicode.IsDigit(cur):
+		return true
+	case unicode.IsDigit(prev) && unicode.IsUpper(cur):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+		// End of an acronym: the last capital starts the next word
+		return true
+	}
+	return false
+}
+
+// findPhrases returns the names match finds among the words of text, trying
+// up to maxWords adjacent words at a time so the longest phrase wins
+func findPhrases(text string, maxWords int, match func(string) (string, bool)) []string {
+	_, names := removePhrases(text, maxWords, match)
+	return names
+}
+
+// extractPhrasesFromEnd removes the phrases match finds at the end of a raw
+// title, returning the rest of the title and the names in the order they
+// appear. At least one word is always left for the title.
+func extractPhrasesFromEnd(title string, maxWords int, match func(string) (string, bool)) (string, []string) {
+	var names []string
+
+	for {
+		spans := wordSpans(title)
+		found := false
+		for n := min(maxWords, len(spans)-1); n >= 1; n-- {
+			first := spans[len(spans)-n]
+			if name, ok := match(title[first.Start:]); ok {
+				names = append([]string{name}, names...)
+				title = strings.TrimRight(title[:first.Start], " _.-+")
+				found = true
+				break
+			}
+		}
+		if !found {
+			return title, names
+		}
+	}
+}
+
+// removePhrases cuts every phrase match finds out of text, returning the
+// remaining words joined by spaces and the names found
+func removePhrases(text string, maxWords int, match func(string) (string, bool)) (string, []string) {
+	var names, kept []string
+	spans := wordSpans(text)
+
+	for i := 0; i < len(spans); {
+		found := false
+		for n := min(maxWords, len(spans)-i); n >= 1; n-- {
+			if name, ok := match(text[spans[i].Start:spans[i+n-1].End]); ok {
+				names = append(names, name)
+				i += n
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, spans[i].Text)
+			i++
+		}
+	}
+
+	return strings.Join(kept, " "), names
+}
+
+// foldName lowercases a name and drops everything but letters and digits,
+// so "Hella Johnson", "HellaJohnson" and "hella_johnson" compare equal
+func foldName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// counting an adjacent transposition as one edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,98 @@
+package musiclib
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// VariantMarker is one entry of the variant vocabulary in config.yml. The
+// name is the variant label shown in the catalog; aliases are the ways the
+// marker is written in filenames.
+type VariantMarker struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+// DefaultVariantMarkers returns the edition markers found in the library
+// before a vocabulary was configured
+func DefaultVariantMarkers() []VariantMarker {
+	return []VariantMarker{
+		{Name: "With Cuts", Aliases: []string{"w cuts", "with cuts"}},
+		{Name: "Regular", Aliases: []string{"reg"}},
+		{Name: "Modified", Aliases: []string{"Mod"}},
+		{Name: "Revised", Aliases: []string{"Rev", "Revision"}},
+		{Name: "Original", Aliases: []string{"Orig"}},
+		{Name: "Final"},
+		{Name: "Reduced", Aliases: []string{"Reduction"}},
+		{Name: "Alternate Lyrics", Aliases: []string{"w alt lyrics", "alt lyrics", "with alternate lyrics"}},
+	}
+}
+
+// versionMarker matches numbered versions such as "v2" or "ver3"
+var versionMarker = regexp.MustCompile(`^(?i:v|ver|version)[ _.-]*(\d{1,2})$`)
+
+// VariantDetector finds edition markers such as "w_cuts" or "v2" in
+// filenames, so the versions of a piece can be listed under one work
+type VariantDetector struct {
+	markers  map[string]string // folded alias -> variant label
+	maxWords int
+}
+
+// NewVariantDetector builds a detector from a variant vocabulary
+func NewVariantDetector(markers []VariantMarker) *VariantDetector {
+	detector := &VariantDetector{markers: make(map[string]string), maxWords: 2}
+
+	for _, marker := range markers {
+		for _, alias := range append([]string{marker.Name}, marker.Aliases...) {
+			folded := foldName(alias)
+			if folded == "" {
+				continue
+			}
+			if _, ok := detector.markers[folded]; !ok {
+				detector.markers[folded] = marker.Name
+			}
+			if words := len(wordSpans(alias)); words > detector.maxWords {
+				detector.maxWords = words
+			}
+		}
+	}
+
+	return detector
+}
+
+// Match returns the variant label when all of text is a variant marker
+func (d *VariantDetector) Match(text string) (string, bool) {
+	if groups := versionMarker.FindStringSubmatch(text); groups != nil {
+		number, _ := strconv.Atoi(groups[1])
+		return fmt.Sprintf("Version %d", number), true
+	}
+	label, ok := d.markers[foldName(text)]
+	return label, ok
+}
+
+// ExtractFromTitle removes variant markers from the end of a raw title, as
+// in "PutOneFootInFrontOfTheOther_w_cuts" or "TheMostWonderfulDayOfTheYearMod"
+func (d *VariantDetector) ExtractFromTitle(title string) (string, []string) {
+	return extractPhrasesFromEnd(title, d.maxWords, d.Match)
+}
+
+// RemoveFromField removes variant markers anywhere in a filename field, such
+// as the composer field "Newan_Revised"
+func (d *VariantDetector) RemoveFromField(field string) (string, []string) {
+	return removePhrases(field, d.maxWords, d.Match)
+}
+
+// JoinVariants joins variant labels for display, dropping duplicates
+func JoinVariants(labels []string) string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, label := range labels {
+		if !seen[label] {
+			seen[label] = true
+			unique = append(unique, label)
+		}
+	}
+	return strings.Join(unique, ", ")
+}
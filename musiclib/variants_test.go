@@ -0,0 +1,64 @@
+package musiclib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVariantDetectorExtractFromTitle(t *testing.T) {
+	detector := NewVariantDetector(DefaultVariantMarkers())
+	tests := []struct {
+		title      string
+		want       string
+		wantLabels []string
+	}{
+		{"PutOneFootInFrontOfTheOther_w_cuts", "PutOneFootInFrontOfTheOther", []string{"With Cuts"}},
+		{"The_Battle_Of_New_Orleans_regular", "The_Battle_Of_New_Orleans", []string{"Regular"}},
+		{"TheMostWonderfulDayOfTheYearMod", "TheMostWonderfulDayOfTheYear", []string{"Modified"}},
+		{"Silent_Night_w_alt_lyrics", "Silent_Night", []string{"Alternate Lyrics"}},
+		{"Gloria_Revised_Final", "Gloria", []string{"Revised", "Final"}},
+		{"Shenandoah_v2", "Shenandoah", []string{"Version 2"}},
+		{"Shenandoah v02", "Shenandoah", []string{"Version 2"}},
+		// Markers are whole words, and never the whole title
+		{"Modern", "Modern", nil},
+		{"Revolver", "Revolver", nil},
+		{"Final", "Final", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got, labels := detector.ExtractFromTitle(tt.title)
+			if got != tt.want || !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("ExtractFromTitle() = %q, %q, want %q, %q", got, labels, tt.want, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestVariantDetectorRemoveFromField(t *testing.T) {
+	detector := NewVariantDetector(DefaultVariantMarkers())
+	tests := []struct {
+		field      string
+		want       string
+		wantLabels []string
+	}{
+		{"Newan_Revised", "Newan", []string{"Revised"}},
+		{"Mod_Huff", "Huff", []string{"Modified"}},
+		{"Revised", "", []string{"Revised"}},
+		{"Version 3", "", []string{"Version 3"}},
+		{"Huff", "Huff", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, labels := detector.RemoveFromField(tt.field)
+			if got != tt.want || !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("RemoveFromField() = %q, %q, want %q, %q", got, labels, tt.want, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestJoinVariants(t *testing.T) {
+	if got := JoinVariants([]string{"With Cuts", "Regular", "With Cuts"}); got != "With Cuts, Regular" {
+		t.Errorf("JoinVariants() = %q, want %q", got, "With Cuts, Regular")
+	}
+}
@@ -0,0 +1,245 @@
+package musiclib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Voicing is the canonical name of a choral voicing. Letter voicings are
+// written in score order (S, A, T, B) with each voice at most twice, so
+// "SSAATTBB" is valid and "STAB" is not.
+type Voicing string
+
+// Named voicings. Any other letter voicing in score order, such as
+// "SAATBB", is also a valid Voicing.
+const (
+	VoicingUnknown        Voicing = "UNKNOWN"
+	VoicingUnison         Voicing = "Unison"
+	VoicingTwoPart        Voicing = "2-Part"
+	VoicingTwoPartTreble  Voicing = "2-Part Treble"
+	VoicingTwoPartMixed   Voicing = "2-Part Mixed"
+	VoicingThreePart      Voicing = "3-Part"
+	VoicingThreePartMixed Voicing = "3-Part Mixed"
+	VoicingFourPart       Voicing = "4-Part"
+	VoicingTreble         Voicing = "Treble"
+	VoicingMixed          Voicing = "Mixed"
+	VoicingMens           Voicing = "Men's"
+	VoicingWomens         Voicing = "Women's"
+	VoicingSA             Voicing = "SA"
+	VoicingSSA            Voicing = "SSA"
+	VoicingSSAA           Voicing = "SSAA"
+	VoicingSAB            Voicing = "SAB"
+	VoicingSATB           Voicing = "SATB"
+	VoicingSSATB          Voicing = "SSATB"
+	VoicingSATBB          Voicing = "SATBB"
+	VoicingSSAATTBB       Voicing = "SSAATTBB"
+	VoicingTB             Voicing = "TB"
+	VoicingTTB            Voicing = "TTB"
+	VoicingTBB            Voicing = "TBB"
+	VoicingTTBB           Voicing = "TTBB"
+)
+
+// Confidence of each kind of voicing evidence
+const (
+	confidenceLetterVoicing = 1.0
+	confidenceDottedVoicing = 0.95
+	confidenceShortVoicing  = 0.9
+	confidenceLowerVoicing  = 0.9
+	confidencePartCount     = 0.9
+	confidenceDescriptive   = 0.8
+	confidenceMixed         = 0.7
+	confidenceReorderedSATB = 0.6
+)
+
+var namedVoicings = map[Voicing]bool{
+	VoicingUnison: true, VoicingTwoPart: true, VoicingTwoPartTreble: true, VoicingTwoPartMixed: true,
+	VoicingThreePart: true, VoicingThreePartMixed: true, VoicingFourPart: true,
+	VoicingTreble: true, VoicingMixed: true, VoicingMens: true, VoicingWomens: true,
+}
+
+// twoLetterVoicings are the only two-voice letter voicings accepted, since
+// words like "AT" or "AB" are common in titles
+var twoLetterVoicings = map[string]bool{"SA": true, "TB": true}
+
+// voicingWords are letter voicings that are also words, so they only count
+// as voicings in capitals
+var voicingWords = map[string]bool{"SAT": true, "ATT": true}
+
+// Valid reports whether v is a named voicing or a letter voicing in score order
+func (v Voicing) Valid() bool {
+	if namedVoicings[v] {
+		return true
+	}
+	return isLetterVoicing(string(v))
+}
+
+func isLetterVoicing(s string) bool {
+	if len(s) < 2 || len(s) > 8 {
+		return false
+	}
+	if len(s) == 2 && !twoLetterVoicings[s] {
+		return false
+	}
+	order := "SATB"
+	counts := make(map[rune]int)
+	last := -1
+	for _, r := range s {
+		i := strings.IndexRune(order, r)
+		if i < 0 || i < last {
+			return false
+		}
+		counts[r]++
+		if counts[r] > 2 {
+			return false
+		}
+		last = i
+	}
+	return true
+}
+
+// VoicingResult is a parsed voicing with how sure the parser is of it
+type VoicingResult struct {
+	Voicing    Voicing
+	Divisi     bool
+	Confidence float64
+	Matched    string
+}
+
+// String returns the voicing as shown in the catalog, such as "SATB divisi"
+func (r VoicingResult) String() string {
+	if r.Divisi && r.Voicing != VoicingUnknown {
+		return string(r.Voicing) + " divisi"
+	}
+	return string(r.Voicing)
+}
+
+var (
+	dottedVoicing  = regexp.MustCompile(`(?i)(?:^|[^a-z])((?:[satb]\.){1,7}[satb]\.?)(?:[^a-z]|$)`)
+	partCount      = regexp.MustCompile(`(?i)(?:^|[^a-z])(2|3|4|two|three|four)[ _-]*parts?(?:[ _-]*(mixed|treble))?(?:[^a-z]|$)`)
+	unisonWord     = regexp.MustCompile(`(?i)(?:^|[^a-z])unison(?:[^a-z]|$)`)
+	mensWord       = regexp.MustCompile(`(?i)(?:^|[^a-z])(?:men'?s|male)(?:[ _-]*(?:chorus|choir|voices))?(?:[^a-z]|$)`)
+	womensWord     = regexp.MustCompile(`(?i)(?:^|[^a-z])(?:women'?s|female)(?:[ _-]*(?:chorus|choir|voices))?(?:[^a-z]|$)`)
+	trebleWord     = regexp.MustCompile(`(?i)(?:^|[^a-z])treble(?:[ _-]*(?:chorus|choir|voices))?(?:[^a-z]|$)`)
+	mixedWord      = regexp.MustCompile(`(?i)(?:^|[^a-z])mixed(?:[ _-]*(?:chorus|choir|voices))?(?:[^a-z]|$)`)
+	divisiWord     = regexp.MustCompile(`(?i)(?:^|[^a-z])div(?:isi|\.)?(?:[^a-z]|$)`)
+	partCountNames = map[string]Voicing{
+		"2": VoicingTwoPart, "two": VoicingTwoPart,
+		"3": VoicingThreePart, "three": VoicingThreePart,
+		"4": VoicingFourPart, "four": VoicingFourPart,
+	}
+)
+
+// ParseVoicing finds the voicing in a filename or title. Letter voicings
+// must be whole words, so "SATB" is not found inside "SSATB" and "SA" is
+// not found inside an ordinary word. When several voicings are present the
+// most certain one wins, and the earliest among equals.
+func ParseVoicing(text string) VoicingResult {
+	best := VoicingResult{Voicing: VoicingUnknown}
+	consider := func(candidate VoicingResult) {
+		if candidate.Confidence > best.Confidence {
+			best = candidate
+		}
+	}
+
+	for _, span := range wordSpans(text) {
+		// Commas and brackets stay on the words of a line of text, as in
+		// "SSA, a cappella" on the first page of a score
+		consider(parseLetterVoicing(strings.Trim(span.Text, ",;:()[]")))
+	}
+
+	for _, groups := range dottedVoicing.FindAllStringSubmatch(text, -1) {
+		letters := strings.ToUpper(strings.ReplaceAll(groups[1], ".", ""))
+		if isLetterVoicing(letters) {
+			consider(VoicingResult{Voicing: Voicing(letters), Confidence: confidenceDottedVoicing, Matched: groups[1]})
+		}
+	}
+
+	if groups := partCount.FindStringSubmatch(text); groups != nil {
+		voicing := partCountNames[strings.ToLower(groups[1])]
+		switch strings.ToLower(groups[2]) {
+		case "mixed":
+			voicing = Voicing(string(voicing) + " Mixed")
+		case "treble":
+			voicing = Voicing(string(voicing) + " Treble")
+		}
+		if !voicing.Valid() {
+			voicing = partCountNames[strings.ToLower(groups[1])]
+		}
+		consider(VoicingResult{Voicing: voicing, Confidence: confidencePartCount, Matched: strings.Trim(groups[0], " _-.")})
+	}
+
+	descriptive := []struct {
+		re         *regexp.Regexp
+		voicing    Voicing
+		confidence float64
+	}{
+		{unisonWord, VoicingUnison, confidencePartCount},
+		{womensWord, VoicingWomens, confidenceDescriptive},
+		{mensWord, VoicingMens, confidenceDescriptive},
+		{trebleWord, VoicingTreble, confidenceDescriptive},
+		{mixedWord, VoicingMixed, confidenceMixed},
+	}
+	for _, d := range descriptive {
+		if match := d.re.FindString(text); match != "" {
+			consider(VoicingResult{Voicing: d.voicing, Confidence: d.confidence, Matched: strings.Trim(match, " _-.")})
+		}
+	}
+
+	if best.Voicing != VoicingUnknown {
+		best.Divisi = best.Divisi || divisiWord.MatchString(text)
+	}
+
+	return best
+}
+
+// parseLetterVoicing checks one word for a letter voicing such as "SATB",
+// "SSAATTBB" or the common misspelling "STAB". A voicing not written in
+// capitals, such as "satb" or "Ssa", must have three letters or more and
+// not be a word like "sat", and the misspelling must be in capitals since
+// "stab" is a word.
+func parseLetterVoicing(word string) VoicingResult {
+	letters := strings.ToUpper(word)
+	capitals := letters == word
+	if !capitals && (len(word) < 3 || voicingWords[letters]) {
+		return VoicingResult{}
+	}
+
+	if isLetterVoicing(letters) {
+		confidence := confidenceLetterVoicing
+		switch {
+		case !capitals:
+			confidence = confidenceLowerVoicing
+		case len(word) == 2:
+			confidence = confidenceShortVoicing
+		}
+		return VoicingResult{Voicing: Voicing(letters), Confidence: confidence, Matched: word}
+	}
+
+	// SATB with its letters out of order is almost always a typo
+	if capitals && len(word) == 4 && strings.ContainsRune(word, 'S') && strings.ContainsRune(word, 'A') &&
+		strings.ContainsRune(word, 'T') && strings.ContainsRune(word, 'B') {
+		return VoicingResult{Voicing: VoicingSATB, Confidence: confidenceReorderedSATB, Matched: word}
+	}
+
+	return VoicingResult{}
+}
+
+// RemoveDottedVoicing removes a voicing written with dots, such as
+// "S.A.T.B.", from a title, which the title normalizer would otherwise
+// split into single letters
+func RemoveDottedVoicing(title string) string {
+	locs := dottedVoicing.FindAllStringSubmatchIndex(title, -1)
+	for i := len(locs) - 1; i >= 0; i-- {
+		start, end := locs[i][2], locs[i][3]
+		if isLetterVoicing(strings.ToUpper(strings.ReplaceAll(title[start:end], ".", ""))) {
+			title = strings.TrimRight(title[:start], " _-") + " " + strings.TrimLeft(title[end:], " _-")
+		}
+	}
+	return strings.TrimSpace(title)
+}
+
+// FormatConfidence formats a confidence score for the CSV output
+func FormatConfidence(confidence float64) string {
+	return fmt.Sprintf("%.2f", confidence)
+}
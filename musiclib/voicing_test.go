@@ -0,0 +1,86 @@
+package musiclib
+
+import "testing"
+
+func TestParseVoicing(t *testing.T) {
+	tests := []struct {
+		text       string
+		want       string
+		confidence float64
+		matched    string
+	}{
+		{"S_SomebodyToLove_SATB_Emerson.pdf", "SATB", confidenceLetterVoicing, "SATB"},
+		{"InTheBleakMidwinter_SATB_a_cappella_Givler.pdf", "SATB", confidenceLetterVoicing, "SATB"},
+		{"TaylorTheLatteBoy_SSA_Huff.pdf", "SSA", confidenceLetterVoicing, "SSA"},
+		{"Circle-SSAA.pdf", "SSAA", confidenceLetterVoicing, "SSAA"},
+		// "SATB" is not found inside "SSATB"
+		{"Shenandoah SSATB.pdf", "SSATB", confidenceLetterVoicing, "SSATB"},
+		{"Glory SATB-2-9.pdf", "SATB", confidenceLetterVoicing, "SATB"},
+		{"P_PleaseKindSir_TB_PDQBach.pdf", "TB", confidenceShortVoicing, "TB"},
+		{"L_LeroyTheRedneckReindeer_STAB_Chinn.pdf", "SATB", confidenceReorderedSATB, "STAB"},
+		{"Ave_satb.pdf", "SATB", confidenceLowerVoicing, "satb"},
+		{"Ave Maria S.A.T.B..pdf", "SATB", confidenceDottedVoicing, "S.A.T.B."},
+		{"Ubi Caritas SATB divisi.pdf", "SATB divisi", confidenceLetterVoicing, "SATB"},
+		{"TTBB_div.pdf", "TTBB divisi", confidenceLetterVoicing, "TTBB"},
+		{"SA, a cappella", "SA", confidenceShortVoicing, "SA"},
+		{"Wade 2-part.pdf", "2-Part", confidencePartCount, "2-part"},
+		{"Three Part Mixed Alleluia.pdf", "3-Part Mixed", confidencePartCount, "Three Part Mixed"},
+		{"Unison Hymn.pdf", "Unison", confidencePartCount, "Unison"},
+		{"Men's Chorus Medley.pdf", "Men's", confidenceDescriptive, "Men's Chorus"},
+		{"Treble Choir Carol.pdf", "Treble", confidenceDescriptive, "Treble Choir"},
+		{"Mixed Chorus", "Mixed", confidenceMixed, "Mixed Chorus"},
+		// Words that look like voicings
+		{"Sat Down.pdf", "UNKNOWN", 0, ""},
+		{"AtTheRiver.pdf", "UNKNOWN", 0, ""},
+		{"SaSa.pdf", "UNKNOWN", 0, ""},
+		{"Fruitcake.pdf", "UNKNOWN", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := ParseVoicing(tt.text)
+			if got.String() != tt.want || got.Confidence != tt.confidence || got.Matched != tt.matched {
+				t.Errorf("ParseVoicing() = %q at %v from %q, want %q at %v from %q",
+					got.String(), got.Confidence, got.Matched, tt.want, tt.confidence, tt.matched)
+			}
+		})
+	}
+}
+
+func TestVoicingValid(t *testing.T) {
+	tests := []struct {
+		voicing Voicing
+		want    bool
+	}{
+		{VoicingSSAATTBB, true},
+		{"SAATBB", true},
+		{VoicingTwoPartTreble, true},
+		{VoicingSA, true},
+		{"STAB", false},
+		{"SSSA", false},
+		{"AT", false},
+		{"SATBSATB", false},
+		{VoicingUnknown, false},
+	}
+	for _, tt := range tests {
+		if got := tt.voicing.Valid(); got != tt.want {
+			t.Errorf("Voicing(%q).Valid() = %v, want %v", tt.voicing, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveDottedVoicing(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Ave Maria S.A.T.B.", "Ave Maria"},
+		{"S.A.T.B. Gloria", "Gloria"},
+		{"Ave_Maria_-_S.S.A.", "Ave_Maria"},
+		{"A.B.C. Song", "A.B.C. Song"},
+	}
+	for _, tt := range tests {
+		if got := RemoveDottedVoicing(tt.title); got != tt.want {
+			t.Errorf("RemoveDottedVoicing(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
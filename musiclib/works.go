@@ -0,0 +1,26 @@
+package musiclib
+
+import "strings"
+
+// WorkID returns the key that groups the files of one piece, such as the
+// score and the bass part of the same arrangement. It is the title with
+// case, spacing and punctuation removed, followed after a "/" by the first
+// composer folded the same way, so Biebl's and Bruckner's "Ave Maria" are
+// two works: "avemaria/biebl" and "avemaria/bruckner". A file crediting no
+// composer has the title alone; WorkTitle gives the title of either kind.
+func WorkID(title, composer string) string {
+	if title == "" || title == "UNKNOWN" {
+		return ""
+	}
+	composer, _, _ = strings.Cut(composer, ", ")
+	if composer == "UNKNOWN" || foldName(composer) == "" {
+		return foldName(title)
+	}
+	return foldName(title) + "/" + foldName(composer)
+}
+
+// WorkTitle returns the title part of a work id
+func WorkTitle(workID string) string {
+	title, _, _ := strings.Cut(workID, "/")
+	return title
+}
@@ -0,0 +1,27 @@
+package musiclib
+
+import "testing"
+
+func TestWorkID(t *testing.T) {
+	tests := []struct {
+		title     string
+		composer  string
+		want      string
+		wantTitle string
+	}{
+		{"Ave Maria", "Franz Biebl", "avemaria/franzbiebl", "avemaria"},
+		{"Ave Maria", "Anton Bruckner, Franz Biebl", "avemaria/antonbruckner", "avemaria"},
+		{"Rock-a-Bye", "Huff", "rockabye/huff", "rockabye"},
+		{"Put One Foot in Front of the Other", "UNKNOWN", "putonefootinfrontoftheother", "putonefootinfrontoftheother"},
+		{"UNKNOWN", "Huff", "", ""},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title+"/"+tt.composer, func(t *testing.T) {
+			got := WorkID(tt.title, tt.composer)
+			if got != tt.want || WorkTitle(got) != tt.wantTitle {
+				t.Errorf("WorkID() = %q with title %q, want %q with title %q", got, WorkTitle(got), tt.want, tt.wantTitle)
+			}
+		})
+	}
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -11,344 +11,1508 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
-	"unicode"
+	"time"
 
-	_ "github.com/marcboeker/go-duckdb"
+	"github.com/ggivl/GoMusicLibraryGUIApp/musiclib"
 )
 
-// DuckDBDatabase represents a DuckDB database connection
-type DuckDBDatabase struct {
-	DbName     string
-	Connection *sql.DB
+// FileMethods represents file processing methods
+type FileMethods struct {
+	BaseDir       string
+	DbColumnNames string
+	db            musiclib.LibraryStore
+	grammar       *musiclib.FilenameGrammar
+	lexicon       *musiclib.ComposerLexicon
+	parts         *musiclib.PartDetector
+	titles        *musiclib.TitleNormalizer
+	variants      *musiclib.VariantDetector
+	filing        *musiclib.FilingRules
+	publishers    *musiclib.PublisherRecognizer
+	pathRules     *musiclib.PathRules
+	unmatched     map[string]int // folder -> files in folders no path rule matched
+	history       *musiclib.FileHistory
+	overrides     *musiclib.Overrides
+	hashes        map[string]string // file path -> content hash
+	flagged       map[string]string // file path -> why its contents could not be read
+	audio         map[string]musiclib.AudioInfo // relative path -> tags and stream properties
+	scores        map[string]musiclib.ScoreInfo // relative path -> credits and part ranges
+	scanDate      string
+}
+
+// NewFileMethods creates a new FileMethods instance using the filename
+// patterns, vocabularies, publisher table, path rules and title and filing
+// rules from the configuration, keeping the catalog in store
+func NewFileMethods(baseDir string, config musiclib.Config, store musiclib.LibraryStore) (*FileMethods, error) {
+	parts := musiclib.NewPartDetector(config.Instruments)
+	
+	grammar, err := musiclib.NewFilenameGrammar(config.FilenamePatterns, map[string]string{
+		"part": parts.FieldExpression(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	publishers, err := musiclib.NewPublisherRecognizer(config.Publishers)
+	if err != nil {
+		return nil, err
+	}
+	
+	pathRules, err := musiclib.NewPathRules(config.PathRules)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &FileMethods{
+		BaseDir:       baseDir,
+		db:            store,
+		DbColumnNames: "id INTEGER PRIMARY KEY, alphabetizing_letter TEXT, sort_key TEXT, full_path_to_folder TEXT, original_filename TEXT, song_title TEXT, voicing TEXT, voicing_confidence DOUBLE, musical_key TEXT, time_signature TEXT, tempo TEXT, part TEXT, variant TEXT, composer_or_arranger TEXT, arranger TEXT, accompaniment TEXT, lyricist TEXT, copyright TEXT, publisher TEXT, catalog_number TEXT, source TEXT, file_type TEXT, page_count TEXT, page_size TEXT, file_status TEXT, album TEXT, track_number TEXT, duration TEXT, media TEXT, recorded_date TEXT, measures TEXT, part_ranges TEXT, file_create_date TEXT, file_modified_date TEXT, first_seen TEXT, library_type TEXT, season TEXT, concert_year TEXT, acquired_date TEXT, matched_pattern TEXT, work_id TEXT",
+		grammar:       grammar,
+		lexicon:       musiclib.NewComposerLexicon(nil),
+		parts:         parts,
+		titles:        musiclib.NewTitleNormalizer(config.TitleNormalizer),
+		variants:      musiclib.NewVariantDetector(config.Variants),
+		filing:        musiclib.NewFilingRules(config.Filing),
+		publishers:    publishers,
+		pathRules:     pathRules,
+		unmatched:     make(map[string]int),
+		history:       musiclib.NewFileHistory(),
+		overrides:     musiclib.NewOverrides(),
+		hashes:        make(map[string]string),
+		flagged:       make(map[string]string),
+		audio:         make(map[string]musiclib.AudioInfo),
+		scores:        make(map[string]musiclib.ScoreInfo),
+		scanDate:      time.Now().Format("2006-01-02"),
+	}, nil
+}
+
+// LoadComposerLexicon seeds the composer_lexicon table from seedFile and
+// loads every known name for matching
+func (fm *FileMethods) LoadComposerLexicon(seedFile string) error {
+	err := musiclib.CreateComposerLexiconTable(fm.db)
+	if err != nil {
+		return err
+	}
+	
+	names, common, err := musiclib.ReadComposerSeedFile(seedFile)
+	if err != nil {
+		fmt.Printf("Skipping composer seed file: %v\n", err)
+	} else {
+		err = musiclib.SeedComposerLexicon(fm.db, names, seedFile)
+		if err != nil {
+			return err
+		}
+	}
+	
+	fm.lexicon, err = musiclib.LoadComposerLexicon(fm.db)
+	if err != nil {
+		return err
+	}
+	fm.lexicon.MarkCommon(common)
+	
+	fmt.Printf("Composer lexicon: %d names\n", fm.lexicon.Len())
+	return nil
+}
+
+// GetComposerFromField replaces the names in a composer field with their
+// lexicon spelling, keeping the field as written when no name is known, and
+// returns the lexicon matches it used
+func (fm *FileMethods) GetComposerFromField(composerField string) (string, []musiclib.ComposerMatch) {
+	matches := fm.lexicon.MatchTokens(composerField)
+	if len(matches) == 0 {
+		return composerField, nil
+	}
+	
+	var names []string
+	for _, match := range matches {
+		names = append(names, match.Name)
+	}
+	return strings.Join(names, ", "), matches
+}
+
+func (fm *FileMethods) FindFilesRecursively(directoryPath string) ([]string, error) {
+	var fileLst []string
+	
+	err := filepath.WalkDir(directoryPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			fileLst = append(fileLst, path)
+		}
+		return nil
+	})
+	
+	return fileLst, err
+}
+
+func (fm *FileMethods) FindFilesInDirectory(directoryPath string) ([]string, error) {
+	var filesList []string
+	
+	entries, err := os.ReadDir(directoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("error: Directory not found at '%s': %v", directoryPath, err)
+	}
+	
+	for _, entry := range entries {
+		fullPath := filepath.Join(directoryPath, entry.Name())
+		fmt.Printf("Full path: %s\n", fullPath)
+		filesList = append(filesList, fullPath)
+	}
+	
+	return filesList, nil
+}
+
+func (fm *FileMethods) GetFullPathToFolder(directoryPath, fileName string) string {
+	return filepath.Join(directoryPath, fileName)
+}
+
+func (fm *FileMethods) SplitFilename(filenameToSplit, separator string) []string {
+	fmt.Printf("Filename to split: %s\n", filenameToSplit)
+	
+	// Use regex to split by space, underscore, or dot
+	re := regexp.MustCompile(`[ _.]`)
+	splitFileLst := re.Split(filenameToSplit, -1)
+	
+	fmt.Printf("Split file list: %+v\n", splitFileLst)
+	fmt.Printf("Length of splitFileLst is: %d\n", len(splitFileLst))
+	
+	return splitFileLst
+}
+
+// SplitSongTitle turns the title part of a filename into a catalog title,
+// splitting CamelCase words and restoring apostrophes and capitalization
+func (fm *FileMethods) SplitSongTitle(songTitle string) string {
+	fmt.Printf("Song title: %s\n", songTitle)
+	
+	result := fm.titles.Normalize(songTitle)
+	
+	fmt.Printf("Split song title: %s\n", result)
+	return result
+}
+
+// RenormalizeTitlesInDB applies the current title rules to the song titles
+// already stored in the database. Fields a librarian corrected keep their
+// corrections, so the overrides must be loaded first.
+func (fm *FileMethods) RenormalizeTitlesInDB(tableName string) error {
+	lookup := func(folder, filename string) map[string]musiclib.OverrideValue {
+		return fm.LookupOverrides(filepath.Join(folder, filename))
+	}
+	changed, err := musiclib.RenormalizeTitles(fm.db, tableName, fm.titles, fm.filing, lookup)
+	if err != nil {
+		return err
+	}
+	
+	fmt.Printf("Re-normalized %d song titles in %s\n", changed, tableName)
+	return nil
+}
+
+// FileTitle sets the alphabetizing letter and sort key of a file from its
+// title using the filing rules, so "The Presidents" is filed under "P".
+// Both fields are as trustworthy as the title they were filed from.
+func (fm *FileMethods) FileTitle(fileInfo *FileInfo, title string, confidence float64) {
+	fileInfo.AlphabetizingLetter = fm.filing.Letter(title)
+	fileInfo.SortKey = fm.filing.SortKey(title)
+	
+	detail := fmt.Sprintf("filing title %q of %q", fm.filing.FilingTitle(title), title)
+	fileInfo.Provenance.Record("alphabetizing letter", fileInfo.AlphabetizingLetter, musiclib.SourceDerived, detail, confidence)
+	fileInfo.Provenance.Record("sort key", fileInfo.SortKey, musiclib.SourceDerived, detail, confidence)
+	
+	fmt.Printf("Alpha letter: %s, sort key: %s\n", fileInfo.AlphabetizingLetter, fileInfo.SortKey)
+}
+
+func (fm *FileMethods) GetExtensionFromFilename(composerArranger string) (string, string) {
+	fmt.Printf("Composer/arranger: %s\n", composerArranger)
+	
+	ext := filepath.Ext(composerArranger)
+	composer := strings.TrimSuffix(composerArranger, ext)
+	
+	fmt.Printf("Composer: %s\n", composer)
+	fmt.Printf("Extension: %s\n", ext)
+	
+	return composer, ext
+}
+
+// GetFileDatesFromFilename returns the dates a file was created and last
+// modified. The creation date is empty when the filesystem does not keep
+// birth times.
+func (fm *FileMethods) GetFileDatesFromFilename(dtFilename string) (string, string) {
+	times, err := musiclib.ReadFileTimes(dtFilename)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return "", ""
+	}
+	
+	created := ""
+	if !times.Created.IsZero() {
+		created = times.Created.Format("2006-01-02")
+	}
+	modified := times.Modified.Format("2006-01-02")
+	
+	fmt.Printf("Created date: %s, modified date: %s\n", created, modified)
+	return created, modified
+}
+
+// LoadFileHistory reads the dates each file was first scanned from the
+// file_history table
+func (fm *FileMethods) LoadFileHistory() error {
+	err := musiclib.CreateFileHistoryTable(fm.db)
+	if err != nil {
+		return err
+	}
+	
+	history, err := musiclib.LoadFileHistory(fm.db)
+	if err != nil {
+		return err
+	}
+	
+	fm.history = history
+	return nil
+}
+
+// SaveFileHistory records the files first seen in this scan
+func (fm *FileMethods) SaveFileHistory() error {
+	fmt.Printf("Files first seen in this scan: %d\n", fm.history.Added())
+	return musiclib.SaveFileHistory(fm.db, fm.history)
+}
+
+// GetFirstSeenFromFilePath returns the date a file was first scanned. Files
+// are known by their path below the library root, so copying the library
+// to a new drive keeps their dates.
+func (fm *FileMethods) GetFirstSeenFromFilePath(filePath string) string {
+	return fm.history.FirstSeen(fm.RelativePath(filePath), fm.scanDate)
+}
+
+// RelativePath returns the path of a file below the library root, which
+// is how the file_history and field_provenance tables know a file
+func (fm *FileMethods) RelativePath(filePath string) string {
+	relPath, err := filepath.Rel(fm.BaseDir, filePath)
+	if err != nil {
+		relPath = filePath
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// GetVoicingFromFilename parses the voicing from a filename or a filename field
+func (fm *FileMethods) GetVoicingFromFilename(filename string) musiclib.VoicingResult {
+	voicing := musiclib.ParseVoicing(filename)
+	fmt.Printf("Voicing of %s: %s (confidence %.2f)\n", filename, voicing, voicing.Confidence)
+	return voicing
+}
+
+// GetFileTypeFromFilePath returns the name of the registered format of a
+// file, detected from its first bytes and then from its extension, and
+// which of the two recognized it
+func (fm *FileMethods) GetFileTypeFromFilePath(filePath string) (string, string) {
+	format, detectedBy, ok := musiclib.DetectFormat(filePath)
+	if !ok {
+		return "UNKNOWN", ""
+	}
+	return format.Name, detectedBy
+}
+
+// GetPathFieldsFromFilePath derives the library type, season and concert
+// year from the folders of a file using the path rules. Folders that no
+// rule matches are counted for the unmatched folders report.
+func (fm *FileMethods) GetPathFieldsFromFilePath(filePath string) musiclib.PathMatch {
+	folder := filepath.Dir(filePath)
+	match := fm.pathRules.Match(fm.BaseDir, folder)
+	if !match.Matched() {
+		if rel, err := filepath.Rel(fm.BaseDir, folder); err == nil {
+			folder = rel
+		}
+		fm.unmatched[folder]++
+	}
+	if match.LibraryType == "" {
+		match.LibraryType = "UNKNOWN"
+	}
+	
+	fmt.Printf("Path rules %v: %+v\n", match.Rules, match)
+	return match
+}
+
+// WriteUnmatchedFoldersReport writes the folders that no path rule matched,
+// with how many files each holds, so new rules can be added for them
+func (fm *FileMethods) WriteUnmatchedFoldersReport(reportFilename string) error {
+	folders := make([]string, 0, len(fm.unmatched))
+	for folder := range fm.unmatched {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+	
+	file, err := os.Create(reportFilename)
+	if err != nil {
+		return fmt.Errorf("error creating report '%s': %v", reportFilename, err)
+	}
+	defer file.Close()
+	
+	fmt.Fprintf(file, "Folders matched by no path rule: %d\n", len(folders))
+	for _, folder := range folders {
+		fmt.Fprintf(file, "%s\t%d files\n", folder, fm.unmatched[folder])
+	}
+	
+	fmt.Printf("%d folders matched no path rule, see %s\n", len(folders), reportFilename)
+	return nil
+}
+
+// GetKeyFromFilename returns the key named in a filename, such as
+// "Jingle_Bells_F_Major.pdf", or else the key signature of the file or of a
+// MusicXML or MIDI file with the same name next to it, along with the
+// source the key came from
+func (fm *FileMethods) GetKeyFromFilename(filePath string) (musiclib.KeyResult, string, bool) {
+	filename := filepath.Base(filePath)
+	if key, ok := musiclib.ParseKey(strings.TrimSuffix(filename, filepath.Ext(filename))); ok {
+		fmt.Printf("Key %s found in filename %s\n", key.Key, filename)
+		return key, musiclib.SourceFilename, true
+	}
+	if key, ok := musiclib.FindScoreKey(filePath); ok {
+		fmt.Printf("Key %s read from %s\n", key.Key, key.Matched)
+		return key, musiclib.SourceFileContents, true
+	}
+	return musiclib.KeyResult{}, "", false
+}
+
+// RemoveKeyFromField drops a key from a filename field, so a composer field
+// of "F_Major" is not taken for a composer
+func (fm *FileMethods) RemoveKeyFromField(field string) string {
+	if key, ok := musiclib.ParseKey(field); ok {
+		return strings.Trim(field[:key.Start]+" "+field[key.End:], " _.-+")
+	}
+	return field
+}
+
+// BuildFileInfo parses one file, reads the metadata inside it and applies
+// the librarians' corrections to it
+func (fm *FileMethods) BuildFileInfo(filePath string) FileInfo {
+	fileInfo := fm.ParseFileInfo(filePath)
+	fm.ApplyFileMetadata(&fileInfo, filePath)
+	// The metadata may have named the composer the filename did not
+	fm.DeriveWorkID(&fileInfo)
+	fm.ApplyOverrides(&fileInfo, filePath)
+	return fileInfo
+}
+
+// DeriveWorkID sets the work id from the song title and composer when
+// either has changed since it was last derived. It is as trustworthy as
+// the less certain of the two.
+func (fm *FileMethods) DeriveWorkID(fileInfo *FileInfo) {
+	workID := musiclib.WorkID(fileInfo.SongTitle, fileInfo.ComposerOrArranger)
+	if step, ok := fileInfo.Provenance.Field("work id"); ok && step.Value == workID {
+		return
+	}
+	fileInfo.WorkID = workID
+	
+	confidence := 1.0
+	var from []string
+	for _, field := range []string{"song title", "composer or arranger"} {
+		if step, ok := fileInfo.Provenance.Field(field); ok {
+			confidence = min(confidence, step.Confidence)
+			from = append(from, fmt.Sprintf("%s %q", field, step.Value))
+		}
+	}
+	if len(from) > 0 {
+		fileInfo.Provenance.Record("work id", workID, musiclib.SourceDerived, strings.Join(from, " and "), confidence)
+	}
+}
+
+// ParseFileInfo parses one file with the first filename pattern that matches it
+func (fm *FileMethods) ParseFileInfo(filePath string) FileInfo {
+	var fileInfo FileInfo
+	record := fileInfo.Provenance.Record
+	filename := filepath.Base(filePath)
+	baseName := strings.TrimSuffix(filename, filepath.Ext(filename))
+	
+	fileInfo.FullPathToFolder = filepath.Dir(filePath)
+	fileInfo.OriginalFilename = filename
+	record("full path to folder", fileInfo.FullPathToFolder, musiclib.SourceFilesystem, "folder of the file", 1.0)
+	record("original filename", filename, musiclib.SourceFilesystem, "name of the file", 1.0)
+	fm.FileTitle(&fileInfo, baseName, confidenceWholeFilename)
+	
+	fileInfo.FileCreateDate, fileInfo.FileModifiedDate = fm.GetFileDatesFromFilename(filePath)
+	if fileInfo.FileCreateDate != "" {
+		record("file create date", fileInfo.FileCreateDate, musiclib.SourceFilesystem, "birth time of the file", 1.0)
+	} else {
+		record("file create date", "", musiclib.SourceDefault, "the filesystem keeps no birth time", 0)
+	}
+	record("file modified date", fileInfo.FileModifiedDate, musiclib.SourceFilesystem, "modification time of the file", 1.0)
+	fileInfo.FirstSeen = fm.GetFirstSeenFromFilePath(filePath)
+	record("first seen", fileInfo.FirstSeen, musiclib.SourceDerived, "first scan in the file_history table", 1.0)
+	
+	fileType, detectedBy := fm.GetFileTypeFromFilePath(filePath)
+	fileInfo.FileType = fileType
+	switch detectedBy {
+	case musiclib.DetectedBySignature:
+		record("file type", fileType, musiclib.SourceFileContents, "signature in the first bytes", 1.0)
+	case musiclib.DetectedByExtension:
+		record("file type", fileType, musiclib.SourceFilename, "extension "+filepath.Ext(filename), 0.6)
+	default:
+		record("file type", fileType, musiclib.SourceDefault, "no registered format", 0)
+	}
+	
+	pathFields := fm.GetPathFieldsFromFilePath(filePath)
+	fileInfo.LibraryType = pathFields.LibraryType
+	fileInfo.Season = pathFields.Season
+	fileInfo.ConcertYear = pathFields.ConcertYear
+	fileInfo.AcquiredDate = pathFields.AcquiredDate
+	for _, field := range []struct{ name, value string }{
+		{"library type", fileInfo.LibraryType},
+		{"season", fileInfo.Season},
+		{"concert year", fileInfo.ConcertYear},
+		{"acquired date", fileInfo.AcquiredDate},
+	} {
+		if origin, ok := pathFields.Origins[field.name]; ok {
+			record(field.name, field.value, musiclib.SourceFolderRule, origin, confidenceFolderRule)
+		} else if field.value != "" {
+			record(field.name, field.value, musiclib.SourceDerived, "start of the concert season", confidenceSeasonStart)
+		} else {
+			record(field.name, field.value, musiclib.SourceDefault, "no folder rule gives it", 0)
+		}
+	}
+	
+	fileInfo.SongTitle = "UNKNOWN"
+	fileInfo.ComposerOrArranger = "UNKNOWN"
+	fileInfo.MatchedPattern = "UNKNOWN"
+	record("song title", fileInfo.SongTitle, musiclib.SourceDefault, "no title found yet", 0)
+	record("composer or arranger", fileInfo.ComposerOrArranger, musiclib.SourceDefault, "no composer found", 0)
+	record("matched pattern", fileInfo.MatchedPattern, musiclib.SourceDefault, "no filename pattern matches", 0)
+	
+	voicing := fm.GetVoicingFromFilename(baseName)
+	fileInfo.Voicing = voicing.String()
+	fileInfo.VoicingConfidence = voicing.Confidence
+	if voicing.Voicing != musiclib.VoicingUnknown {
+		record("voicing", fileInfo.Voicing, musiclib.SourceFilename, fmt.Sprintf("%q in the filename", voicing.Matched), voicing.Confidence)
+	} else {
+		record("voicing", fileInfo.Voicing, musiclib.SourceDefault, "no voicing in the filename", 0)
+	}
+	
+	if key, source, ok := fm.GetKeyFromFilename(filePath); ok {
+		fileInfo.Key = key.Key.String()
+		detail := fmt.Sprintf("%q in the filename", key.Matched)
+		if source == musiclib.SourceFileContents {
+			detail = "key signature of " + key.Matched
+		}
+		record("key", fileInfo.Key, source, detail, key.Confidence)
+	} else {
+		record("key", "", musiclib.SourceDefault, "no key in the filename or a score", 0)
+	}
+	
+	publisher := fm.publishers.Recognize(baseName)
+	fileInfo.Publisher = publisher.Publisher
+	fileInfo.CatalogNumber = publisher.CatalogNumber
+	fileInfo.Source = publisher.Source
+	for _, field := range []struct{ name, value string }{
+		{"publisher", fileInfo.Publisher},
+		{"catalog number", fileInfo.CatalogNumber},
+		{"source", fileInfo.Source},
+	} {
+		if field.value != "" {
+			record(field.name, field.value, musiclib.SourceFilename, "publisher table", confidencePublisherRule)
+		}
+	}
+	
+	match, ok := fm.grammar.Match(filename)
+	if !ok {
+		fmt.Printf("No filename pattern matched: %s\n", filename)
+		return fileInfo
+	}
+	fmt.Printf("Matched pattern %s: %+v\n", match.Pattern, match.Fields)
+	token := func(field string) string {
+		return fmt.Sprintf("token %d {%s} of pattern %s", match.Token(field), field, match.Pattern)
+	}
+	
+	fileInfo.MatchedPattern = match.Pattern
+	record("matched pattern", match.Pattern, musiclib.SourceFilename, "first filename pattern that matches", 1.0)
+	title := fm.publishers.StripFromTitle(match.Field("title"))
+	if musiclib.HyphenSeparated(filename) || musiclib.HyphenSeparated(title) {
+		// Decide from the whole filename too, since little of it may be
+		// left in the title, as in "165042971-Runaround-Sue"
+		title = strings.ReplaceAll(title, "-", " ")
+	}
+	title, _, _ = musiclib.ExtractKey(musiclib.RemoveDottedVoicing(title))
+	record("song title", title, musiclib.SourceFilename, token("title")+" without publisher, dotted voicing and key words", confidencePatternField)
+	title, variants := fm.variants.ExtractFromTitle(title)
+	titleHasVariants := len(variants) > 0
+	composerHasVariants := false
+	parts := fm.parts.FindParts(match.Field("part"))
+	partPlaces := placesOf(len(parts) > 0, token("part"))
+	composer := fm.RemoveKeyFromField(fm.publishers.StripFromTitle(match.Field("composer")))
+	if remaining, composerVariants := fm.variants.RemoveFromField(composer); len(composerVariants) > 0 {
+		composer = remaining
+		variants = append(variants, composerVariants...)
+		composerHasVariants = true
+	}
+	if composerParts, ok := fm.parts.OnlyParts(composer); ok {
+		// A part written where the composer goes, as in "Song_SATB_Bass",
+		// names no composer
+		parts = append(parts, composerParts...)
+		partPlaces = append(partPlaces, token("composer"))
+		composer = ""
+	}
+	if composer != "" {
+		name, matches := fm.GetComposerFromField(composer)
+		fileInfo.ComposerOrArranger = name
+		if len(matches) > 0 {
+			confidence := 1.0
+			for _, lexiconMatch := range matches {
+				confidence = min(confidence, lexiconMatch.Confidence)
+			}
+			record("composer or arranger", name, musiclib.SourceLexicon, token("composer")+" matched to the lexicon", confidence)
+		} else {
+			record("composer or arranger", name, musiclib.SourceFilename, token("composer")+" as written", confidencePatternField)
+		}
+		if composerParts := fm.parts.FindParts(composer); len(composerParts) > 0 {
+			parts = append(parts, composerParts...)
+			partPlaces = append(partPlaces, token("composer"))
+		}
+	} else if remaining, composer, ok := fm.lexicon.ExtractFromTitle(title); ok {
+		fmt.Printf("Composer %s found at the end of title %s\n", composer.Name, title)
+		fileInfo.ComposerOrArranger = composer.Name
+		title = remaining
+		record("composer or arranger", composer.Name, musiclib.SourceLexicon, fmt.Sprintf("lexicon name %q at the end of the title", composer.Matched), composer.Confidence)
+		record("song title", title, musiclib.SourceFilename, token("title")+" without the composer", confidencePatternField)
+	} else if composer, ok := fm.lexicon.FindInFilename(baseName); ok {
+		fileInfo.ComposerOrArranger = composer.Name
+		record("composer or arranger", composer.Name, musiclib.SourceLexicon, fmt.Sprintf("lexicon name %q in the filename", composer.Matched), composer.Confidence)
+	}
+	
+	title, titleParts := fm.parts.ExtractFromTitle(title)
+	parts = append(titleParts, parts...)
+	fileInfo.Part = musiclib.JoinParts(parts)
+	if len(titleParts) > 0 {
+		partPlaces = append([]string{"the end of the title"}, partPlaces...)
+		record("song title", title, musiclib.SourceFilename, token("title")+" without the parts", confidencePatternField)
+	}
+	if fileInfo.Part != "" {
+		record("part", fileInfo.Part, musiclib.SourceFilename, "instrument vocabulary in "+strings.Join(partPlaces, ", "), confidencePatternField)
+	}
+	
+	// A marker may also come before the parts, as in "..._w_cuts_Bass"
+	title, titleVariants := fm.variants.ExtractFromTitle(title)
+	variants = append(titleVariants, variants...)
+	fileInfo.Variant = musiclib.JoinVariants(variants)
+	if len(titleVariants) > 0 {
+		titleHasVariants = true
+		record("song title", title, musiclib.SourceFilename, token("title")+" without the variant markers", confidencePatternField)
+	}
+	if fileInfo.Variant != "" {
+		variantPlaces := append(placesOf(titleHasVariants, "the title"), placesOf(composerHasVariants, token("composer"))...)
+		record("variant", fileInfo.Variant, musiclib.SourceFilename, "variant markers in "+strings.Join(variantPlaces, ", "), confidencePatternField)
+	}
+	
+	if title != "" {
+		fileInfo.SongTitle = fm.SplitSongTitle(title)
+		record("song title", fileInfo.SongTitle, musiclib.SourceFilename, token("title")+" normalized", confidencePatternField)
+		fm.FileTitle(&fileInfo, fileInfo.SongTitle, confidencePatternField)
+	}
+	fm.DeriveWorkID(&fileInfo)
+	if field := match.Field("voicing"); field != "" {
+		if voicing := fm.GetVoicingFromFilename(field); voicing.Voicing != musiclib.VoicingUnknown {
+			fileInfo.Voicing = voicing.String()
+			fileInfo.VoicingConfidence = voicing.Confidence
+			record("voicing", fileInfo.Voicing, musiclib.SourceFilename, fmt.Sprintf("%q in %s", voicing.Matched, token("voicing")), voicing.Confidence)
+		}
+	}
+	
+	return fileInfo
+}
+
+// ApplyFileMetadata reads the metadata inside a file, which fills the
+// fields its name did not give and confirms those it did
+func (fm *FileMethods) ApplyFileMetadata(fileInfo *FileInfo, filePath string) {
+	if fileInfo.FileType == "PDF" {
+		fm.ApplyPDFMetadata(fileInfo, filePath)
+		return
+	}
+	format, ok := musiclib.FormatByName(fileInfo.FileType)
+	switch {
+	case ok && format.ReadAudio != nil:
+		fm.ApplyAudioMetadata(fileInfo, filePath, format)
+	case ok && format.ReadScore != nil:
+		fm.ApplyScoreMetadata(fileInfo, filePath, format)
+	}
+}
+
+// ApplyPDFMetadata reads the page count, page size, title and author of a
+// PDF. An encrypted or damaged PDF is flagged in its file status rather
+// than skipped.
+func (fm *FileMethods) ApplyPDFMetadata(fileInfo *FileInfo, filePath string) {
+	record := fileInfo.Provenance.Record
+	info, err := musiclib.InspectPDF(filePath)
+	switch {
+	case errors.Is(err, musiclib.ErrPDFEncrypted):
+		fileInfo.FileStatus = "encrypted"
+	case err != nil:
+		fileInfo.FileStatus = "damaged: " + err.Error()
+	case info.Repaired:
+		fileInfo.FileStatus = "repaired"
+	default:
+		fileInfo.FileStatus = "ok"
+	}
+	record("file status", fileInfo.FileStatus, musiclib.SourceFileContents, "structure of the PDF", 1.0)
+	if fileInfo.FileStatus != "ok" {
+		fm.flagged[filePath] = fileInfo.FileStatus
+	}
+	
+	if info.PageCount > 0 {
+		fileInfo.PageCount = strconv.Itoa(info.PageCount)
+		record("page count", fileInfo.PageCount, musiclib.SourceFileContents, "page tree", 1.0)
+	}
+	if pageSize := info.PageSize(); pageSize != "" {
+		fileInfo.PageSize = pageSize
+		record("page size", pageSize, musiclib.SourceFileContents, fmt.Sprintf("first page %.0f x %.0f pt", info.PageWidth, info.PageHeight), 1.0)
+	}
+	
+	if title := info.UsefulTitle(); title != "" {
+		fm.ApplyEmbeddedTitle(fileInfo, title, musiclib.SourcePDFMetadata, fmt.Sprintf("document title %q", info.Title), confidenceDocumentTitle)
+	}
+	fm.ApplyEmbeddedComposer(fileInfo, info.Author, musiclib.SourcePDFMetadata, fmt.Sprintf("document author %q", info.Author), confidenceDocumentTitle)
+	
+	if err == nil {
+		fm.ApplyFirstPageText(fileInfo, filePath)
+	}
+}
+
+// ApplyFirstPageText reads the title, voicing and credits printed on the
+// first page of a PDF. They are trusted less than the filename and the
+// document's metadata, so they fill only the fields those left blank, and
+// confirm the others when they agree. A scanned page has no text to read.
+func (fm *FileMethods) ApplyFirstPageText(fileInfo *FileInfo, filePath string) {
+	lines, err := musiclib.FirstPageText(filePath)
+	if err != nil || len(lines) == 0 {
+		return
+	}
+	credits := musiclib.ReadPageCredits(lines, fm.lexicon)
+	record := fileInfo.Provenance.Record
+	detail := func(field string) string {
+		return fmt.Sprintf("first page line %q", credits.Lines[field])
+	}
+	
+	if credits.Title != "" {
+		fm.ApplyEmbeddedTitle(fileInfo, credits.Title, musiclib.SourcePageText, detail("title"), confidenceFirstPage)
+	}
+	
+	if voicing := credits.Voicing; voicing.Voicing != musiclib.VoicingUnknown {
+		if fileInfo.Voicing == string(musiclib.VoicingUnknown) {
+			fileInfo.Voicing = voicing.String()
+			fileInfo.VoicingConfidence = min(voicing.Confidence, confidenceFirstPage)
+			record("voicing", fileInfo.Voicing, musiclib.SourcePageText, detail("voicing"), fileInfo.VoicingConfidence)
+		} else if fileInfo.Voicing == voicing.String() {
+			fileInfo.VoicingConfidence = max(fileInfo.VoicingConfidence, confidenceConfirmed)
+			record("voicing", fileInfo.Voicing, musiclib.SourcePageText, "confirmed by "+detail("voicing"), confidenceConfirmed)
+		}
+	}
+	
+	fm.ApplyCredits(fileInfo, credits.Composer, credits.Arranger, musiclib.SourcePageText, detail, confidenceFirstPage)
+	if credits.Accompaniment != "" && fileInfo.Accompaniment == "" {
+		fileInfo.Accompaniment = credits.Accompaniment
+		record("accompaniment", fileInfo.Accompaniment, musiclib.SourcePageText, detail("accompaniment"), confidenceFirstPage)
+	}
+}
+
+// ApplyAudioMetadata reads the tags and stream properties of an audio
+// file. Rehearsal tracks named "Track 01.mp3" get their song and part from
+// the tags alone.
+func (fm *FileMethods) ApplyAudioMetadata(fileInfo *FileInfo, filePath string, format musiclib.Format) {
+	record := fileInfo.Provenance.Record
+	info, err := format.ReadAudio(filePath)
+	if err != nil {
+		// Tags read before the error are still used
+		fileInfo.FileStatus = "damaged: " + err.Error()
+		fm.flagged[filePath] = fileInfo.FileStatus
+	} else {
+		fileInfo.FileStatus = "ok"
+	}
+	record("file status", fileInfo.FileStatus, musiclib.SourceFileContents, "structure of the "+format.Name+" stream", 1.0)
+	
+	tags := info.Tags
+	detail := func(tag, value string) string {
+		return fmt.Sprintf("%s %s %q", info.TagFormat, tag, value)
+	}
+	if duration := info.Properties.FormatDuration(); duration != "" {
+		fileInfo.Duration = duration
+		record("duration", duration, musiclib.SourceFileContents, fmt.Sprintf("%d Hz, %d kbit/s stream", info.Properties.SampleRate, info.Properties.Bitrate), 1.0)
+		fm.audio[fm.RelativePath(filePath)] = info
+	}
+	fileInfo.Media = "audio"
+	if info.HasVideo {
+		fileInfo.Media = "video"
+	}
+	record("media", fileInfo.Media, musiclib.SourceFileContents, "tracks of the "+format.Name+" file", 1.0)
+	if !info.Created.IsZero() {
+		fileInfo.RecordedDate = info.Created.Format("2006-01-02")
+		record("recorded date", fileInfo.RecordedDate, musiclib.SourceFileContents, "creation time of the movie header", 1.0)
+	}
+	if tags.Album != "" {
+		fileInfo.Album = tags.Album
+		record("album", tags.Album, musiclib.SourceAudioTags, detail("album", tags.Album), 1.0)
+	}
+	if tags.Track != "" {
+		fileInfo.TrackNumber = tags.Track
+		record("track number", tags.Track, musiclib.SourceAudioTags, detail("track", tags.Track), 1.0)
+	}
+	
+	title, parts := fm.parts.ExtractFromTitle(strings.Trim(tags.Title, " -,"))
+	title = strings.Trim(title, " -,(")
+	partDetail := "instrument vocabulary in the " + detail("title", tags.Title)
+	if tagParts := fm.parts.FindParts(tags.Part); len(tagParts) > 0 {
+		parts, partDetail = tagParts, "instrument vocabulary in the "+detail("part", tags.Part)
+	}
+	if len(parts) > 0 && fileInfo.Part == "" {
+		fileInfo.Part = musiclib.JoinParts(parts)
+		record("part", fileInfo.Part, musiclib.SourceAudioTags, partDetail, confidencePatternField)
+	}
+	if title != "" {
+		fm.ApplyEmbeddedTitle(fileInfo, title, musiclib.SourceAudioTags, detail("title", tags.Title), confidenceDocumentTitle)
+	}
+	// The artist of a rehearsal track is often the choir, so it is only
+	// used when the lexicon knows it as a composer
+	fm.ApplyEmbeddedComposer(fileInfo, tags.Composer, musiclib.SourceAudioTags, detail("composer", tags.Composer), confidenceDocumentTitle)
+	fm.ApplyEmbeddedComposer(fileInfo, tags.Artist, musiclib.SourceAudioTags, detail("artist", tags.Artist), confidenceDocumentTitle)
+}
+
+// ApplyEmbeddedTitle uses a title stored inside a file when its name gave
+// no title or only a placeholder such as "Scan 001" or "Track 01", and
+// otherwise lets a matching title confirm the one from the name
+func (fm *FileMethods) ApplyEmbeddedTitle(fileInfo *FileInfo, title, source, detail string, confidence float64) {
+	record := fileInfo.Provenance.Record
+	if fileInfo.SongTitle == "UNKNOWN" || musiclib.IsPlaceholderTitle(fileInfo.SongTitle) {
+		fileInfo.SongTitle = fm.SplitSongTitle(title)
+		record("song title", fileInfo.SongTitle, source, detail+" normalized", confidence)
+		fm.FileTitle(fileInfo, fileInfo.SongTitle, confidence)
+		fm.DeriveWorkID(fileInfo)
+	} else if musiclib.WorkID(title, "") == musiclib.WorkTitle(fileInfo.WorkID) {
+		record("song title", fileInfo.SongTitle, source, "confirmed by "+detail, confidenceConfirmed)
+	}
+}
+
+// ApplyEmbeddedComposer uses a name stored inside a file that the composer
+// lexicon knows when the filename gave no composer, and otherwise lets it
+// confirm the composer from the filename
+func (fm *FileMethods) ApplyEmbeddedComposer(fileInfo *FileInfo, name, source, detail string, confidence float64) {
+	if name == "" {
+		return
+	}
+	composer, ok := fm.lexicon.FindInFilename(name)
+	if !ok {
+		return
+	}
+	record := fileInfo.Provenance.Record
+	if fileInfo.ComposerOrArranger == "UNKNOWN" {
+		fileInfo.ComposerOrArranger = composer.Name
+		record("composer or arranger", composer.Name, source, detail+" matched to the lexicon", min(composer.Confidence, confidence))
+	} else if slices.Contains(strings.Split(fileInfo.ComposerOrArranger, ", "), composer.Name) {
+		record("composer or arranger", fileInfo.ComposerOrArranger, source, "confirmed by "+detail, confidenceConfirmed)
+	}
+}
+
+// ApplyCredits uses the composer and arranger credited inside a file. The
+// arranger fills "arranger" when it is blank. The composer is matched to
+// the lexicon as ApplyEmbeddedComposer does; a name the lexicon does not
+// know still fills a blank "composer or arranger" as written, or the
+// arranger's name does when no composer is credited. detail describes
+// where the "composer" or "arranger" credit was read.
+func (fm *FileMethods) ApplyCredits(fileInfo *FileInfo, composer, arranger, source string, detail func(field string) string, confidence float64) {
+	record := fileInfo.Provenance.Record
+	if composer != "" {
+		fm.ApplyEmbeddedComposer(fileInfo, composer, source, detail("composer"), confidence)
+	}
+	if arranger != "" && fileInfo.Arranger == "" {
+		fileInfo.Arranger = arranger
+		record("arranger", arranger, source, detail("arranger"), confidence)
+	}
+	if fileInfo.ComposerOrArranger == "UNKNOWN" {
+		name, field := composer, "composer"
+		if name == "" {
+			name, field = arranger, "arranger"
+		}
+		if name != "" {
+			fileInfo.ComposerOrArranger = name
+			record("composer or arranger", name, source, detail(field)+" as written", confidence)
+		}
+	}
+}
+
+// ApplyScoreMetadata reads the title, credits, signatures, length and part
+// ranges of a score. The voicing is implied by the names of its vocal
+// parts, and the range of each part is kept for the score_parts table. A
+// rehearsal MIDI file gets its part from the track it features.
+func (fm *FileMethods) ApplyScoreMetadata(fileInfo *FileInfo, filePath string, format musiclib.Format) {
+	record := fileInfo.Provenance.Record
+	score, err := format.ReadScore(filePath)
+	if err != nil {
+		fileInfo.FileStatus = "damaged: " + err.Error()
+		fm.flagged[filePath] = fileInfo.FileStatus
+		record("file status", fileInfo.FileStatus, musiclib.SourceFileContents, "structure of the "+format.Name+" score", 1.0)
+		return
+	}
+	fileInfo.FileStatus = "ok"
+	record("file status", fileInfo.FileStatus, musiclib.SourceFileContents, "structure of the "+format.Name+" score", 1.0)
+	detail := func(field string) string {
+		values := map[string]string{"title": score.Title, "composer": score.Composer, "arranger": score.Arranger, "lyricist": score.Lyricist}
+		return fmt.Sprintf("%s %s %q", score.Format, field, values[field])
+	}
+	
+	if score.Title != "" && !musiclib.IsPlaceholderTitle(score.Title) {
+		fm.ApplyEmbeddedTitle(fileInfo, score.Title, musiclib.SourceScore, detail("title"), confidenceDocumentTitle)
+	}
+	fm.ApplyCredits(fileInfo, score.Composer, score.Arranger, musiclib.SourceScore, detail, confidenceDocumentTitle)
+	if score.Lyricist != "" && fileInfo.Lyricist == "" {
+		fileInfo.Lyricist = score.Lyricist
+		record("lyricist", score.Lyricist, musiclib.SourceScore, detail("lyricist"), confidenceDocumentTitle)
+	}
+	if score.Copyright != "" {
+		fileInfo.Copyright = score.Copyright
+		record("copyright", score.Copyright, musiclib.SourceScore, score.Format+" copyright notice", 1.0)
+	}
+	if parts := fm.parts.FindParts(score.Part); len(parts) > 0 && fileInfo.Part == "" {
+		fileInfo.Part = musiclib.JoinParts(parts)
+		record("part", fileInfo.Part, musiclib.SourceScore, fmt.Sprintf("instrument vocabulary in the featured %s track %q", score.Format, score.Part), confidencePatternField)
+	}
+	
+	if voicing := score.Voicing(); voicing.Voicing != musiclib.VoicingUnknown {
+		partsDetail := fmt.Sprintf("%s parts %s", score.Format, voicing.Matched)
+		if fileInfo.Voicing == string(musiclib.VoicingUnknown) {
+			fileInfo.Voicing = voicing.String()
+			fileInfo.VoicingConfidence = voicing.Confidence
+			record("voicing", fileInfo.Voicing, musiclib.SourceScore, partsDetail, voicing.Confidence)
+		} else if fileInfo.Voicing == voicing.String() {
+			fileInfo.VoicingConfidence = max(fileInfo.VoicingConfidence, confidenceConfirmed)
+			record("voicing", fileInfo.Voicing, musiclib.SourceScore, "confirmed by "+partsDetail, confidenceConfirmed)
+		}
+	}
+	if fileInfo.Key == "" && score.Key.Tonic != "" {
+		fileInfo.Key = score.Key.String()
+		record("key", fileInfo.Key, musiclib.SourceScore, "first key signature", 1.0)
+	}
+	if score.Time != "" {
+		fileInfo.TimeSignature = score.Time
+		record("time signature", score.Time, musiclib.SourceScore, "first time signature", 1.0)
+	}
+	if tempo := score.FormatTempo(); tempo != "" {
+		fileInfo.Tempo = tempo
+		record("tempo", tempo, musiclib.SourceScore, fmt.Sprintf("%d tempo events", len(score.Tempos)), 1.0)
+	}
+	if duration := musiclib.FormatDuration(score.Duration); duration != "" {
+		fileInfo.Duration = duration
+		record("duration", duration, musiclib.SourceScore, "length of the longest track at its tempos", 1.0)
+	}
+	if score.Measures > 0 {
+		fileInfo.Measures = strconv.Itoa(score.Measures)
+		record("measures", fileInfo.Measures, musiclib.SourceScore, "measures of the longest part", 1.0)
+	}
+	if score.Pages > 0 && fileInfo.PageCount == "" {
+		fileInfo.PageCount = strconv.Itoa(score.Pages)
+		record("page count", fileInfo.PageCount, musiclib.SourceScore, fmt.Sprintf("%d page breaks", score.Pages-1), confidencePageBreaks)
+	}
+	if ranges := score.FormatRanges(); ranges != "" {
+		fileInfo.PartRanges = ranges
+		record("part ranges", ranges, musiclib.SourceScore, "lowest and highest note of each part", 1.0)
+	}
+	fm.scores[fm.RelativePath(filePath)] = score
+}
+
+// ReportFlaggedFiles lists the files whose contents could not be read, so
+// they can be replaced or unlocked
+func (fm *FileMethods) ReportFlaggedFiles() {
+	if len(fm.flagged) == 0 {
+		return
+	}
+	
+	paths := make([]string, 0, len(fm.flagged))
+	for path := range fm.flagged {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	
+	fmt.Printf("%d files are encrypted or damaged:\n", len(paths))
+	for _, path := range paths {
+		fmt.Printf("  %s: %s\n", fm.RelativePath(path), fm.flagged[path])
+	}
+}
+
+// placesOf returns place in a list when found is true, for describing
+// where a field's values were found
+func placesOf(found bool, place string) []string {
+	if !found {
+		return nil
+	}
+	return []string{place}
+}
+
+// Confidences of the field values whose rules do not score them
+const (
+	confidenceWholeFilename = 0.3
+	confidencePatternField  = 0.8
+	confidenceFolderRule    = 0.9
+	confidenceSeasonStart   = 0.5
+	confidencePublisherRule = 0.9
+	confidenceDocumentTitle = 0.6
+	confidenceFirstPage     = 0.5
+	confidencePageBreaks    = 0.8
+	confidenceConfirmed     = 0.95
+)
+
+// ExplainFile prints how each field of a file was derived, step by step,
+// followed by the final value of each field
+func (fm *FileMethods) ExplainFile(filePath string) {
+	fileInfo := fm.BuildFileInfo(filePath)
+	
+	fmt.Printf("\nDerivation of %s\n", filePath)
+	for i, step := range fileInfo.Provenance {
+		fmt.Printf("%3d. %s\n", i+1, step)
+	}
+	
+	fmt.Printf("\nFinal values\n")
+	for _, step := range fileInfo.Provenance.Final() {
+		fmt.Printf("  %-22s %q (%s, %.2f)\n", step.Field, step.Value, step.Source, step.Confidence)
+	}
+}
+
+// SaveProvenance writes where each field of each file came from to the
+// field_provenance table
+func (fm *FileMethods) SaveProvenance(files []FileInfo) error {
+	err := musiclib.ResetProvenanceTable(fm.db)
+	if err != nil {
+		return err
+	}
+	
+	logs := make(map[string]musiclib.ProvenanceLog, len(files))
+	for _, fileInfo := range files {
+		logs[fm.RelativePath(filepath.Join(fileInfo.FullPathToFolder, fileInfo.OriginalFilename))] = fileInfo.Provenance
+	}
+	return musiclib.SaveProvenance(fm.db, logs)
+}
+
+// SaveAudioProperties rebuilds the audio_properties table from the audio
+// files of the scan
+func (fm *FileMethods) SaveAudioProperties() error {
+	err := musiclib.ResetAudioPropertiesTable(fm.db)
+	if err != nil {
+		return err
+	}
+	return musiclib.SaveAudioProperties(fm.db, fm.audio)
+}
+
+// SaveScoreParts rebuilds the score_parts table from the score files of
+// the scan
+func (fm *FileMethods) SaveScoreParts() error {
+	err := musiclib.ResetScorePartsTable(fm.db)
+	if err != nil {
+		return err
+	}
+	return musiclib.SaveScoreParts(fm.db, fm.scores)
+}
+
+// LoadOverrides reads the librarians' corrections from the overrides table
+func (fm *FileMethods) LoadOverrides() error {
+	err := musiclib.CreateOverridesTable(fm.db)
+	if err != nil {
+		return err
+	}
+	
+	overrides, err := musiclib.LoadOverrides(fm.db)
+	if err != nil {
+		return err
+	}
+	
+	fm.overrides = overrides
+	fmt.Printf("Overrides: %d files\n", fm.overrides.Len())
+	return nil
+}
+
+// ContentHash returns the hash of a file's contents, reading each file once
+// per run. A file that cannot be read has no hash and is matched by path.
+func (fm *FileMethods) ContentHash(filePath string) string {
+	if hash, ok := fm.hashes[filePath]; ok {
+		return hash
+	}
+	
+	hash, err := musiclib.ContentHash(filePath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+	fm.hashes[filePath] = hash
+	return hash
+}
+
+// LookupOverrides returns the corrections of a file, found by the hash of
+// its contents or else by its path below the library root
+func (fm *FileMethods) LookupOverrides(filePath string) map[string]musiclib.OverrideValue {
+	if fm.overrides.Len() == 0 {
+		return nil
+	}
+	return fm.overrides.Lookup(fm.ContentHash(filePath), fm.RelativePath(filePath))
+}
+
+// ApplyOverrides replaces parsed fields with the corrections made for the
+// file. A corrected title is filed again unless its letter, sort key or
+// work id were corrected too.
+func (fm *FileMethods) ApplyOverrides(fileInfo *FileInfo, filePath string) {
+	overrides := fm.LookupOverrides(filePath)
+	if len(overrides) == 0 {
+		return
+	}
+	
+	fields := make([]string, 0, len(overrides))
+	for field := range overrides {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	
+	for _, field := range fields {
+		override := overrides[field]
+		target := fileInfo.Field(field)
+		if target == nil {
+			fmt.Printf("Skipping override of unknown field %q for %s\n", field, filePath)
+			continue
+		}
+		*target = override.Value
+		fileInfo.Provenance.Record(field, override.Value, musiclib.SourceOverride, "correction matched by "+override.MatchedBy, 1.0)
+		if field == "voicing" {
+			fileInfo.VoicingConfidence = 1.0
+		}
+	}
+	
+	if title, ok := overrides["song title"]; ok {
+		_, letterOverridden := overrides["alphabetizing letter"]
+		_, sortKeyOverridden := overrides["sort key"]
+		if !letterOverridden && !sortKeyOverridden {
+			fm.FileTitle(fileInfo, title.Value, 1.0)
+		}
+	}
+	if _, ok := overrides["work id"]; !ok {
+		fm.DeriveWorkID(fileInfo)
+	}
+	
+	fmt.Printf("Applied %d overrides to %s\n", len(overrides), filePath)
 }
 
-// NewDuckDBDatabase creates a new DuckDB database instance
-func NewDuckDBDatabase(dbName string) (*DuckDBDatabase, error) {
-	if dbName == "" {
-		dbName = "musiclibrary.duckdb"
+// SetOverride stores a correction of one field of a file, keyed by the
+// file's content hash and path. A corrected composer is confirmed in the
+// composer lexicon.
+func (fm *FileMethods) SetOverride(override musiclib.FileOverride) error {
+	for field := range override.Fields {
+		if (&FileInfo{}).Field(field) == nil {
+			return fmt.Errorf("error in override of '%s': unknown field %q", override.Path, field)
+		}
 	}
 	
-	db := &DuckDBDatabase{
-		DbName: dbName,
+	err := musiclib.CreateOverridesTable(fm.db)
+	if err != nil {
+		return err
 	}
 	
-	err := db.connect()
+	err = musiclib.SaveOverride(fm.db, override)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	
-	return db, nil
-}
-
-func (db *DuckDBDatabase) logSQLCallback(statement string) {
-	fmt.Printf("Executing SQL statement: %s\n", statement)
-}
-
-func (db *DuckDBDatabase) connect() error {
-	var err error
-	db.Connection, err = sql.Open("duckdb", db.DbName)
-	if err != nil {
-		return fmt.Errorf("error connecting to database: %v", err)
+	if composers, ok := override.Fields["composer or arranger"]; ok && fm.lexicon != nil {
+		for _, name := range strings.Split(composers, ", ") {
+			if name == "" || name == "UNKNOWN" {
+				continue
+			}
+			err = fm.lexicon.Confirm(fm.db, name)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
-func (db *DuckDBDatabase) Close() error {
-	if db.Connection != nil {
-		return db.Connection.Close()
+// OverrideFile corrects one field of a file from the command line
+func (fm *FileMethods) OverrideFile(filePath, field, value string) error {
+	override := musiclib.FileOverride{
+		Path:   fm.RelativePath(filePath),
+		Hash:   fm.ContentHash(filePath),
+		Fields: map[string]string{field: value},
 	}
-	return nil
-}
-
-func (db *DuckDBDatabase) CreateTable(tableName, columns string) error {
-	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", tableName, columns)
-	_, err := db.Connection.Exec(query)
+	
+	err := fm.SetOverride(override)
 	if err != nil {
-		return fmt.Errorf("error creating table: %v", err)
+		return err
 	}
+	
+	fmt.Printf("Override saved: %s %s = %q\n", override.Path, field, value)
 	return nil
 }
 
-func (db *DuckDBDatabase) InsertData(tableName string, data []interface{}) error {
-	placeholders := strings.Repeat("?,", len(data))
-	placeholders = strings.TrimSuffix(placeholders, ",")
-	
-	fmt.Printf("Data: %+v\n", data)
-	
-	query := fmt.Sprintf("INSERT INTO %s VALUES (%s)", tableName, placeholders)
-	fmt.Printf("Query: %s\n", query)
+// ExportOverrides writes the corrections in the overrides table to a YAML
+// file that can be reviewed and kept in git
+func (fm *FileMethods) ExportOverrides(yamlFilename string) error {
+	err := fm.LoadOverrides()
+	if err != nil {
+		return err
+	}
 	
-	_, err := db.Connection.Exec(query, data...)
+	err = musiclib.WriteOverridesFile(yamlFilename, fm.overrides.Files())
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
 		return err
 	}
+	
+	fmt.Printf("Exported overrides of %d files to %s\n", fm.overrides.Len(), yamlFilename)
 	return nil
 }
 
-func (db *DuckDBDatabase) FetchAll(tableName, condition string) ([][]interface{}, error) {
-	query := fmt.Sprintf("SELECT * FROM %s %s", tableName, condition)
-	rows, err := db.Connection.Query(query)
+// ImportOverrides stores the corrections of a YAML overrides file in the
+// overrides table
+func (fm *FileMethods) ImportOverrides(yamlFilename string) error {
+	overrides, err := musiclib.ReadOverridesFile(yamlFilename)
 	if err != nil {
-		fmt.Printf("Error fetching data: %v\n", err)
-		return nil, err
-	}
-	defer rows.Close()
-	
-	columns, err := rows.Columns()
-	if err != nil {
-		return nil, err
+		return err
 	}
 	
-	var result [][]interface{}
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-		
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, err
+	for _, override := range overrides {
+		err = fm.SetOverride(override)
+		if err != nil {
+			return err
 		}
-		
-		result = append(result, values)
 	}
 	
-	return result, nil
+	fmt.Printf("Imported overrides of %d files from %s\n", len(overrides), yamlFilename)
+	return nil
 }
 
-func (db *DuckDBDatabase) ExecuteQuery(query string, params ...interface{}) error {
-	_, err := db.Connection.Exec(query, params...)
-	if err != nil {
-		return fmt.Errorf("error executing query: %v", err)
+// overrideCSVRow applies the corrections of the file a CSV row describes,
+// so values edited in the overrides table win over the CSV on import
+func (fm *FileMethods) overrideCSVRow(header, row []string) {
+	var folder, filename string
+	for i, name := range header {
+		switch name {
+		case "full path to folder":
+			folder = row[i]
+		case "original filename":
+			filename = row[i]
+		}
+	}
+	if filename == "" {
+		return
+	}
+	
+	overrides := fm.LookupOverrides(filepath.Join(folder, filename))
+	for i, name := range header {
+		if override, ok := overrides[name]; ok {
+			row[i] = override.Value
+		}
 	}
-	return nil
 }
 
-// FileMethods represents file processing methods
-type FileMethods struct {
-	BaseDir       string
-	DbColumnNames string
-	db            *DuckDBDatabase
+// FileInfo represents the structure for JSON output
+type FileInfo struct {
+	AlphabetizingLetter string  `json:"alphabetizing letter"`
+	SortKey             string  `json:"sort key"`
+	FullPathToFolder    string  `json:"full path to folder"`
+	OriginalFilename    string  `json:"original filename"`
+	SongTitle           string  `json:"song title"`
+	Voicing             string  `json:"voicing"`
+	VoicingConfidence   float64 `json:"voicing confidence"`
+	Key                 string  `json:"key"`
+	TimeSignature       string  `json:"time signature"`
+	Tempo               string  `json:"tempo"`
+	Part                string  `json:"part"`
+	Variant             string  `json:"variant"`
+	ComposerOrArranger  string  `json:"composer or arranger"`
+	Arranger            string  `json:"arranger"`
+	Accompaniment       string  `json:"accompaniment"`
+	Lyricist            string  `json:"lyricist"`
+	Copyright           string  `json:"copyright"`
+	Publisher           string  `json:"publisher"`
+	CatalogNumber       string  `json:"catalog number"`
+	Source              string  `json:"source"`
+	FileType            string  `json:"file type"`
+	PageCount           string  `json:"page count"`
+	PageSize            string  `json:"page size"`
+	FileStatus          string  `json:"file status"`
+	Album               string  `json:"album"`
+	TrackNumber         string  `json:"track number"`
+	Duration            string  `json:"duration"`
+	Media               string  `json:"media"`
+	RecordedDate        string  `json:"recorded date"`
+	Measures            string  `json:"measures"`
+	PartRanges          string  `json:"part ranges"`
+	FileCreateDate      string  `json:"file create date"`
+	FileModifiedDate    string  `json:"file modified date"`
+	FirstSeen           string  `json:"first seen"`
+	LibraryType         string  `json:"library type"`
+	Season              string  `json:"season"`
+	ConcertYear         string  `json:"concert year"`
+	AcquiredDate        string  `json:"acquired date"`
+	MatchedPattern      string  `json:"matched pattern"`
+	WorkID              string  `json:"work id"`
+	
+	// Provenance records where each field came from; it is saved to the
+	// field_provenance table rather than the CSV and JSON files
+	Provenance musiclib.ProvenanceLog `json:"-"`
 }
 
-// NewFileMethods creates a new FileMethods instance
-func NewFileMethods(baseDir string) *FileMethods {
-	return &FileMethods{
-		BaseDir:       baseDir,
-		DbColumnNames: "id INTEGER PRIMARY KEY, alphabetizing_letter TEXT, full_path_to_folder TEXT, original_filename TEXT, song_title TEXT, voicing TEXT, composer_or_arranger TEXT, file_type TEXT, file_create_date TEXT, library_type TEXT",
+// Field returns the text field of a FileInfo named by its CSV keyword, or
+// nil when there is no such field
+func (fileInfo *FileInfo) Field(name string) *string {
+	switch name {
+	case "alphabetizing letter":
+		return &fileInfo.AlphabetizingLetter
+	case "sort key":
+		return &fileInfo.SortKey
+	case "full path to folder":
+		return &fileInfo.FullPathToFolder
+	case "original filename":
+		return &fileInfo.OriginalFilename
+	case "song title":
+		return &fileInfo.SongTitle
+	case "voicing":
+		return &fileInfo.Voicing
+	case "key":
+		return &fileInfo.Key
+	case "time signature":
+		return &fileInfo.TimeSignature
+	case "tempo":
+		return &fileInfo.Tempo
+	case "part":
+		return &fileInfo.Part
+	case "variant":
+		return &fileInfo.Variant
+	case "composer or arranger":
+		return &fileInfo.ComposerOrArranger
+	case "arranger":
+		return &fileInfo.Arranger
+	case "accompaniment":
+		return &fileInfo.Accompaniment
+	case "lyricist":
+		return &fileInfo.Lyricist
+	case "copyright":
+		return &fileInfo.Copyright
+	case "publisher":
+		return &fileInfo.Publisher
+	case "catalog number":
+		return &fileInfo.CatalogNumber
+	case "source":
+		return &fileInfo.Source
+	case "file type":
+		return &fileInfo.FileType
+	case "page count":
+		return &fileInfo.PageCount
+	case "page size":
+		return &fileInfo.PageSize
+	case "file status":
+		return &fileInfo.FileStatus
+	case "album":
+		return &fileInfo.Album
+	case "track number":
+		return &fileInfo.TrackNumber
+	case "duration":
+		return &fileInfo.Duration
+	case "media":
+		return &fileInfo.Media
+	case "recorded date":
+		return &fileInfo.RecordedDate
+	case "measures":
+		return &fileInfo.Measures
+	case "part ranges":
+		return &fileInfo.PartRanges
+	case "file create date":
+		return &fileInfo.FileCreateDate
+	case "file modified date":
+		return &fileInfo.FileModifiedDate
+	case "first seen":
+		return &fileInfo.FirstSeen
+	case "library type":
+		return &fileInfo.LibraryType
+	case "season":
+		return &fileInfo.Season
+	case "concert year":
+		return &fileInfo.ConcertYear
+	case "acquired date":
+		return &fileInfo.AcquiredDate
+	case "matched pattern":
+		return &fileInfo.MatchedPattern
+	case "work id":
+		return &fileInfo.WorkID
 	}
+	return nil
 }
 
-func (fm *FileMethods) FindFilesRecursively(directoryPath string) ([]string, error) {
-	var fileLst []string
-	
-	err := filepath.WalkDir(directoryPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if !d.IsDir() {
-			fileLst = append(fileLst, path)
-		}
-		return nil
-	})
-	
-	return fileLst, err
+// Work groups the files that belong to one piece, such as its score and
+// parts. Recordings lists the recordings of the piece: those titled with
+// it and those of a concert it was performed at.
+type Work struct {
+	WorkID     string        `json:"work id"`
+	SongTitle  string        `json:"song title"`
+	Parts      []string      `json:"parts"`
+	Files      []string      `json:"files"`
+	Variants   []WorkVariant `json:"variants"`
+	Recordings []string      `json:"recordings"`
+	MIDIFiles  []string      `json:"midi files"`
 }
 
-func (fm *FileMethods) FindFilesInDirectory(directoryPath string) ([]string, error) {
-	var filesList []string
-	
-	entries, err := os.ReadDir(directoryPath)
-	if err != nil {
-		return nil, fmt.Errorf("error: Directory not found at '%s': %v", directoryPath, err)
-	}
-	
-	for _, entry := range entries {
-		fullPath := filepath.Join(directoryPath, entry.Name())
-		fmt.Printf("Full path: %s\n", fullPath)
-		filesList = append(filesList, fullPath)
-	}
-	
-	return filesList, nil
+// WorkVariant lists the files of one version of a work, such as the
+// "With Cuts" edition. Files without a variant marker are only listed
+// under the work.
+type WorkVariant struct {
+	Label string   `json:"label"`
+	Files []string `json:"files"`
 }
 
-func (fm *FileMethods) GetFullPathToFolder(directoryPath, fileName string) string {
-	return filepath.Join(directoryPath, fileName)
+// MasterJSONFile represents the complete JSON structure
+type MasterJSONFile struct {
+	Files []FileInfo `json:"files"`
+	Works []Work     `json:"works"`
 }
 
-func (fm *FileMethods) SplitFilename(filenameToSplit, separator string) []string {
-	fmt.Printf("Filename to split: %s\n", filenameToSplit)
-	
-	// Use regex to split by space, underscore, or dot
-	re := regexp.MustCompile(`[ _.]`)
-	splitFileLst := re.Split(filenameToSplit, -1)
-	
-	fmt.Printf("Split file list: %+v\n", splitFileLst)
-	fmt.Printf("Length of splitFileLst is: %d\n", len(splitFileLst))
-	
-	return splitFileLst
+// SortFiles puts files in catalog order: by sort key, then by folder and
+// filename so the order is the same on every scan
+func SortFiles(files []FileInfo) {
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].SortKey != files[j].SortKey {
+			return files[i].SortKey < files[j].SortKey
+		}
+		if files[i].FullPathToFolder != files[j].FullPathToFolder {
+			return files[i].FullPathToFolder < files[j].FullPathToFolder
+		}
+		return files[i].OriginalFilename < files[j].OriginalFilename
+	})
 }
 
-func (fm *FileMethods) SplitSongTitle(songTitle string) string {
-	fmt.Printf("Song title: %s\n", songTitle)
-	
-	// Add space before capital letters (except at the beginning)
-	// Go doesn't support lookbehind, so we need to use a different approach
-	if len(songTitle) <= 1 {
-		return songTitle
+// GroupWorks collects files with the same work ID, in the order each work
+// was first seen. A file that credits no composer joins the work of its
+// title when only one composer's work has that title, so an uncredited
+// part of Biebl's "Ave Maria" joins it while Bruckner's stays apart. A
+// recording titled with a piece joins its work; one of a
+// whole concert, found by its season and concert year, is linked to every
+// piece performed at that concert. A rehearsal MIDI file titled with a
+// piece joins its work, and one named only for its part, such as
+// "Alto.mid", joins the work of the one score in its folder.
+func (fm *FileMethods) GroupWorks(files []FileInfo) []Work {
+	var works []Work
+	index := make(map[string]int)
+	credited := make(map[string][]string) // title -> work ids naming a composer
+	for _, fileInfo := range files {
+		title := musiclib.WorkTitle(fileInfo.WorkID)
+		if fileInfo.WorkID != title && !slices.Contains(credited[title], fileInfo.WorkID) {
+			credited[title] = append(credited[title], fileInfo.WorkID)
+		}
 	}
-	
-	// Start with the first character, then iterate through the rest
-	result := string(songTitle[0])
-	for _, r := range songTitle[1:] {
-		if unicode.IsUpper(r) {
-			// Add a space before uppercase letters
-			result += " "
+	workOf := func(fileInfo FileInfo) string {
+		if ids := credited[fileInfo.WorkID]; len(ids) == 1 {
+			return ids[0]
 		}
-		result += string(r)
+		return fileInfo.WorkID
 	}
-	
-	fmt.Printf("Split song title: %s\n", result)
-	return result
-}
-
-func (fm *FileMethods) GetAlphabetizerLetterFromFilename(filename string) string {
-	if len(filename) == 0 {
-		return ""
+	concerts := make(map[string][]int) // concert year and season -> works
+	folders := make(map[string][]int)  // folder -> works with a score in it
+	join := func(i int, fileInfo FileInfo) {
+		path := filepath.Join(fileInfo.FullPathToFolder, fileInfo.OriginalFilename)
+		works[i].Files = append(works[i].Files, path)
+		if fileInfo.Part != "" {
+			works[i].Parts = append(works[i].Parts, fileInfo.Part)
+		}
+		if fileInfo.Variant != "" {
+			works[i].Variants = addWorkVariant(works[i].Variants, fileInfo.Variant, path)
+		}
 	}
-	
-	alphaLetter := string(filename[0])
-	if unicode.IsDigit(rune(filename[0])) {
-		for _, r := range filename {
-			if unicode.IsLetter(r) {
-				alphaLetter = string(r)
-				break
-			}
+	add := func(fileInfo FileInfo) int {
+		workID := workOf(fileInfo)
+		i, ok := index[workID]
+		if !ok {
+			i = len(works)
+			index[workID] = i
+			works = append(works, Work{WorkID: workID, SongTitle: fileInfo.SongTitle})
 		}
+		join(i, fileInfo)
+		return i
 	}
 	
-	fmt.Printf("Alpha letter: %s\n", alphaLetter)
-	return strings.ToUpper(alphaLetter)
-}
-
-func (fm *FileMethods) GetExtensionFromFilename(composerArranger string) (string, string) {
-	fmt.Printf("Composer/arranger: %s\n", composerArranger)
-	
-	ext := filepath.Ext(composerArranger)
-	composer := strings.TrimSuffix(composerArranger, ext)
-	
-	fmt.Printf("Composer: %s\n", composer)
-	fmt.Printf("Extension: %s\n", ext)
-	
-	return composer, ext
-}
-
-func (fm *FileMethods) GetFileCreationDateFromFilename(dtFilename string) string {
-	formattedDt := ""
-	
-	info, err := os.Stat(dtFilename)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		fmt.Printf("Error: File not found at '%s'\n", dtFilename)
-		return formattedDt
+	var recordings, midiFiles []FileInfo
+	for _, fileInfo := range files {
+		if fileInfo.WorkID == "" {
+			continue
+		}
+		if IsRecording(fileInfo) {
+			recordings = append(recordings, fileInfo)
+			continue
+		}
+		if fileInfo.FileType == "MIDI" {
+			midiFiles = append(midiFiles, fileInfo)
+			continue
+		}
+		i := add(fileInfo)
+		if format, ok := musiclib.FormatByName(fileInfo.FileType); ok && format.Kind != musiclib.KindAudio && !slices.Contains(folders[fileInfo.FullPathToFolder], i) {
+			folders[fileInfo.FullPathToFolder] = append(folders[fileInfo.FullPathToFolder], i)
+		}
+		// Schedules and receipts in a concert folder were not performed
+		isMusic := fileInfo.Voicing != "UNKNOWN" || fileInfo.ComposerOrArranger != "UNKNOWN"
+		if concert := ConcertOf(fileInfo); concert != "" && isMusic && !slices.Contains(concerts[concert], i) {
+			concerts[concert] = append(concerts[concert], i)
+		}
 	}
 	
-	modTime := info.ModTime()
-	formattedDt = modTime.Format("2006-01-02")
-	
-	fmt.Printf("Formatted date: %s\n", formattedDt)
-	return formattedDt
-}
-
-func (fm *FileMethods) GetVoicingFromParsedFilename(listToParse interface{}) string {
-	fmt.Printf("List to parse: %+v\n", listToParse)
-	
-	voicing := ""
-	voicings := []string{
-		"SATB", "SSATB", "SSAATTBB", "SAB", "SA", "SSA", "SAA", 
-		"SSAA", "TB", "TTB", "TBB", "TTBB",
+	linked := 0
+	for _, recording := range recordings {
+		path := filepath.Join(recording.FullPathToFolder, recording.OriginalFilename)
+		if i, ok := index[workOf(recording)]; ok {
+			add(recording)
+			works[i].Recordings = append(works[i].Recordings, path)
+			linked++
+			continue
+		}
+		performed := concerts[ConcertOf(recording)]
+		if len(performed) == 0 {
+			add(recording)
+			continue
+		}
+		for _, i := range performed {
+			works[i].Recordings = append(works[i].Recordings, path)
+		}
+		linked++
 	}
 	
-	switch v := listToParse.(type) {
-	case []string:
-		for _, entry := range voicings {
-			for _, item := range v {
-				if strings.Contains(strings.ToUpper(item), entry) {
-					voicing = entry
-					return voicing
-				}
-			}
+	linkedMIDI := 0
+	for _, midi := range midiFiles {
+		i, ok := index[workOf(midi)]
+		if !ok && len(folders[midi.FullPathToFolder]) == 1 {
+			i, ok = folders[midi.FullPathToFolder][0], true
 		}
-		voicing = "UNKNOWN"
-	case string:
-		upperStr := strings.ToUpper(v)
-		for _, entry := range voicings {
-			if strings.Contains(upperStr, entry) {
-				voicing = entry
-				return voicing
-			}
+		if ok {
+			join(i, midi)
+			linkedMIDI++
+		} else {
+			i = add(midi)
 		}
-		voicing = fmt.Sprintf("Unable to find any of these voicings %+v in the following string '%s'", voicings, v)
-	default:
-		voicing = "Error: The variable listToParse must be a string or a slice"
+		works[i].MIDIFiles = append(works[i].MIDIFiles, filepath.Join(midi.FullPathToFolder, midi.OriginalFilename))
 	}
 	
-	return voicing
+	fmt.Printf("Grouped %d files into %d works, linking %d of %d recordings and %d of %d MIDI files\n", len(files), len(works), linked, len(recordings), linkedMIDI, len(midiFiles))
+	return works
 }
 
-func (fm *FileMethods) GetFileTypeFromFilePath(filepath string) string {
-	ext := strings.ToLower(filepath)
-	
-	switch {
-	case strings.Contains(ext, "pdf"):
-		return "PDF"
-	case strings.Contains(ext, "mp3"):
-		return "MP3"
-	case strings.Contains(ext, "ogg"):
-		return "OGG"
-	case strings.Contains(ext, "wma"):
-		return "WMA"
-	case strings.Contains(ext, "mp4"):
-		return "MP4"
-	default:
-		return "UNKNOWN"
-	}
+// IsRecording reports whether a file is a recorded performance. MP4 files
+// are recordings; M4A and the other audio files are rehearsal tracks.
+func IsRecording(fileInfo FileInfo) bool {
+	return fileInfo.FileType == "MP4"
 }
 
-func (fm *FileMethods) GetLibraryTypeFromFilePath(filepath string) string {
-	libraryType := strings.ToLower(filepath)
-	
-	switch {
-	case strings.Contains(libraryType, "christmas"):
-		return "Christmas"
-	case strings.Contains(libraryType, "spring"):
-		return "Spring"
-	case strings.Contains(libraryType, "repertoire"):
-		return "Repertoire"
-	default:
-		return "UNKNOWN"
+// ConcertOf names the concert a file belongs to, such as "2019 Spring", or
+// returns "" for a file outside the concert folders
+func ConcertOf(fileInfo FileInfo) string {
+	if fileInfo.ConcertYear == "" {
+		return ""
 	}
+	return strings.TrimSpace(fileInfo.ConcertYear + " " + fileInfo.Season)
 }
 
-// FileInfo represents the structure for JSON output
-type FileInfo struct {
-	AlphabetizingLetter string `json:"alphabetizing letter"`
-	FullPathToFolder    string `json:"full path to folder"`
-	OriginalFilename    string `json:"original filename"`
-	SongTitle           string `json:"song title"`
-	Voicing             string `json:"voicing"`
-	ComposerOrArranger  string `json:"composer or arranger"`
-	FileType            string `json:"file type"`
-	FileCreateDate      string `json:"file create date"`
-	LibraryType         string `json:"library type"`
-}
-
-// MasterJSONFile represents the complete JSON structure
-type MasterJSONFile struct {
-	Files []FileInfo `json:"files"`
+// addWorkVariant adds a file to the variant with the given label, creating
+// the variant the first time it is seen
+func addWorkVariant(variants []WorkVariant, label, path string) []WorkVariant {
+	for i := range variants {
+		if variants[i].Label == label {
+			variants[i].Files = append(variants[i].Files, path)
+			return variants
+		}
+	}
+	return append(variants, WorkVariant{Label: label, Files: []string{path}})
 }
 
 func (fm *FileMethods) WriteCSVOutputFile(inputJSON MasterJSONFile, outputPath, outputFilename string, fieldnames []string) error {
@@ -375,14 +1539,45 @@ func (fm *FileMethods) WriteCSVOutputFile(inputJSON MasterJSONFile, outputPath,
 	for _, fileInfo := range inputJSON.Files {
 		row := []string{
 			fileInfo.AlphabetizingLetter,
+			fileInfo.SortKey,
 			fileInfo.FullPathToFolder,
 			fileInfo.OriginalFilename,
 			fileInfo.SongTitle,
 			fileInfo.Voicing,
+			musiclib.FormatConfidence(fileInfo.VoicingConfidence),
+			fileInfo.Key,
+			fileInfo.TimeSignature,
+			fileInfo.Tempo,
+			fileInfo.Part,
+			fileInfo.Variant,
 			fileInfo.ComposerOrArranger,
+			fileInfo.Arranger,
+			fileInfo.Accompaniment,
+			fileInfo.Lyricist,
+			fileInfo.Copyright,
+			fileInfo.Publisher,
+			fileInfo.CatalogNumber,
+			fileInfo.Source,
 			fileInfo.FileType,
+			fileInfo.PageCount,
+			fileInfo.PageSize,
+			fileInfo.FileStatus,
+			fileInfo.Album,
+			fileInfo.TrackNumber,
+			fileInfo.Duration,
+			fileInfo.Media,
+			fileInfo.RecordedDate,
+			fileInfo.Measures,
+			fileInfo.PartRanges,
 			fileInfo.FileCreateDate,
+			fileInfo.FileModifiedDate,
+			fileInfo.FirstSeen,
 			fileInfo.LibraryType,
+			fileInfo.Season,
+			fileInfo.ConcertYear,
+			fileInfo.AcquiredDate,
+			fileInfo.MatchedPattern,
+			fileInfo.WorkID,
 		}
 		if err := writer.Write(row); err != nil {
 			return err
@@ -393,18 +1588,24 @@ func (fm *FileMethods) WriteCSVOutputFile(inputJSON MasterJSONFile, outputPath,
 	return nil
 }
 
-func (fm *FileMethods) ImportCSVFileIntoDB(csvFilename, databaseFilename, tableName string) error {
-	var err error
-	fm.db, err = NewDuckDBDatabase(databaseFilename)
+// ImportCSVFileIntoDB replaces the catalog in tableName with a scan. The
+// scan is loaded into a table of its own and swapped in only when every row
+// went in, so a failed import leaves the old catalog as it was, and the new
+// catalog has the current columns whatever columns the old one had. What
+// must outlive a scan, such as the first-seen dates, is kept in tables of
+// its own.
+func (fm *FileMethods) ImportCSVFileIntoDB(csvFilename, tableName string) error {
+	scanTable := tableName + "_scan"
+	err := fm.db.ExecuteQuery("DROP TABLE IF EXISTS " + scanTable)
 	if err != nil {
 		return err
 	}
-	defer fm.db.Close()
 	
-	err = fm.db.CreateTable("music_library", fm.DbColumnNames)
+	err = fm.db.CreateTable(scanTable, fm.DbColumnNames)
 	if err != nil {
 		return err
 	}
+	defer fm.db.ExecuteQuery("DROP TABLE IF EXISTS " + scanTable)
 	
 	file, err := os.Open(csvFilename)
 	if err != nil {
@@ -424,6 +1625,7 @@ func (fm *FileMethods) ImportCSVFileIntoDB(csvFilename, databaseFilename, tableN
 		fmt.Printf("Header: %+v\n", header)
 		
 		for i, row := range records[1:] {
+			fm.overrideCSVRow(header, row)
 			fmt.Printf("Row: %+v\n", row)
 			
 			// Insert ID at the beginning
@@ -434,14 +1636,19 @@ func (fm *FileMethods) ImportCSVFileIntoDB(csvFilename, databaseFilename, tableN
 			}
 			fmt.Printf("Data with ID: %+v\n", data)
 			
-			err = fm.db.InsertData(tableName, data)
+			err = fm.db.InsertData(scanTable, data)
 			if err != nil {
 				fmt.Printf("Database Error has occurred: %v\n", err)
-				continue
+				return fmt.Errorf("error importing line %d of %s, the catalog in %s is unchanged: %v", i+2, csvFilename, tableName, err)
 			}
 		}
 		
-		allRows, err := fm.db.FetchAll("music_library", "")
+		err = fm.ReplaceTable(tableName, scanTable)
+		if err != nil {
+			return err
+		}
+		
+		allRows, err := fm.db.FetchAll(tableName, "ORDER BY sort_key, original_filename")
 		if err != nil {
 			return err
 		}
@@ -452,31 +1659,163 @@ func (fm *FileMethods) ImportCSVFileIntoDB(csvFilename, databaseFilename, tableN
 	return nil
 }
 
+// ReplaceTable drops a table and renames another to take its place, in one
+// transaction so the table is never missing
+func (fm *FileMethods) ReplaceTable(tableName, newTable string) error {
+	tx, err := fm.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error replacing %s: %v", tableName, err)
+	}
+	defer tx.Rollback()
+	
+	_, err = tx.Exec("DROP TABLE IF EXISTS " + tableName)
+	if err != nil {
+		return fmt.Errorf("error replacing %s: %v", tableName, err)
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", newTable, tableName))
+	if err != nil {
+		return fmt.Errorf("error replacing %s: %v", tableName, err)
+	}
+	return tx.Commit()
+}
+
 func main() {
 	// Command line arguments
 	dirpath := flag.String("d", `C:\Users\ggivl\Documents\PythonDevelopment\FortyNinersDevelopment\49ersMusicLibrary`, "Path to the directory of the files to parsed")
-	extension := flag.String("e", ".pdf", "Extension of the files to parse")
+	extension := flag.String("e", ".pdf,.musicxml,.mxl,.mscz,.mscx,.mid,.midi,.abc,.ly,.mp3,.ogg,.opus,.flac,.m4a,.mp4,.wma", "Extensions of the files to parse, separated by commas")
 	outputCSV := flag.String("o", "csv_output_full.csv", "CSV output file")
-	dbname := flag.String("b", "musiclibrary.duckdb", "Database filename")
+	dbname := flag.String("b", "", "Database filename, overriding the one named in the configuration file; a .duckdb file is opened with DuckDB and a .db file with SQLite")
+	configFile := flag.String("c", "config.yml", "Configuration file with the filename patterns")
+	folderReport := flag.String("r", "unmatched_folders.txt", "Report of folders that matched no path rule")
+	exportOverrides := flag.String("export-overrides", "", "Write the overrides in the database to this YAML file and exit")
+	importOverrides := flag.String("import-overrides", "", "Read overrides from this YAML file into the database and exit")
+	renormalize := flag.Bool("renormalize", false, "Re-normalize the song titles already in the database and exit")
 	flag.Parse()
 	
-	fileExt := *extension
+	fileExts := strings.Split(*extension, ",")
 	
 	keywords := []string{
 		"alphabetizing letter",
+		"sort key",
 		"full path to folder", 
 		"original filename",
 		"song title",
 		"voicing",
+		"voicing confidence",
+		"key",
+		"time signature",
+		"tempo",
+		"part",
+		"variant",
 		"composer or arranger",
+		"arranger",
+		"accompaniment",
+		"lyricist",
+		"copyright",
+		"publisher",
+		"catalog number",
+		"source",
 		"file type",
+		"page count",
+		"page size",
+		"file status",
+		"album",
+		"track number",
+		"duration",
+		"media",
+		"recorded date",
+		"measures",
+		"part ranges",
 		"file create date",
+		"file modified date",
+		"first seen",
 		"library type",
+		"season",
+		"concert year",
+		"acquired date",
+		"matched pattern",
+		"work id",
 	}
 	
 	fmt.Printf("Directory path: %s\n", *dirpath)
 	
-	fileMethods := NewFileMethods(*dirpath)
+	config, err := musiclib.LoadConfig(*configFile)
+	if err != nil {
+		log.Printf("Using default configuration: %v", err)
+	}
+	
+	backend, databaseFilename := config.StoreFile(*dbname)
+	store, err := musiclib.OpenStore(backend, databaseFilename)
+	if err != nil {
+		log.Fatalf("Error opening %s database: %v", backend, err)
+	}
+	defer store.Close()
+	fmt.Printf("Database: %s\n", strings.TrimSpace(backend+" "+databaseFilename))
+	
+	fileMethods, err := NewFileMethods(*dirpath, config, store)
+	if err != nil {
+		log.Fatalf("Error loading filename patterns: %v", err)
+	}
+	
+	if *renormalize {
+		err = fileMethods.LoadOverrides()
+		if err != nil {
+			log.Fatalf("Error loading overrides: %v", err)
+		}
+		err = fileMethods.RenormalizeTitlesInDB("music_library")
+		if err != nil {
+			log.Fatalf("Error re-normalizing titles: %v", err)
+		}
+		return
+	}
+	
+	err = fileMethods.LoadComposerLexicon(config.ComposerLexicon.SeedFile)
+	if err != nil {
+		log.Printf("Error loading composer lexicon: %v", err)
+	}
+	
+	err = fileMethods.LoadFileHistory()
+	if err != nil {
+		log.Printf("Error loading file history: %v", err)
+	}
+	
+	err = fileMethods.LoadOverrides()
+	if err != nil {
+		log.Printf("Error loading overrides: %v", err)
+	}
+	
+	switch flag.Arg(0) {
+	case "explain":
+		if flag.NArg() < 2 {
+			log.Fatalf("Usage: walk_demo [flags] explain <file>")
+		}
+		fileMethods.ExplainFile(flag.Arg(1))
+		return
+	case "override":
+		if flag.NArg() < 4 {
+			log.Fatalf("Usage: walk_demo [flags] override <file> <field> <value>")
+		}
+		err = fileMethods.OverrideFile(flag.Arg(1), flag.Arg(2), flag.Arg(3))
+		if err != nil {
+			log.Fatalf("Error saving override: %v", err)
+		}
+		return
+	}
+	
+	if *exportOverrides != "" {
+		err = fileMethods.ExportOverrides(*exportOverrides)
+		if err != nil {
+			log.Fatalf("Error exporting overrides: %v", err)
+		}
+		return
+	}
+	if *importOverrides != "" {
+		err = fileMethods.ImportOverrides(*importOverrides)
+		if err != nil {
+			log.Fatalf("Error importing overrides: %v", err)
+		}
+		return
+	}
 	
 	// Find files recursively
 	fileLst, err := fileMethods.FindFilesRecursively(*dirpath)
@@ -488,7 +1827,7 @@ func main() {
 	
 	var pdfFileLst []string
 	for _, filename := range fileLst {
-		if strings.HasSuffix(filename, fileExt) {
+		if slices.ContainsFunc(fileExts, func(ext string) bool { return strings.HasSuffix(filename, strings.TrimSpace(ext)) }) {
 			fmt.Printf("PDF filename: %s\n", filename)
 			pdfFileLst = append(pdfFileLst, filename)
 		}
@@ -496,49 +1835,7 @@ func main() {
 	
 	var jsonFileLst []FileInfo
 	for _, pdfFilepath := range pdfFileLst {
-		var jsonFileInfo FileInfo
-		pdfFilename := filepath.Base(pdfFilepath)
-		splitFilename := fileMethods.SplitFilename(pdfFilename, "_")
-		
-		fmt.Printf("Split filename: %+v\n", splitFilename)
-		
-		// Initialize default values
-		dirPath := "UNKNOWN"
-		rawTitle := "UNKNOWN"
-		rawVoicing := "UNKNOWN"
-		rawComposerArranger := "UNKNOWN"
-		rawExt := "UNKNOWN"
-		
-		k := len(splitFilename)
-		if k > 0 {
-			dirPath = splitFilename[0]
-		}
-		if k > 1 {
-			rawTitle = splitFilename[1]
-		}
-		if k > 2 {
-			rawVoicing = splitFilename[2]
-		}
-		if k > 3 {
-			rawComposerArranger = splitFilename[3]
-		}
-		if k > 0 {
-			rawExt = splitFilename[k-1]
-		}
-		
-		_ = dirPath
-		_ = rawVoicing
-		_ = rawExt
-		
-		jsonFileInfo.AlphabetizingLetter = fileMethods.GetAlphabetizerLetterFromFilename(filepath.Base(pdfFilepath))
-		jsonFileInfo.FullPathToFolder = filepath.Dir(pdfFilepath)
-		jsonFileInfo.OriginalFilename = filepath.Base(pdfFilepath)
-		jsonFileInfo.FileCreateDate = fileMethods.GetFileCreationDateFromFilename(pdfFilepath)
-		jsonFileInfo.SongTitle = fileMethods.SplitSongTitle(rawTitle)
-		jsonFileInfo.Voicing = fileMethods.GetVoicingFromParsedFilename(splitFilename)
-		jsonFileInfo.ComposerOrArranger = rawComposerArranger
-		jsonFileInfo.FileType = fileMethods.GetFileTypeFromFilePath(pdfFilepath)
-		jsonFileInfo.LibraryType = fileMethods.GetLibraryTypeFromFilePath(pdfFilepath)
+		jsonFileInfo := fileMethods.BuildFileInfo(pdfFilepath)
 		
 		fmt.Printf("PDF filepath: %s\n", pdfFilepath)
 		fmt.Printf("JSON file info: %+v\n", jsonFileInfo)
@@ -546,8 +1843,17 @@ func main() {
 		jsonFileLst = append(jsonFileLst, jsonFileInfo)
 	}
 	
+	SortFiles(jsonFileLst)
+	fileMethods.ReportFlaggedFiles()
+	
+	err = fileMethods.WriteUnmatchedFoldersReport(*folderReport)
+	if err != nil {
+		log.Printf("Error writing folder report: %v", err)
+	}
+	
 	masterJSONFile := MasterJSONFile{
 		Files: jsonFileLst,
+		Works: fileMethods.GroupWorks(jsonFileLst),
 	}
 	
 	fmt.Printf("Number of PDF files: %d\n", len(pdfFileLst))
@@ -560,11 +1866,31 @@ func main() {
 	}
 	
 	// Import CSV to database
-	err = fileMethods.ImportCSVFileIntoDB(*outputCSV, *dbname, "music_library")
+	err = fileMethods.ImportCSVFileIntoDB(*outputCSV, "music_library")
 	if err != nil {
 		log.Printf("Error importing to database: %v", err)
 	}
 	
+	err = fileMethods.SaveFileHistory()
+	if err != nil {
+		log.Printf("Error saving file history: %v", err)
+	}
+	
+	err = fileMethods.SaveProvenance(jsonFileLst)
+	if err != nil {
+		log.Printf("Error saving field provenance: %v", err)
+	}
+	
+	err = fileMethods.SaveAudioProperties()
+	if err != nil {
+		log.Printf("Error saving audio properties: %v", err)
+	}
+	
+	err = fileMethods.SaveScoreParts()
+	if err != nil {
+		log.Printf("Error saving score parts: %v", err)
+	}
+	
 	// Write JSON output
 	jsonOutPath := "output_file_full.json"
 	jsonData, err := json.MarshalIndent(masterJSONFile, "", "    ")
@@ -7,7 +7,6 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"flag"
@@ -20,124 +19,14 @@ import (
 	"strings"
 	"unicode"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/ggivl/GoMusicLibraryGUIApp/musiclib"
 )
 
-// SQLiteDatabase represents a SQLite database connection
-type SQLiteDatabase struct {
-	DbName     string
-	Connection *sql.DB
-}
-
-// NewSQLiteDatabase creates a new SQLite database instance
-func NewSQLiteDatabase(dbName string) (*SQLiteDatabase, error) {
-	if dbName == "" {
-		dbName = "musiclibrary.db"
-	}
-	
-	db := &SQLiteDatabase{
-		DbName: dbName,
-	}
-	
-	err := db.connect()
-	if err != nil {
-		return nil, err
-	}
-	
-	return db, nil
-}
-
-func (db *SQLiteDatabase) logSQLCallback(statement string) {
-	fmt.Printf("Executing SQL statement: %s\n", statement)
-}
-
-func (db *SQLiteDatabase) connect() error {
-	var err error
-	db.Connection, err = sql.Open("sqlite3", db.DbName)
-	if err != nil {
-		return fmt.Errorf("error connecting to database: %v", err)
-	}
-	return nil
-}
-
-func (db *SQLiteDatabase) Close() error {
-	if db.Connection != nil {
-		return db.Connection.Close()
-	}
-	return nil
-}
-
-func (db *SQLiteDatabase) CreateTable(tableName, columns string) error {
-	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", tableName, columns)
-	_, err := db.Connection.Exec(query)
-	if err != nil {
-		return fmt.Errorf("error creating table: %v", err)
-	}
-	return nil
-}
-
-func (db *SQLiteDatabase) InsertData(tableName string, data []interface{}) error {
-	placeholders := strings.Repeat("?,", len(data))
-	placeholders = strings.TrimSuffix(placeholders, ",")
-	
-	fmt.Printf("Data: %+v\n", data)
-	
-	query := fmt.Sprintf("INSERT INTO %s VALUES (%s)", tableName, placeholders)
-	fmt.Printf("Query: %s\n", query)
-	
-	_, err := db.Connection.Exec(query, data...)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return err
-	}
-	return nil
-}
-
-func (db *SQLiteDatabase) FetchAll(tableName, condition string) ([][]interface{}, error) {
-	query := fmt.Sprintf("SELECT * FROM %s %s", tableName, condition)
-	rows, err := db.Connection.Query(query)
-	if err != nil {
-		fmt.Printf("Error fetching data: %v\n", err)
-		return nil, err
-	}
-	defer rows.Close()
-	
-	columns, err := rows.Columns()
-	if err != nil {
-		return nil, err
-	}
-	
-	var result [][]interface{}
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-		
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, err
-		}
-		
-		result = append(result, values)
-	}
-	
-	return result, nil
-}
-
-func (db *SQLiteDatabase) ExecuteQuery(query string, params ...interface{}) error {
-	_, err := db.Connection.Exec(query, params...)
-	if err != nil {
-		return fmt.Errorf("error executing query: %v", err)
-	}
-	return nil
-}
-
 // FileMethods represents file processing methods
 type FileMethods struct {
 	BaseDir       string
 	DbColumnNames string
-	db            *SQLiteDatabase
+	db            musiclib.LibraryStore
 }
 
 // NewFileMethods creates a new FileMethods instance
@@ -387,15 +276,15 @@ func (fm *FileMethods) WriteCSVOutputFile(inputJSON MasterJSONFile, outputPath,
 	return nil
 }
 
-func (fm *FileMethods) ImportCSVFileIntoDB(csvFilename, databaseFilename, tableName string) error {
+func (fm *FileMethods) ImportCSVFileIntoDB(csvFilename, backend, databaseFilename, tableName string) error {
 	var err error
-	fm.db, err = NewSQLiteDatabase(databaseFilename)
+	fm.db, err = musiclib.OpenStore(backend, databaseFilename)
 	if err != nil {
 		return err
 	}
 	defer fm.db.Close()
 	
-	err = fm.db.CreateTable("music_library", fm.DbColumnNames)
+	err = fm.db.CreateTable(tableName, fm.DbColumnNames)
 	if err != nil {
 		return err
 	}
@@ -435,7 +324,7 @@ func (fm *FileMethods) ImportCSVFileIntoDB(csvFilename, databaseFilename, tableN
 			}
 		}
 		
-		allRows, err := fm.db.FetchAll("music_library", "")
+		allRows, err := fm.db.FetchAll(tableName, "")
 		if err != nil {
 			return err
 		}
@@ -451,7 +340,8 @@ func main() {
 	dirpath := flag.String("d", `C:\Users\ggivl\Documents\PythonDevelopment\FortyNinersDevelopment\49ersMusicLibrary`, "Path to the directory of the files to parsed")
 	extension := flag.String("e", ".pdf", "Extension of the files to parse")
 	outputCSV := flag.String("o", "csv_output_full.csv", "CSV output file")
-	dbname := flag.String("b", "musiclibrary.db", "Database filename")
+	dbname := flag.String("b", "", "Database filename, overriding the one named in the configuration file; a .duckdb file is opened with DuckDB and a .db file with SQLite")
+	configFile := flag.String("c", "config.yml", "Configuration file naming the database")
 	flag.Parse()
 	
 	fileExt := *extension
@@ -553,8 +443,13 @@ func main() {
 		log.Printf("Error writing CSV: %v", err)
 	}
 	
-	// Import CSV to database
-	err = fileMethods.ImportCSVFileIntoDB(*outputCSV, *dbname, "music_library")
+	// Import CSV to the database named in the configuration file
+	config, err := musiclib.LoadConfig(*configFile)
+	if err != nil {
+		log.Printf("Using default configuration: %v", err)
+	}
+	backend, databaseFilename := config.StoreFile(*dbname)
+	err = fileMethods.ImportCSVFileIntoDB(*outputCSV, backend, databaseFilename, "music_library")
 	if err != nil {
 		log.Printf("Error importing to database: %v", err)
 	}